@@ -0,0 +1,107 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/lint"
+)
+
+const (
+	lintOutputText = "text"
+	lintOutputJSON = "json"
+)
+
+type LintOptions struct {
+	Files      []string
+	Output     string
+	StrictYAML bool
+	Debug      bool
+
+	SymlinkAllowOpts files.SymlinkAllowOpts
+}
+
+func NewLintOptions() *LintOptions {
+	return &LintOptions{}
+}
+
+func NewLintCmd(o *LintOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Find common mistakes in templates",
+		Long: `Find common mistakes in templates.
+
+Files are parsed and compiled the same way as by 'ytt template' (and evaluated once with default data values) to find:
+
+  unused-load           load(...) symbols that are never used
+  unused-data-value     data values defined in schema that are never read
+  unmatched-overlay     document overlays (@overlay/match without expects=...) that matched nothing
+  unknown-annotation    annotations with unknown names (e.g. @overlay/mathc)
+  unknown-comment       comments that would fail --ignore-unknown-comments=false
+  shadowed-function     functions that shadow loaded symbols, builtins or other functions
+  evaluation            errors that prevented other checks from running
+
+Exits with an error if anything was found.
+`,
+		RunE: func(_ *cobra.Command, _ []string) error { return o.Run() },
+	}
+	cmd.Flags().StringArrayVarP(&o.Files, "file", "f", nil, "File (ie local path, path/to/dir) (can be specified multiple times)")
+	cmd.Flags().StringVarP(&o.Output, "output", "o", lintOutputText, "Output format (text, json)")
+	cmd.Flags().BoolVarP(&o.StrictYAML, "strict", "s", false, "Configure to use _strict_ YAML subset")
+	cmd.Flags().BoolVar(&o.Debug, "debug", false, "Enable debug output")
+
+	cmd.Flags().BoolVar(&o.SymlinkAllowOpts.AllowAll, "dangerous-allow-all-symlink-destinations", false,
+		"Symlinks to all destinations are allowed")
+	cmd.Flags().StringSliceVar(&o.SymlinkAllowOpts.AllowedDstPaths, "allow-symlink-destination", nil,
+		"File paths to which symlinks are allowed (can be specified multiple times)")
+	return cmd
+}
+
+func (o *LintOptions) Run() error {
+	ui := ui.NewTTY(o.Debug)
+
+	if o.Output != lintOutputText && o.Output != lintOutputJSON {
+		return fmt.Errorf("Expected --output to be either '%s' or '%s', but was '%s'", lintOutputText, lintOutputJSON, o.Output)
+	}
+	if len(o.Files) == 0 {
+		return fmt.Errorf("Expected at least one file (via --file)")
+	}
+
+	filesToLint, err := files.NewSortedFilesFromPaths(o.Files, o.SymlinkAllowOpts)
+	if err != nil {
+		return err
+	}
+
+	findings, err := lint.NewLinter(lint.LinterOpts{StrictYAML: o.StrictYAML}, ui).Lint(filesToLint)
+	if err != nil {
+		return err
+	}
+
+	switch o.Output {
+	case lintOutputJSON:
+		if findings == nil {
+			findings = []lint.Finding{}
+		}
+		bs, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			return err
+		}
+		ui.Printf("%s\n", bs)
+
+	default:
+		for _, finding := range findings {
+			ui.Printf("%s\n", finding)
+		}
+	}
+
+	if len(findings) > 0 {
+		return fmt.Errorf("Expected no lint findings, but found %d", len(findings))
+	}
+	return nil
+}
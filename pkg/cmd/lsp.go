@@ -0,0 +1,45 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/lsp"
+)
+
+type LSPOptions struct {
+	StrictYAML bool
+	Debug      bool
+}
+
+func NewLSPOptions() *LSPOptions {
+	return &LSPOptions{}
+}
+
+func NewLSPCmd(o *LSPOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lsp",
+		Short: "Start language server (speaking Language Server Protocol over stdio)",
+		Long: `Start language server (speaking Language Server Protocol over stdio).
+
+Workspace root (as given by the editor) is templated as if it was given via --file.
+Provides diagnostics, go-to-definition of load(...) symbols, completion of '@ytt:' modules
+and data.values keys (from schema), and hover with data values descriptions (@schema/desc).
+`,
+		RunE: func(_ *cobra.Command, _ []string) error { return o.Run() },
+	}
+	cmd.Flags().BoolVarP(&o.StrictYAML, "strict", "s", false, "Configure to use _strict_ YAML subset")
+	cmd.Flags().BoolVar(&o.Debug, "debug", false, "Enable debug output (to stderr)")
+	return cmd
+}
+
+func (o *LSPOptions) Run() error {
+	// Stdout is reserved for protocol messages
+	ui := ui.NewCustomWriterTTY(o.Debug, os.Stderr, os.Stderr)
+
+	return lsp.NewServer(os.Stdin, os.Stdout, lsp.ServerOpts{StrictYAML: o.StrictYAML}, ui).Run()
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/spf13/cobra"
+	cmdtpl "github.com/vmware-tanzu/carvel-ytt/pkg/cmd/template"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace"
+)
+
+type REPLOptions struct {
+	Files           []string
+	TemplateOptions *cmdtpl.Options
+}
+
+func NewREPLOptions() *REPLOptions {
+	return &REPLOptions{TemplateOptions: cmdtpl.NewOptions()}
+}
+
+func NewREPLCmd(o *REPLOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "repl",
+		Short: "Start interactive Starlark session with templates' data values and modules loaded",
+		Long: `Start interactive Starlark session with templates' data values and modules loaded.
+
+Files are loaded, and data values are calculated, the same way as by 'ytt template'.
+Within the session:
+
+  data.values           holds final data values of the root library
+  struct, overlay, ...  all @ytt: modules are available without loading them
+  load("file.star", ...)
+                        loads files (and @ytt: modules) the same way as templates do
+
+Expressions are evaluated and their values printed. Blocks (e.g. def ... end) span multiple lines.
+Session ends at the end of input (e.g. Ctrl-D).
+`,
+		RunE: func(_ *cobra.Command, _ []string) error { return o.Run() },
+	}
+
+	opts := o.TemplateOptions
+
+	cmd.Flags().StringArrayVarP(&o.Files, "file", "f", nil, "File (ie local path, path/to/dir, HTTP URL) (can be specified multiple times)")
+	cmd.Flags().BoolVar(&opts.IgnoreUnknownComments, "ignore-unknown-comments", false,
+		"Configure whether unknown comments are considered as errors (comments that do not start with '#@' or '#!')")
+	cmd.Flags().BoolVar(&opts.ImplicitMapKeyOverrides, "implicit-map-key-overrides", false,
+		"Configure whether implicit map keys overrides are allowed")
+	cmd.Flags().BoolVarP(&opts.StrictYAML, "strict", "s", false, "Configure to use _strict_ YAML subset")
+	cmd.Flags().BoolVar(&opts.Debug, "debug", false, "Enable debug output")
+
+	opts.FileMarksOpts.Set(cmd.Flags())
+	opts.DataValuesFlags.Set(cmd.Flags())
+
+	cmd.Flags().BoolVar(&opts.RegularFilesSourceOpts.SymlinkAllowOpts.AllowAll, "dangerous-allow-all-symlink-destinations", false,
+		"Symlinks to all destinations are allowed")
+	cmd.Flags().StringSliceVar(&opts.RegularFilesSourceOpts.SymlinkAllowOpts.AllowedDstPaths, "allow-symlink-destination", nil,
+		"File paths to which symlinks are allowed (can be specified multiple times)")
+	return cmd
+}
+
+func (o *REPLOptions) Run() error {
+	ui := ui.NewTTY(o.TemplateOptions.Debug)
+
+	srcOpts := o.TemplateOptions.RegularFilesSourceOpts
+
+	httpOpts := srcOpts.HTTPOpts()
+	httpOpts.UI = ui
+
+	filesToLoad, _, err := files.NewSortedFilesFromPathsWithIgnored(o.Files, *srcOpts.SymlinkAllowOpts, httpOpts)
+	if err != nil {
+		return err
+	}
+
+	repl, err := o.TemplateOptions.NewREPL(cmdtpl.Input{Files: filesToLoad}, ui)
+	if err != nil {
+		return err
+	}
+
+	return o.run(repl, os.Stdin, os.Stdout, os.Stderr)
+}
+
+// run reads statements from in until its end, printing results to out and errors to errOut.
+func (o *REPLOptions) run(repl *workspace.REPL, in io.Reader, out, errOut io.Writer) error {
+	reader := bufio.NewReader(in)
+
+	for {
+		prompt := ">>> "
+		readline := func() ([]byte, error) {
+			fmt.Fprint(out, prompt)
+			prompt = "... "
+
+			line, err := reader.ReadBytes('\n')
+			if err == io.EOF && len(line) > 0 {
+				// last line without trailing newline
+				return append(line, '\n'), nil
+			}
+			return line, err
+		}
+
+		val, err := repl.Eval(readline)
+		switch {
+		case err == io.EOF:
+			fmt.Fprintln(out)
+			return nil
+		case err != nil:
+			if evalErr, ok := err.(*starlark.EvalError); ok {
+				fmt.Fprintln(errOut, evalErr.Backtrace())
+			} else {
+				fmt.Fprintln(errOut, err)
+			}
+		case val != starlark.None:
+			fmt.Fprintln(out, val.String())
+		}
+	}
+}
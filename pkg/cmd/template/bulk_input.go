@@ -62,7 +62,7 @@ func (s BulkFilesSource) Input() (Input, error) {
 		result = append(result, file)
 	}
 
-	return Input{files.NewSortedFiles(result)}, nil
+	return Input{Files: files.NewSortedFiles(result)}, nil
 }
 
 func (s *BulkFilesSource) Output(out Output) error {
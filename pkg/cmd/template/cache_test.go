@@ -0,0 +1,140 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package template_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cmdtpl "github.com/vmware-tanzu/carvel-ytt/pkg/cmd/template"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+)
+
+func TestCacheDirProducesSameOutput(t *testing.T) {
+	expectedOut := cmdtpl.NewOptions().RunWithFiles(cmdtpl.Input{Files: cacheTestFiles("")}, ui.NewTTY(false))
+	require.NoError(t, expectedOut.Err)
+	require.Contains(t, string(expectedOut.Files[0].Bytes()), "name: app-config")
+
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+
+	opts := cmdtpl.NewOptions()
+	opts.CacheDir = cacheDir
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: cacheTestFiles("")}, ui.NewTTY(false))
+	requireSameOutputFiles(t, expectedOut, out)
+
+	entries, err := os.ReadDir(cacheDir)
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+
+	// Second run uses populated cache
+	out = opts.RunWithFiles(cmdtpl.Input{Files: cacheTestFiles("")}, ui.NewTTY(false))
+	requireSameOutputFiles(t, expectedOut, out)
+}
+
+func TestCacheDirDoesNotUseEntriesOfChangedFiles(t *testing.T) {
+	expectedOut := cmdtpl.NewOptions().RunWithFiles(cmdtpl.Input{Files: cacheTestFiles("")}, ui.NewTTY(false))
+	require.NoError(t, expectedOut.Err)
+
+	opts := cmdtpl.NewOptions()
+	opts.CacheDir = filepath.Join(t.TempDir(), "cache")
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: cacheTestFiles("")}, ui.NewTTY(false))
+	requireSameOutputFiles(t, expectedOut, out)
+
+	out = opts.RunWithFiles(cmdtpl.Input{Files: cacheTestFiles("---\nextra: #@ 2 * 2\n")}, ui.NewTTY(false))
+	require.NoError(t, out.Err)
+	require.Contains(t, string(out.Files[0].Bytes()), "extra: 4")
+
+	out = opts.RunWithFiles(cmdtpl.Input{Files: cacheTestFiles("")}, ui.NewTTY(false))
+	requireSameOutputFiles(t, expectedOut, out)
+}
+
+func TestCacheDirIgnoresCorruptedEntries(t *testing.T) {
+	expectedOut := cmdtpl.NewOptions().RunWithFiles(cmdtpl.Input{Files: cacheTestFiles("")}, ui.NewTTY(false))
+	require.NoError(t, expectedOut.Err)
+
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+
+	opts := cmdtpl.NewOptions()
+	opts.CacheDir = cacheDir
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: cacheTestFiles("")}, ui.NewTTY(false))
+	requireSameOutputFiles(t, expectedOut, out)
+
+	entries, err := os.ReadDir(cacheDir)
+	require.NoError(t, err)
+	for _, entry := range entries {
+		err := os.WriteFile(filepath.Join(cacheDir, entry.Name()), []byte("corrupted"), 0600)
+		require.NoError(t, err)
+	}
+
+	out = opts.RunWithFiles(cmdtpl.Input{Files: cacheTestFiles("")}, ui.NewTTY(false))
+	requireSameOutputFiles(t, expectedOut, out)
+
+	out = opts.RunWithFiles(cmdtpl.Input{Files: cacheTestFiles("")}, ui.NewTTY(false))
+	requireSameOutputFiles(t, expectedOut, out)
+}
+
+// cacheTestFiles returns templates covering most of template compilation (code, annotations,
+// text templated strings, overlays, plain YAML and libraries); extraTplData is appended to main template.
+func cacheTestFiles(extraTplData string) []*files.File {
+	tplData := `#@ load("@ytt:data", "data")
+#@ load("@ytt:library", "library")
+#@ load("@ytt:template", "template")
+
+#@ def labels():
+app: #@ data.values.name
+#@ end
+
+#! comment preserved in plain nodes
+---
+kind: ConfigMap
+metadata:
+  labels: #@ labels()
+  #@yaml/text-templated-strings
+  name: (@= data.values.name @)-config
+data:
+  #@ for i in range(2):
+  #@ if/end i > 0:
+  #@overlay/match missing_ok=True
+  key: #@ "value-{}".format(i)
+  #@ end
+  plain: 1.5
+  when: 2022-01-01
+--- #@ template.replace(library.get("lib").eval())
+` + extraTplData
+
+	return files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", []byte(tplData))),
+		files.MustNewFileFromSource(files.NewBytesSource("values.yml", []byte(`#@data/values
+---
+name: app
+`))),
+		files.MustNewFileFromSource(files.NewBytesSource("overlay.yml", []byte(`#@ load("@ytt:overlay", "overlay")
+#@overlay/match by=overlay.subset({"kind": "ConfigMap"})
+---
+data:
+  #@overlay/match missing_ok=True
+  overlaid: true
+`))),
+		files.MustNewFileFromSource(files.NewBytesSource("plain.yml", []byte(`plain: [1, true, null]
+`))),
+		files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/lib/tpl.yml", []byte(`---
+lib: #@ 1 + 2
+`))),
+	})
+}
+
+func requireSameOutputFiles(t *testing.T, expectedOut, out cmdtpl.Output) {
+	require.NoError(t, out.Err)
+	require.Equal(t, len(expectedOut.Files), len(out.Files))
+	for i, expectedFile := range expectedOut.Files {
+		require.Equal(t, expectedFile.RelativePath(), out.Files[i].RelativePath())
+		require.Equal(t, string(expectedFile.Bytes()), string(out.Files[i].Bytes()))
+	}
+}
@@ -5,11 +5,14 @@ package template
 
 import (
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/dap"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/schema"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/template"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace/datavalues"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/yamlmeta"
@@ -26,21 +29,43 @@ type Options struct {
 	StrictYAML   bool
 	Debug        bool
 	InspectFiles bool
+	// RunStarlarkTests calls test functions within *_test.star files instead of rendering templates
+	RunStarlarkTests bool
+	// Parallelism limits number of templates evaluated concurrently (0 or 1 means sequentially)
+	Parallelism int
+	// CacheDir, if set, is a directory where parsed and compiled YAML templates are cached between runs
+	CacheDir string
+	// Debugger, if set, is an address ('stdio' or TCP address) at which Debug Adapter Protocol is served
+	// so that a debugger client can step through evaluation of templates
+	Debugger string
+	// DebugServer, if set, is used instead of serving Debug Adapter Protocol at Debugger address
+	DebugServer *dap.Server
 
 	BulkFilesSourceOpts    BulkFilesSourceOpts
 	RegularFilesSourceOpts RegularFilesSourceOpts
 	FileMarksOpts          FileMarksOpts
 	DataValuesFlags        DataValuesFlags
+	ExecutionLimitsFlags   ExecutionLimitsFlags
+	ProfileFlags           ProfileFlags
 }
 
 type Input struct {
 	Files []*files.File
+	// IgnoredFiles were skipped due to rules in ignore files (i.e. .yttignore)
+	IgnoredFiles []files.IgnoredFile
 }
 
 type Output struct {
 	Files  []files.OutputFile
 	DocSet *yamlmeta.DocumentSet
-	Err    error
+	// StarlarkTests hold results of test functions (see Options.RunStarlarkTests)
+	StarlarkTests []workspace.StarlarkTestResult
+	// Profile holds time spent during evaluation, longest first (see Options.ProfileFlags)
+	Profile []template.ProfileEntry
+	Err     error
+
+	// templateDocSets hold results of YAML templates with formats of their output files (see --output-split)
+	templateDocSets []workspace.TemplateDocSet
 }
 
 // FileSource provides both a means of loading from sources (i.e. Input) and rendering into sinks (i.e. Output)
@@ -57,9 +82,10 @@ var _ []FileSource = []FileSource{&BulkFilesSource{}, &RegularFilesSource{}}
 // NewOptions initializes a new instance of template.Options.
 func NewOptions() *Options {
 	var opts files.SymlinkAllowOpts
+	httpOpts := files.HTTPSourceOpts{RetryInterval: time.Second}
 	return &Options{
-		RegularFilesSourceOpts: RegularFilesSourceOpts{SymlinkAllowOpts: &opts},
-		DataValuesFlags:        DataValuesFlags{SymlinkAllowOpts: &opts},
+		RegularFilesSourceOpts: RegularFilesSourceOpts{SymlinkAllowOpts: &opts, HTTPSourceOpts: &httpOpts},
+		DataValuesFlags:        DataValuesFlags{SymlinkAllowOpts: &opts, HTTPSourceOpts: &httpOpts},
 	}
 }
 
@@ -72,17 +98,28 @@ func (o *Options) BindFlags(cmdFlags CmdFlags) {
 	cmdFlags.BoolVarP(&o.StrictYAML, "strict", "s", false, "Configure to use _strict_ YAML subset")
 	cmdFlags.BoolVar(&o.Debug, "debug", false, "Enable debug output")
 	cmdFlags.BoolVar(&o.InspectFiles, "files-inspect", false, "Determine the set of files that would be processed and display that result")
+	cmdFlags.IntVar(&o.Parallelism, "parallelism", 1, "Maximum number of templates evaluated concurrently (1 means sequentially)")
+	cmdFlags.StringVar(&o.CacheDir, "cache-dir", "", "Directory in which parsed and compiled YAML templates are cached between runs (disabled by default)")
+	cmdFlags.StringVar(&o.Debugger, "debugger", "",
+		"Step through evaluation with a debugger client speaking Debug Adapter Protocol over 'stdio' or at a TCP address (e.g. 'localhost:4711')")
 
 	o.BulkFilesSourceOpts.Set(cmdFlags)
 	o.RegularFilesSourceOpts.Set(cmdFlags)
 	o.FileMarksOpts.Set(cmdFlags)
 	o.DataValuesFlags.Set(cmdFlags)
+	o.ExecutionLimitsFlags.Set(cmdFlags)
+	o.ProfileFlags.Set(cmdFlags)
 }
 
 func (o *Options) Run() error {
-	ui := ui.NewTTY(o.Debug)
+	ui := o.newUI()
 	t1 := time.Now()
 
+	// Shared by regular files and data values flags
+	if o.RegularFilesSourceOpts.HTTPSourceOpts != nil {
+		o.RegularFilesSourceOpts.HTTPSourceOpts.UI = ui
+	}
+
 	defer func() {
 		ui.Debugf("total: %s\n", time.Now().Sub(t1))
 	}()
@@ -98,42 +135,114 @@ func (o *Options) Run() error {
 	}
 
 	out := o.RunWithFiles(in, ui)
+
+	if o.ProfileFlags.Profile {
+		ui.Warnf("%s", ProfileAsTable(out.Profile))
+	}
+
 	return o.pickSource(srcs, func(s FileSource) bool { return s.HasOutput() }).Output(out)
 }
 
 func (o *Options) RunWithFiles(in Input, ui ui.UI) Output {
-	var err error
+	var profiler *template.Profiler
+	if o.ProfileFlags.Profile {
+		profiler = template.NewProfiler()
+	}
 
-	in.Files, err = o.FileMarksOpts.Apply(in.Files)
+	stopPprof, err := o.ProfileFlags.StartPprof()
 	if err != nil {
 		return Output{Err: err}
 	}
 
-	rootLibrary := workspace.NewRootLibrary(in.Files)
-	rootLibrary.Print(ui.DebugWriter())
+	debugServer, err := o.startDebugServer(ui)
+	if err != nil {
+		stopPprof()
+		return Output{Err: err}
+	}
+
+	var debugger template.Debugger
+	if debugServer != nil {
+		debugServer.SetFiles(in.Files)
+		debugger = debugServer
+	}
+
+	out := o.runWithFiles(in, ui, profiler, debugger)
+	out.Profile = profiler.Entries()
+
+	if debugServer != nil {
+		err = debugServer.Finish(out.Err)
+		if err != nil && out.Err == nil {
+			out.Err = err
+		}
+	}
+
+	err = stopPprof()
+	if err != nil && out.Err == nil {
+		out.Err = err
+	}
+
+	return out
+}
+
+func (o *Options) newUI() ui.TTY {
+	if o.Debugger == dap.StdioAddress {
+		// Stdout is reserved for protocol messages
+		return ui.NewCustomWriterTTY(o.Debug, os.Stderr, os.Stderr)
+	}
+	return ui.NewTTY(o.Debug)
+}
+
+// startDebugServer waits for debugger client (if requested) to finish its configuration.
+func (o *Options) startDebugServer(ui ui.UI) (*dap.Server, error) {
+	debugServer := o.DebugServer
+	if debugServer == nil {
+		if len(o.Debugger) == 0 {
+			return nil, nil
+		}
+
+		var err error
+		debugServer, err = dap.Listen(o.Debugger, ui)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := debugServer.Start()
+	if err != nil {
+		return nil, err
+	}
+	return debugServer, nil
+}
+
+func (o *Options) runWithFiles(in Input, ui ui.UI, profiler *template.Profiler, debugger template.Debugger) Output {
+	rootLibrary, err := o.newRootLibrary(in, ui)
+	if err != nil {
+		return Output{Err: err}
+	}
 
 	if o.InspectFiles {
+		for _, ignoredFile := range in.IgnoredFiles {
+			ui.Debugf("ignored: %s (rule %s)\n", ignoredFile.Path, ignoredFile.Rule)
+		}
 		return o.inspectFiles(rootLibrary)
 	}
 
-	valuesOverlays, libraryValuesOverlays, err := o.DataValuesFlags.AsOverlays(o.StrictYAML)
+	rootLibraryExecution, err := o.newRootLibraryExecution(rootLibrary, ui, profiler, debugger)
 	if err != nil {
 		return Output{Err: err}
 	}
 
-	libraryExecutionFactory := workspace.NewLibraryExecutionFactory(
-		ui,
-		workspace.TemplateLoaderOpts{
-			IgnoreUnknownComments:   o.IgnoreUnknownComments,
-			ImplicitMapKeyOverrides: o.ImplicitMapKeyOverrides,
-			StrictYAML:              o.StrictYAML,
-		},
-		o.DataValuesFlags.SkipValidation)
+	schemaOverlays, err := o.DataValuesFlags.AsSchemaOverlays(rootLibraryExecution)
+	if err != nil {
+		return Output{Err: err}
+	}
 
-	libraryCtx := workspace.LibraryExecutionContext{Current: rootLibrary, Root: rootLibrary}
-	rootLibraryExecution := libraryExecutionFactory.New(libraryCtx)
+	schema, librarySchemas, err := rootLibraryExecution.Schemas(schemaOverlays)
+	if err != nil {
+		return Output{Err: err}
+	}
 
-	schema, librarySchemas, err := rootLibraryExecution.Schemas(nil)
+	valuesOverlays, libraryValuesOverlays, err := o.DataValuesFlags.AsOverlays(o.StrictYAML, schema)
 	if err != nil {
 		return Output{Err: err}
 	}
@@ -161,12 +270,118 @@ func (o *Options) RunWithFiles(in Input, ui ui.UI) Output {
 		return o.inspectDataValues(values)
 	}
 
+	if o.RunStarlarkTests {
+		results, err := rootLibraryExecution.StarlarkTests(values, libraryValues, librarySchemas)
+		return Output{StarlarkTests: results, Err: err}
+	}
+
 	result, err := rootLibraryExecution.Eval(values, libraryValues, librarySchemas)
 	if err != nil {
 		return Output{Err: err}
 	}
 
-	return Output{Files: result.Files, DocSet: result.DocSet}
+	// Split documents are printed in formats of their templates (JSON documents of split templates are not limited to one)
+	if len(o.RegularFilesSourceOpts.OutputSplit) == 0 {
+		err = result.FormatFiles(o.RegularFilesSourceOpts.YAMLPrinterOpts)
+		if err != nil {
+			return Output{Err: err}
+		}
+	}
+
+	return Output{Files: result.Files, DocSet: result.DocSet, templateDocSets: result.TemplateDocSets()}
+}
+
+// newRootLibrary applies file marks (and related flags) to given files and groups them into a root library.
+func (o *Options) newRootLibrary(in Input, ui ui.UI) (*workspace.Library, error) {
+	var err error
+
+	in.Files, err = o.FileMarksOpts.Apply(in.Files)
+	if err != nil {
+		return nil, err
+	}
+
+	err = o.markOutputFormat(in.Files)
+	if err != nil {
+		return nil, err
+	}
+
+	err = o.RegularFilesSourceOpts.YAMLPrinterOpts.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("Checking YAML output style flags: %s", err)
+	}
+
+	rootLibrary := workspace.NewRootLibrary(in.Files)
+	rootLibrary.Print(ui.DebugWriter())
+
+	return rootLibrary, nil
+}
+
+// newRootLibraryExecution configures execution of root library according to evaluation flags.
+func (o *Options) newRootLibraryExecution(rootLibrary *workspace.Library, ui ui.UI,
+	profiler *template.Profiler, debugger template.Debugger) (*workspace.LibraryExecution, error) {
+
+	executionLimits, err := o.ExecutionLimitsFlags.Limits()
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Parallelism < 0 {
+		return nil, fmt.Errorf("Expected --parallelism to be non-negative, but was %d", o.Parallelism)
+	}
+	if debugger != nil && o.Parallelism > 1 {
+		return nil, fmt.Errorf("Expected --parallelism to be 1 when debugging, but was %d", o.Parallelism)
+	}
+	if debugger != nil && executionLimits.Timeout > 0 {
+		// Time spent paused in debugger would count towards timeout
+		return nil, fmt.Errorf("Expected --%s to be 0 when debugging, but was %s", executionTimeoutFlagName, executionLimits.Timeout)
+	}
+
+	var compiledTemplateCache *workspace.CompiledTemplateCache
+	if len(o.CacheDir) > 0 {
+		compiledTemplateCache, err = workspace.NewCompiledTemplateCache(o.CacheDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	templateLoaderOpts := workspace.TemplateLoaderOpts{
+		IgnoreUnknownComments:   o.IgnoreUnknownComments,
+		ImplicitMapKeyOverrides: o.ImplicitMapKeyOverrides,
+		StrictYAML:              o.StrictYAML,
+		Profiler:                profiler,
+		Debugger:                debugger,
+		Parallelism:             o.Parallelism,
+		CompiledTemplateCache:   compiledTemplateCache,
+	}
+	if !executionLimits.IsZero() {
+		templateLoaderOpts.ExecutionLimiter = template.NewExecutionLimiter(executionLimits)
+	}
+
+	libraryExecutionFactory := workspace.NewLibraryExecutionFactory(
+		ui, templateLoaderOpts, o.DataValuesFlags.SkipValidation)
+
+	libraryCtx := workspace.LibraryExecutionContext{Current: rootLibrary, Root: rootLibrary}
+	return libraryExecutionFactory.New(libraryCtx), nil
+}
+
+// markOutputFormat applies --output-files-format to YAML files that were not marked with specific format.
+func (o *Options) markOutputFormat(filesToProcess []*files.File) error {
+	format := o.RegularFilesSourceOpts.OutputFilesFormat
+	if len(format) == 0 || format == files.OutputFormatYAML {
+		return nil
+	}
+
+	_, err := workspace.OutputFormatPrinter(format, yamlmeta.YAMLPrinterOpts{})
+	if err != nil {
+		return fmt.Errorf("Checking --output-files-format: %s", err)
+	}
+
+	for _, file := range filesToProcess {
+		if _, marked := file.OutputFormat(); !marked && file.Type() == files.TypeYAML {
+			file.MarkOutputFormat(format)
+		}
+	}
+	return nil
 }
 
 func (o *Options) inspectDataValues(values *datavalues.Envelope) Output {
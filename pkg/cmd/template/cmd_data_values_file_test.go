@@ -5,6 +5,8 @@ package template_test
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -118,6 +120,34 @@ array:
 	assert.Equal(t, expectedYAMLTplData, string(file.Bytes()))
 }
 
+func TestDataValuesFilesFlag_WithoutHTTPSourceOpts(t *testing.T) {
+	yamlTplData := []byte(`
+#@ load("@ytt:data", "data")
+values: #@ data.values`)
+
+	dvsPath := filepath.Join(t.TempDir(), "dvs.yml")
+	require.NoError(t, os.WriteFile(dvsPath, []byte("int: 123\n"), 0600))
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", yamlTplData)),
+	})
+
+	ui := ui.NewTTY(false)
+	opts := cmdtpl.NewOptions()
+
+	// Options built by library callers do not necessarily configure fetching over HTTP
+	opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+		FromFiles:        []string{dvsPath},
+		SymlinkAllowOpts: &files.SymlinkAllowOpts{},
+	}
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+	require.NoError(t, out.Err)
+	require.Len(t, out.Files, 1, "unexpected number of output files")
+
+	assert.Equal(t, "values:\n  int: 123\n", string(out.Files[0].Bytes()))
+}
+
 func TestDataValuesFilesFlag_rejectsTemplatedYAML(t *testing.T) {
 	yamlTplData := []byte(`
 #@ load("@ytt:data", "data")
@@ -232,3 +262,168 @@ int: 123
 		assert.Equal(t, expectedYAMLTplData, string(file.Bytes()))
 	})
 }
+
+func TestDataValuesFilesFlag_WithOverlayAnnotations(t *testing.T) {
+	yamlTplData := []byte(`
+#@ load("@ytt:data", "data")
+values: #@ data.values`)
+
+	builtinDVs := []byte(`
+#@data/values
+---
+name: app
+owner: me
+ports: [80, 443]
+hosts:
+- name: a
+  port: 80
+- name: b
+  port: 81
+`)
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", yamlTplData)),
+		files.MustNewFileFromSource(files.NewBytesSource("values.yml", builtinDVs)),
+	})
+
+	t.Run("honors overlay annotations", func(t *testing.T) {
+		dvs1 := `
+# plain comments are still allowed
+name: new-app
+#@overlay/remove
+owner:
+ports: [8080]
+hosts:
+#@overlay/match by=overlay.index(1)
+- port: 8081
+- name: c
+  port: 82
+`
+
+		ui := ui.NewTTY(false)
+		opts := cmdtpl.NewOptions()
+
+		opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+			FromFiles:          []string{"dvs1.yml"},
+			FromFilesTemplated: true,
+			ReadFilesFunc: func(path string) ([]*files.File, error) {
+				switch path {
+				case "dvs1.yml":
+					return []*files.File{files.MustNewFileFromSource(files.NewBytesSource("dvs1.yml", []byte(dvs1)))}, nil
+				default:
+					return nil, fmt.Errorf("Unknown file '%s'", path)
+				}
+			},
+		}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+		require.NoError(t, out.Err)
+		require.Len(t, out.Files, 1, "unexpected number of output files")
+
+		assert.Equal(t, `values:
+  name: new-app
+  ports:
+  - 8080
+  hosts:
+  - name: a
+    port: 80
+  - name: b
+    port: 8081
+  - name: c
+    port: 82
+`, string(out.Files[0].Bytes()))
+	})
+
+	t.Run("replaces arrays whose items are not annotated themselves", func(t *testing.T) {
+		dvs1 := `
+hosts:
+- name: c
+  #@overlay/match missing_ok=True
+  port: 82
+`
+
+		ui := ui.NewTTY(false)
+		opts := cmdtpl.NewOptions()
+
+		opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+			FromFiles:          []string{"dvs1.yml"},
+			FromFilesTemplated: true,
+			ReadFilesFunc: func(path string) ([]*files.File, error) {
+				switch path {
+				case "dvs1.yml":
+					return []*files.File{files.MustNewFileFromSource(files.NewBytesSource("dvs1.yml", []byte(dvs1)))}, nil
+				default:
+					return nil, fmt.Errorf("Unknown file '%s'", path)
+				}
+			},
+		}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+		require.NoError(t, out.Err)
+		require.Len(t, out.Files, 1, "unexpected number of output files")
+
+		assert.Equal(t, `values:
+  name: app
+  owner: me
+  ports:
+  - 80
+  - 443
+  hosts:
+  - name: c
+    port: 82
+`, string(out.Files[0].Bytes()))
+	})
+
+	t.Run("rejects code in annotation arguments", func(t *testing.T) {
+		dvs1 := `
+hosts:
+#@overlay/match by=lambda i, l, r: [x for x in range(100000000)]
+- port: 8081
+`
+
+		ui := ui.NewTTY(false)
+		opts := cmdtpl.NewOptions()
+
+		opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+			FromFiles:          []string{"dvs1.yml"},
+			FromFilesTemplated: true,
+			ReadFilesFunc: func(path string) ([]*files.File, error) {
+				switch path {
+				case "dvs1.yml":
+					return []*files.File{files.MustNewFileFromSource(files.NewBytesSource("dvs1.yml", []byte(dvs1)))}, nil
+				default:
+					return nil, fmt.Errorf("Unknown file '%s'", path)
+				}
+			},
+		}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+		require.EqualError(t, out.Err, "Extracting data value from file: Checking data values file 'dvs1.yml': Evaluating annotation '#@overlay/match by=lambda i, l, r: [x for x in range(100000000)]' on line dvs1.yml:3: Expected annotation argument to be a literal value or a member of 'overlay' module, but was 'lambda i, l, r: [x for x in range(100000000)]'")
+	})
+
+	t.Run("rejects code", func(t *testing.T) {
+		dvs1 := `
+#@ x = 1
+name: new-app
+`
+
+		ui := ui.NewTTY(false)
+		opts := cmdtpl.NewOptions()
+
+		opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+			FromFiles:          []string{"dvs1.yml"},
+			FromFilesTemplated: true,
+			ReadFilesFunc: func(path string) ([]*files.File, error) {
+				switch path {
+				case "dvs1.yml":
+					return []*files.File{files.MustNewFileFromSource(files.NewBytesSource("dvs1.yml", []byte(dvs1)))}, nil
+				default:
+					return nil, fmt.Errorf("Unknown file '%s'", path)
+				}
+			},
+		}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+		require.EqualError(t, out.Err, "Extracting data value from file: Checking data values file 'dvs1.yml': Expected to be plain YAML, having only overlay annotations (@overlay/match, @overlay/merge, @overlay/remove, @overlay/replace, @overlay/insert, @overlay/append), but found '#@ x = 1' on line dvs1.yml:2")
+	})
+}
@@ -798,3 +798,343 @@ func TestDataValuesWithInvalidFlagsFail(t *testing.T) {
 		require.Equal(t, expectedErr, out.Err.Error())
 	})
 }
+
+func TestDataValuesEnvWithKeySepAndCase(t *testing.T) {
+	tmplBytes := []byte(`
+#@ load("@ytt:data", "data")
+--- #@ data.values
+`)
+
+	ui := ui.NewTTY(false)
+	opts := cmdtpl.NewOptions()
+
+	opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+		EnvFromStrings: []string{"APP"},
+		EnvKeySep:      "__",
+		EnvKeyCase:     "camel",
+		EnvironFunc: func() []string {
+			return []string{"APP_DB__HOST_NAME=localhost", "APP_LOG_LEVEL=debug"}
+		},
+	}
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", tmplBytes)),
+	})
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+	require.NoError(t, out.Err)
+	require.Len(t, out.Files, 1, "unexpected number of output files")
+
+	assert.Equal(t, `db:
+  hostName: localhost
+logLevel: debug
+`, string(out.Files[0].Bytes()))
+
+	t.Run("fails on unknown key case", func(t *testing.T) {
+		opts.DataValuesFlags.EnvKeyCase = "kebab"
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+		require.EqualError(t, out.Err, "Extracting data values from env under prefix 'APP': Expected env key case to be one of: verbatim, lower, camel, but was 'kebab'")
+	})
+}
+
+func TestDataValuesEnvWithKeysFromSchema(t *testing.T) {
+	tmplBytes := []byte(`
+#@ load("@ytt:data", "data")
+--- #@ data.values
+`)
+
+	schemaBytes := []byte(`
+#@data/values-schema
+---
+db:
+  host: ""
+  port: 0
+dbUser: ""
+log_level: ""
+extra:
+  #@schema/type any=True
+  opts: {}
+`)
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", tmplBytes)),
+		files.MustNewFileFromSource(files.NewBytesSource("schema.yml", schemaBytes)),
+	})
+
+	t.Run("resolves keys against schema", func(t *testing.T) {
+		ui := ui.NewTTY(false)
+		opts := cmdtpl.NewOptions()
+
+		opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+			EnvFromYAML:   []string{"APP"},
+			EnvKeySep:     "_",
+			EnvKeyCase:    "lower",
+			EnvKeysSchema: true,
+			EnvironFunc: func() []string {
+				return []string{
+					"APP_DB_HOST=localhost",
+					"APP_DB_PORT=5432",
+					"APP_DB_USER=admin",
+					"APP_LOG_LEVEL=debug",
+					"APP_EXTRA_OPTS_CACHE_SIZE=10",
+				}
+			},
+		}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+		require.NoError(t, out.Err)
+		require.Len(t, out.Files, 1, "unexpected number of output files")
+
+		assert.Equal(t, `db:
+  host: localhost
+  port: 5432
+dbUser: admin
+log_level: debug
+extra:
+  opts:
+    cache:
+      size: 10
+`, string(out.Files[0].Bytes()))
+	})
+
+	t.Run("reports env vars that do not match schema", func(t *testing.T) {
+		ui := ui.NewTTY(false)
+		opts := cmdtpl.NewOptions()
+
+		opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+			EnvFromStrings: []string{"APP"},
+			EnvKeySep:      "_",
+			EnvKeysSchema:  true,
+			EnvironFunc: func() []string {
+				return []string{"APP_DB_HOST=localhost", "APP_DB_NAME=db", "APP_UNKNOWN=1"}
+			},
+		}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+		require.EqualError(t, out.Err, "Extracting data values from env under prefix 'APP': Expected env variables to match keys in data values schema, but did not find match for: APP_DB_NAME, APP_UNKNOWN")
+	})
+}
+
+func TestDataValuesFlagsWithArrayIndexes(t *testing.T) {
+	tmplBytes := []byte(`
+#@ load("@ytt:data", "data")
+--- #@ data.values
+`)
+
+	dataBytes := []byte(`
+#@data/values
+---
+ingress:
+  hosts:
+  - name: a
+    port: 80
+  - name: b
+    port: 81
+  paths: [/a, /b]
+`)
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", tmplBytes)),
+		files.MustNewFileFromSource(files.NewBytesSource("data.yml", dataBytes)),
+	})
+
+	t.Run("sets and appends array items", func(t *testing.T) {
+		ui := ui.NewTTY(false)
+		opts := cmdtpl.NewOptions()
+
+		opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+			KVsFromStrings: []string{"ingress.hosts[1].name=foo", "ingress.hosts[+].name=bar", "ingress.paths[0]=/c"},
+			KVsFromYAML:    []string{"ingress.paths[+]={\"x\": 1}"},
+		}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+		require.NoError(t, out.Err)
+		require.Len(t, out.Files, 1, "unexpected number of output files")
+
+		assert.Equal(t, `ingress:
+  hosts:
+  - name: a
+    port: 80
+  - name: foo
+    port: 81
+  - name: bar
+  paths:
+  - /c
+  - /b
+  - x: 1
+`, string(out.Files[0].Bytes()))
+	})
+
+	t.Run("fails when index does not exist", func(t *testing.T) {
+		ui := ui.NewTTY(false)
+		opts := cmdtpl.NewOptions()
+
+		opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+			KVsFromStrings: []string{"ingress.hosts[5].name=foo"},
+		}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+		require.Error(t, out.Err)
+		assert.Contains(t, out.Err.Error(), "Expected number of matched nodes to be 1, but was 0")
+	})
+
+	t.Run("fails when index does not follow map key", func(t *testing.T) {
+		ui := ui.NewTTY(false)
+		opts := cmdtpl.NewOptions()
+
+		opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+			KVsFromStrings: []string{"[0]=foo"},
+		}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+		require.EqualError(t, out.Err, "Extracting data value from KV: Expected array index in key '[0]' to follow a map key (e.g. 'key[0]')")
+
+		opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+			KVsFromYAML: []string{"ingress.[0]=foo"},
+		}
+
+		out = opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+		require.EqualError(t, out.Err, "Extracting data value from KV: Expected array index in key 'ingress.[0]' to follow a map key (e.g. 'key[0]')")
+	})
+}
+
+func TestDataValuesFlagsUnsetAndNull(t *testing.T) {
+	tmplBytes := []byte(`
+#@ load("@ytt:data", "data")
+--- #@ data.values
+`)
+
+	schemaBytes := []byte(`
+#@data/values-schema
+---
+name: default
+#@schema/nullable
+owner: ""
+replicas: 1
+tags:
+- ""
+#@schema/type any=True
+extra:
+  a: 1
+`)
+
+	valuesBytes := []byte(`
+#@data/values
+---
+name: from-file
+owner: me
+replicas: 3
+tags: [a, b]
+`)
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", tmplBytes)),
+		files.MustNewFileFromSource(files.NewBytesSource("schema.yml", schemaBytes)),
+		files.MustNewFileFromSource(files.NewBytesSource("values.yml", valuesBytes)),
+	})
+
+	t.Run("removes and nulls values", func(t *testing.T) {
+		ui := ui.NewTTY(false)
+		opts := cmdtpl.NewOptions()
+
+		opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+			KVsToUnset: []string{"name"},
+			KVsToNull:  []string{"owner"},
+		}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+		require.NoError(t, out.Err)
+		require.Len(t, out.Files, 1, "unexpected number of output files")
+
+		assert.Equal(t, `owner: null
+replicas: 3
+tags:
+- a
+- b
+extra:
+  a: 1
+name: default
+`, string(out.Files[0].Bytes()))
+	})
+
+	t.Run("unsets values after setting them regardless of flag order", func(t *testing.T) {
+		ui := ui.NewTTY(false)
+		opts := cmdtpl.NewOptions()
+
+		opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+			KVsFromStrings: []string{"name=from-flag", "owner=from-flag"},
+			KVsToUnset:     []string{"name"},
+			KVsToNull:      []string{"owner"},
+		}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+		require.NoError(t, out.Err)
+		require.Len(t, out.Files, 1, "unexpected number of output files")
+
+		assert.Equal(t, `owner: null
+replicas: 3
+tags:
+- a
+- b
+extra:
+  a: 1
+name: default
+`, string(out.Files[0].Bytes()))
+	})
+
+	t.Run("fails to unset key not in schema", func(t *testing.T) {
+		ui := ui.NewTTY(false)
+		opts := cmdtpl.NewOptions()
+
+		opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+			KVsToUnset: []string{"nme"},
+		}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+		require.EqualError(t, out.Err, "Unsetting data value: Unable to unset key 'nme': Expected key 'nme' to be defined in data values schema (allowed keys: name, owner, replicas, tags, extra)")
+	})
+
+	t.Run("fails to unset values without schema defaults", func(t *testing.T) {
+		ui := ui.NewTTY(false)
+		opts := cmdtpl.NewOptions()
+
+		opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+			KVsToUnset: []string{"tags[0]"},
+		}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+		require.EqualError(t, out.Err, "Unsetting data value: Unable to unset key 'tags[0]': Expected key to end with a map key, but was array index [0] (array items do not have defaults in data values schema)")
+
+		opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+			KVsToUnset: []string{"extra.a"},
+		}
+
+		out = opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+		require.EqualError(t, out.Err, "Unsetting data value: Unable to unset key 'extra.a': Expected key to have a default in data values schema, but it is within a value of any type (i.e. annotated with @schema/type any=True)")
+	})
+
+	t.Run("fails to unset values of other libraries", func(t *testing.T) {
+		ui := ui.NewTTY(false)
+		opts := cmdtpl.NewOptions()
+
+		opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+			KVsToUnset: []string{"@lib:name"},
+		}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+		require.EqualError(t, out.Err, "Unsetting data value: Unable to unset key '@lib:name': Expected key to belong to root library (data values of other libraries cannot be checked against their schemas)")
+	})
+
+	t.Run("fails to null key that is not nullable", func(t *testing.T) {
+		ui := ui.NewTTY(false)
+		opts := cmdtpl.NewOptions()
+
+		opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+			KVsToNull: []string{"replicas"},
+		}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+		require.EqualError(t, out.Err, "Nulling data value: Unable to set key 'replicas' to null: Expected key to be nullable in data values schema (i.e. annotated with @schema/nullable)")
+	})
+}
@@ -3,6 +3,10 @@
 
 package template
 
+import (
+	"time"
+)
+
 // CmdFlags interface decouples this package from
 // depending on cobra.Command/flags concrete types.
 type CmdFlags interface {
@@ -17,4 +21,7 @@ type CmdFlags interface {
 
 	StringSliceVar(p *[]string, name string, value []string, usage string)
 	StringSliceVarP(p *[]string, name, shorthand string, value []string, usage string)
+
+	IntVar(p *int, name string, value int, usage string)
+	DurationVar(p *time.Duration, name string, value time.Duration, usage string)
 }
@@ -88,6 +88,55 @@ end`)
 	assert.Equal(t, expectedYAMLTplData, string(file.Bytes()))
 }
 
+func TestStarlarkTests(t *testing.T) {
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", []byte(`
+#@ load("funcs.star", "double")
+val: #@ double(2)`))),
+		files.MustNewFileFromSource(files.NewBytesSource("funcs.star", []byte(`
+def double(x):
+  return x * 2
+end`))),
+		files.MustNewFileFromSource(files.NewBytesSource("funcs_test.star", []byte(`
+load("@ytt:assert", "assert")
+load("funcs.star", "double")
+def test_double():
+  assert.equals(4, double(2))
+end
+def test_double_str():
+  assert.equals("aa", double(1))
+end`))),
+	})
+
+	t.Run("excludes test files from rendering", func(t *testing.T) {
+		out := cmdtpl.NewOptions().RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		require.NoError(t, out.Err)
+
+		bs, err := out.DocSet.AsBytes()
+		require.NoError(t, err)
+		require.Equal(t, "val: 4\n", string(bs))
+		require.Empty(t, out.StarlarkTests)
+	})
+
+	t.Run("runs test functions", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.RunStarlarkTests = true
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		require.NoError(t, out.Err)
+		require.Nil(t, out.DocSet)
+
+		require.Len(t, out.StarlarkTests, 2)
+		require.Equal(t, "funcs_test.star", out.StarlarkTests[0].Path)
+		require.Equal(t, "test_double", out.StarlarkTests[0].Name)
+		require.NoError(t, out.StarlarkTests[0].Err)
+		require.Equal(t, "test_double_str", out.StarlarkTests[1].Name)
+		require.Error(t, out.StarlarkTests[1].Err)
+		require.Contains(t, out.StarlarkTests[1].Err.Error(), "funcs_test.star:8:")
+		require.Contains(t, out.StarlarkTests[1].Err.Error(), "Not equal")
+	})
+}
+
 func TestDataListRelativeToDir(t *testing.T) {
 	yamlTplData := []byte(`
 #@ load("@ytt:data", "data")
@@ -0,0 +1,130 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package template
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vmware-tanzu/carvel-ytt/pkg/schema"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace/datavalues"
+)
+
+const (
+	envDefaultMapKeySep = "__"
+
+	envKeyCaseVerbatim = "verbatim"
+	envKeyCaseLower    = "lower"
+	envKeyCaseCamel    = "camel"
+)
+
+var envKeyCases = []string{envKeyCaseVerbatim, envKeyCaseLower, envKeyCaseCamel}
+
+// envKeyResolver translates the name of an env variable (sans prefix) into data value key pieces.
+type envKeyResolver struct {
+	sep      string
+	keyCase  string
+	rootType schema.Type
+}
+
+func (s *DataValuesFlags) envKeyResolver(libRef string, dvSchema *datavalues.Schema) (envKeyResolver, error) {
+	resolver := envKeyResolver{sep: s.EnvKeySep, keyCase: s.EnvKeyCase}
+
+	if len(resolver.sep) == 0 {
+		resolver.sep = envDefaultMapKeySep
+	}
+	if len(resolver.keyCase) == 0 {
+		resolver.keyCase = envKeyCaseVerbatim
+	}
+
+	switch resolver.keyCase {
+	case envKeyCaseVerbatim, envKeyCaseLower, envKeyCaseCamel:
+	default:
+		return envKeyResolver{}, fmt.Errorf("Expected env key case to be one of: %s, but was '%s'",
+			strings.Join(envKeyCases, ", "), resolver.keyCase)
+	}
+
+	if s.EnvKeysSchema {
+		if len(libRef) > 0 {
+			return envKeyResolver{}, fmt.Errorf("Expected env variables resolved against schema to target root library, but were addressed to '%s'", libRef)
+		}
+		if dvSchema == nil {
+			dvSchema = datavalues.NewNullSchema()
+		}
+		resolver.rootType = dvSchema.GetDocumentType().GetValueType()
+	}
+
+	return resolver, nil
+}
+
+// Resolve splits name into key pieces. When resolving against a schema,
+// pieces are matched to the schema's map keys ignoring case, '_' and '-'
+// (e.g. DB_HOST matches either db.host or dbHost); returns false if no match was found.
+func (r envKeyResolver) Resolve(name string) ([]string, bool) {
+	pieces := strings.Split(name, r.sep)
+	if r.rootType == nil {
+		return r.convertCase(pieces), true
+	}
+	return r.match(r.rootType, pieces)
+}
+
+func (r envKeyResolver) match(typeOfValue schema.Type, pieces []string) ([]string, bool) {
+	if len(pieces) == 0 {
+		return nil, true
+	}
+
+	switch typed := typeOfValue.(type) {
+	case *schema.NullType:
+		return r.match(typed.GetValueType(), pieces)
+
+	case *schema.AnyType:
+		return r.convertCase(pieces), true
+
+	case *schema.MapType:
+		for i := 1; i <= len(pieces); i++ {
+			candidate := r.normalize(strings.Join(pieces[:i], ""))
+			for _, item := range typed.Items {
+				key, isStr := item.Key.(string)
+				if !isStr || r.normalize(key) != candidate {
+					continue
+				}
+				rest, found := r.match(item.GetValueType(), pieces[i:])
+				if found {
+					return append([]string{key}, rest...), true
+				}
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func (r envKeyResolver) normalize(key string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+}
+
+func (r envKeyResolver) convertCase(pieces []string) []string {
+	var result []string
+	for _, piece := range pieces {
+		switch r.keyCase {
+		case envKeyCaseLower:
+			piece = strings.ToLower(piece)
+		case envKeyCaseCamel:
+			piece = r.camelCase(piece)
+		}
+		result = append(result, piece)
+	}
+	return result
+}
+
+func (r envKeyResolver) camelCase(piece string) string {
+	var result string
+	for i, word := range strings.Split(strings.ToLower(piece), "_") {
+		if i > 0 && len(word) > 0 {
+			word = strings.ToUpper(word[:1]) + word[1:]
+		}
+		result += word
+	}
+	return result
+}
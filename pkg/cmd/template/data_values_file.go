@@ -26,3 +26,16 @@ func (f DataValuesFile) AsOverlay() (*yamlmeta.Document, error) {
 
 	return doc, nil
 }
+
+// AsOverlayWithOverlayAnnotations is like AsOverlay, but allows the file to contain overlay annotations
+// (e.g. to replace an entire array or remove a key).
+func (f DataValuesFile) AsOverlayWithOverlayAnnotations() (*yamlmeta.Document, error) {
+	doc := f.doc.DeepCopy()
+
+	err := overlay.AnnotateForPlainMergeWithOverlays(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
@@ -6,13 +6,17 @@ package template
 import (
 	"fmt"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/k14s/starlark-go/starlark"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/experiments"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/filepos"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/schema"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/template"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace/datavalues"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace/ref"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/yamlmeta"
@@ -20,20 +24,31 @@ import (
 )
 
 const (
-	dvsKVSep      = "="
-	dvsMapKeySep  = "."
-	libraryKeySep = ":"
+	dvsKVSep       = "="
+	dvsMapKeySep   = "."
+	dvsArrayAppend = "+"
+	libraryKeySep  = ":"
 )
 
+var dvsArrayIdxRegexp = regexp.MustCompile(`\[(\d+|\+)\]$`)
+
 type DataValuesFlags struct {
 	EnvFromStrings []string
 	EnvFromYAML    []string
+	EnvKeySep      string
+	EnvKeyCase     string
+	EnvKeysSchema  bool
 
 	KVsFromStrings []string
 	KVsFromYAML    []string
 	KVsFromFiles   []string
+	KVsToUnset     []string
+	KVsToNull      []string
+
+	FromFiles          []string
+	FromFilesTemplated bool
 
-	FromFiles []string
+	SchemaFiles []string
 
 	Inspect        bool
 	InspectSchema  bool
@@ -43,6 +58,7 @@ type DataValuesFlags struct {
 	ReadFilesFunc func(paths string) ([]*files.File, error)
 
 	*files.SymlinkAllowOpts
+	*files.HTTPSourceOpts
 }
 
 // Set registers data values ingestion flags and wires-up those flags up to this
@@ -50,12 +66,22 @@ type DataValuesFlags struct {
 func (s *DataValuesFlags) Set(cmdFlags CmdFlags) {
 	cmdFlags.StringArrayVar(&s.EnvFromStrings, "data-values-env", nil, "Extract data values (as strings) from prefixed env vars (format: PREFIX for PREFIX_all__key1=str) (can be specified multiple times)")
 	cmdFlags.StringArrayVar(&s.EnvFromYAML, "data-values-env-yaml", nil, "Extract data values (parsed as YAML) from prefixed env vars (format: PREFIX for PREFIX_all__key1=true) (can be specified multiple times)")
+	cmdFlags.StringVar(&s.EnvKeySep, "data-values-env-key-sep", envDefaultMapKeySep, "Separator between nested keys in data values env vars (e.g. '_' for PREFIX_ALL_KEY1=str)")
+	cmdFlags.StringVar(&s.EnvKeyCase, "data-values-env-key-case", envKeyCaseVerbatim, fmt.Sprintf("Case conversion applied to keys of data values env vars (one of: %s)", strings.Join(envKeyCases, ", ")))
+	cmdFlags.BoolVar(&s.EnvKeysSchema, "data-values-env-keys-from-schema", false, "Resolve keys of data values env vars against data values schema (e.g. DB_HOST to db.host or dbHost) and fail on env vars that do not match")
 
-	cmdFlags.StringArrayVarP(&s.KVsFromStrings, "data-value", "v", nil, "Set specific data value to given value, as string (format: all.key1.subkey=123) (can be specified multiple times)")
-	cmdFlags.StringArrayVar(&s.KVsFromYAML, "data-value-yaml", nil, "Set specific data value to given value, parsed as YAML (format: all.key1.subkey=true) (can be specified multiple times)")
+	cmdFlags.StringArrayVarP(&s.KVsFromStrings, "data-value", "v", nil, "Set specific data value to given value, as string (format: all.key1.subkey=123, all.array[0].key=123 or all.array[+]=123 to append) (can be specified multiple times)")
+	cmdFlags.StringArrayVar(&s.KVsFromYAML, "data-value-yaml", nil, "Set specific data value to given value, parsed as YAML (format: all.key1.subkey=true, all.array[0].key=true or all.array[+]=true to append) (can be specified multiple times)")
 	cmdFlags.StringArrayVar(&s.KVsFromFiles, "data-value-file", nil, "Set specific data value to contents of a file (format: [@lib1:]all.key1.subkey={file path, HTTP URL, or '-' (i.e. stdin)}) (can be specified multiple times)")
+	cmdFlags.StringArrayVar(&s.KVsToUnset, "data-value-unset", nil, "Remove specific data value of root library, reverting it to its schema default (format: all.key1.subkey) "+
+		"(applied after --data-value and --data-value-yaml regardless of flag order; reverted key moves to the end of its map) (can be specified multiple times)")
+	cmdFlags.StringArrayVar(&s.KVsToNull, "data-value-null", nil, "Set specific data value to null (format: [@lib1:]all.key1.subkey) "+
+		"(applied after --data-value and --data-value-yaml regardless of flag order) (can be specified multiple times)")
 	cmdFlags.StringArrayVar(&s.FromFiles, "data-values-file", nil, "Set multiple data values via plain YAML files (format: [@lib1:]{file path, HTTP URL, or '-' (i.e. stdin)}) (can be specified multiple times)")
 
+	cmdFlags.StringArrayVar(&s.SchemaFiles, "data-values-schema-file", nil, "Overlay data values schema with the schema in given files (format: [@lib1:]{file path, HTTP URL, or '-' (i.e. stdin)}) (can be specified multiple times)")
+	cmdFlags.BoolVar(&s.FromFilesTemplated, "data-values-file-templated", false, "Allow overlay annotations (e.g. @overlay/replace, @overlay/remove, @overlay/match) in files given via --data-values-file")
+
 	cmdFlags.BoolVar(&s.Inspect, "data-values-inspect", false, "Determine the final data values (applying any overlays) and display that result")
 	if experiments.IsValidationsEnabled() {
 		cmdFlags.BoolVar(&s.SkipValidation, "dangerous-data-values-disable-validation", false, "Skip validating data values (not recommended: may result in templates failing or invalid output)")
@@ -75,7 +101,12 @@ type valueTransformFunc func(string) (interface{}, error)
 //
 // Returns a collection of overlays targeted for the root library and a separate collection of overlays "addressed" to
 // children libraries.
-func (s *DataValuesFlags) AsOverlays(strict bool) ([]*datavalues.Envelope, []*datavalues.Envelope, error) {
+//
+// When schema is given (i.e. not nil), it's used to resolve keys of data values env vars (see EnvKeysSchema),
+// and to check that unset keys have defaults (see KVsToUnset) and nulled keys are nullable (see KVsToNull).
+//
+// Unset and nulled keys are applied after all KVs (regardless of order of flags), hence override them.
+func (s *DataValuesFlags) AsOverlays(strict bool, schema *datavalues.Schema) ([]*datavalues.Envelope, []*datavalues.Envelope, error) {
 	plainValFunc := func(rawVal string) (interface{}, error) { return rawVal, nil }
 
 	yamlValFunc := func(rawVal string) (interface{}, error) {
@@ -101,7 +132,7 @@ func (s *DataValuesFlags) AsOverlays(strict bool) ([]*datavalues.Envelope, []*da
 	// since env vars are specific to command execution
 	for _, src := range []dataValuesFlagsSource{{s.EnvFromStrings, plainValFunc, "data-values-env"}, {s.EnvFromYAML, yamlValFunc, "data-values-env-yaml"}} {
 		for _, envPrefix := range src.Values {
-			vals, err := s.env(envPrefix, src)
+			vals, err := s.env(envPrefix, src, schema)
 			if err != nil {
 				return nil, nil, fmt.Errorf("Extracting data values from env under prefix '%s': %s", envPrefix, err)
 			}
@@ -120,6 +151,23 @@ func (s *DataValuesFlags) AsOverlays(strict bool) ([]*datavalues.Envelope, []*da
 		}
 	}
 
+	// Unsetting and nulling is as specific as KVs, but goes after them (regardless of flag order) to override their values
+	for _, key := range s.KVsToUnset {
+		val, err := s.unsetKey(key, schema)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Unsetting data value: %s", err)
+		}
+		result = append(result, val)
+	}
+
+	for _, key := range s.KVsToNull {
+		val, err := s.nullKey(key, schema)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Nulling data value: %s", err)
+		}
+		result = append(result, val)
+	}
+
 	// Finally KV files take precedence over rest
 	// (technically should be same level as KVs, but gotta pick one)
 	for _, file := range s.KVsFromFiles {
@@ -143,6 +191,41 @@ func (s *DataValuesFlags) AsOverlays(strict bool) ([]*datavalues.Envelope, []*da
 	return overlayValues, libraryOverlays, nil
 }
 
+// AsSchemaOverlays generates Data Values Schema overlays, one for each document in the files given via SchemaFiles.
+//
+// Files are evaluated in the context of the given (root) library; resulting overlays may be addressed to children
+// libraries.
+func (s *DataValuesFlags) AsSchemaOverlays(libraryExecution *workspace.LibraryExecution) ([]*datavalues.SchemaEnvelope, error) {
+	var result []*datavalues.SchemaEnvelope
+
+	for _, fullPath := range s.SchemaFiles {
+		libRef, path, err := s.libraryRefAndRemainder(fullPath)
+		if err != nil {
+			return nil, err
+		}
+
+		schemaFiles, err := s.asFiles(path)
+		if err != nil {
+			return nil, fmt.Errorf("Find files '%s': %s", path, err)
+		}
+
+		for _, schemaFile := range schemaFiles {
+			// Users may want to store other files (docs, etc.) within this directory; ignore those.
+			if schemaFile.IsImplied() && !(schemaFile.Type() == files.TypeYAML) {
+				continue
+			}
+
+			overlays, err := libraryExecution.SchemaOverlaysFromFile(schemaFile, libRef)
+			if err != nil {
+				return nil, fmt.Errorf("Extracting data values schema from file '%s': %s", schemaFile.RelativePath(), err)
+			}
+			result = append(result, overlays...)
+		}
+	}
+
+	return result, nil
+}
+
 func (s *DataValuesFlags) file(fullPath string, strict bool) ([]*datavalues.Envelope, error) {
 	libRef, path, err := s.libraryRefAndRemainder(fullPath)
 	if err != nil {
@@ -176,7 +259,7 @@ func (s *DataValuesFlags) file(fullPath string, strict bool) ([]*datavalues.Enve
 
 		for _, doc := range docSet.Items {
 			if doc.Value != nil {
-				dvsOverlay, err := NewDataValuesFile(doc).AsOverlay()
+				dvsOverlay, err := s.dataValuesFileAsOverlay(doc)
 				if err != nil {
 					return nil, fmt.Errorf("Checking data values file '%s': %s", path, err)
 				}
@@ -192,10 +275,16 @@ func (s *DataValuesFlags) file(fullPath string, strict bool) ([]*datavalues.Enve
 	return result, nil
 }
 
-func (s *DataValuesFlags) env(prefix string, src dataValuesFlagsSource) ([]*datavalues.Envelope, error) {
+func (s *DataValuesFlags) dataValuesFileAsOverlay(doc *yamlmeta.Document) (*yamlmeta.Document, error) {
+	if s.FromFilesTemplated {
+		return NewDataValuesFile(doc).AsOverlayWithOverlayAnnotations()
+	}
+	return NewDataValuesFile(doc).AsOverlay()
+}
+
+func (s *DataValuesFlags) env(prefix string, src dataValuesFlagsSource, schema *datavalues.Schema) ([]*datavalues.Envelope, error) {
 	const (
 		envKeyPrefix = "_"
-		envMapKeySep = "__"
 	)
 
 	result := []*datavalues.Envelope{}
@@ -210,6 +299,13 @@ func (s *DataValuesFlags) env(prefix string, src dataValuesFlagsSource) ([]*data
 		return nil, err
 	}
 
+	keys, err := s.envKeyResolver(libRef, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var unmatchedVars []string
+
 	for _, envVar := range envVars {
 		pieces := strings.SplitN(envVar, dvsKVSep, 2)
 		if len(pieces) != 2 {
@@ -225,10 +321,15 @@ func (s *DataValuesFlags) env(prefix string, src dataValuesFlagsSource) ([]*data
 			return nil, fmt.Errorf("Extracting data value from env variable '%s': %s", pieces[0], err)
 		}
 
-		// '__' gets translated into a '.' since periods may not be liked by shells
-		keyPieces := strings.Split(strings.TrimPrefix(pieces[0], keyPrefix+envKeyPrefix), envMapKeySep)
+		// separator (by default '__') gets translated into a '.' since periods may not be liked by shells
+		keyPieces, found := keys.Resolve(strings.TrimPrefix(pieces[0], keyPrefix+envKeyPrefix))
+		if !found {
+			unmatchedVars = append(unmatchedVars, pieces[0])
+			continue
+		}
+
 		desc := fmt.Sprintf("(%s arg) %s", src.Name, keyPrefix)
-		overlay := s.buildOverlay(keyPieces, val, desc, envVar)
+		overlay := s.buildOverlay(s.mapKeyPieces(keyPieces), val, desc, envVar)
 
 		dvs, err := datavalues.NewEnvelopeWithLibRef(overlay, libRef)
 		if err != nil {
@@ -238,6 +339,11 @@ func (s *DataValuesFlags) env(prefix string, src dataValuesFlagsSource) ([]*data
 		result = append(result, dvs)
 	}
 
+	if len(unmatchedVars) > 0 {
+		return nil, fmt.Errorf("Expected env variables to match keys in data values schema, but did not find match for: %s",
+			strings.Join(unmatchedVars, ", "))
+	}
+
 	return result, nil
 }
 
@@ -256,12 +362,144 @@ func (s *DataValuesFlags) kv(kv string, src dataValuesFlagsSource) (*datavalues.
 	if err != nil {
 		return nil, err
 	}
+	keyPieces, err := s.parseKeyPath(key)
+	if err != nil {
+		return nil, err
+	}
 	desc := fmt.Sprintf("(%s arg)", src.Name)
-	overlay := s.buildOverlay(strings.Split(key, dvsMapKeySep), val, desc, kv)
+	overlay := s.buildOverlay(keyPieces, val, desc, kv)
 
 	return datavalues.NewEnvelopeWithLibRef(overlay, libRef)
 }
 
+func (s *DataValuesFlags) unsetKey(fullKey string, schema *datavalues.Schema) (*datavalues.Envelope, error) {
+	libRef, key, err := s.libraryRefAndKey(fullKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(libRef) > 0 {
+		// schemas of other libraries are not known until those libraries are evaluated
+		return nil, fmt.Errorf("Unable to unset key '%s': Expected key to belong to root library "+
+			"(data values of other libraries cannot be checked against their schemas)", fullKey)
+	}
+	keyPieces, err := s.parseKeyPath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.checkUnsetKey(keyPieces, schema)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to unset key '%s': %s", key, err)
+	}
+
+	desc := fmt.Sprintf("(data-value-unset arg) %s", key)
+	overlay := s.buildOverlayWithOp(keyPieces, nil, yttoverlay.AnnotationRemove, desc, fullKey)
+
+	return datavalues.NewEnvelopeWithLibRef(overlay, libRef)
+}
+
+// checkUnsetKey ensures that removed value is filled back in with its schema default
+// (i.e. key is a map key defined in data values schema; nullable keys default to null).
+func (s *DataValuesFlags) checkUnsetKey(keyPieces []dataValuesKeyPiece, dvSchema *datavalues.Schema) error {
+	if dvSchema == nil {
+		return nil
+	}
+	if _, isAny := dvSchema.GetDocumentType().GetValueType().(*schema.AnyType); isAny {
+		// without schema, data values do not have defaults and are simply removed
+		return nil
+	}
+
+	if lastPiece := keyPieces[len(keyPieces)-1]; lastPiece.IsArray && !lastPiece.Append {
+		return fmt.Errorf("Expected key to end with a map key, but was array index [%d] (array items do not have defaults in data values schema)", lastPiece.ArrayIdx)
+	}
+
+	typeOfValue, err := s.schemaTypeAt(keyPieces, dvSchema)
+	if err != nil {
+		return err
+	}
+	if typeOfValue == nil {
+		return fmt.Errorf("Expected key to have a default in data values schema, but it is within a value of any type (i.e. annotated with @schema/type any=True)")
+	}
+	return nil
+}
+
+func (s *DataValuesFlags) nullKey(fullKey string, dvSchema *datavalues.Schema) (*datavalues.Envelope, error) {
+	libRef, key, err := s.libraryRefAndKey(fullKey)
+	if err != nil {
+		return nil, err
+	}
+	keyPieces, err := s.parseKeyPath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(libRef) == 0 {
+		typeOfValue, err := s.schemaTypeAt(keyPieces, dvSchema)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to set key '%s' to null: %s", key, err)
+		}
+		switch typeOfValue.(type) {
+		case nil, *schema.AnyType, *schema.NullType:
+		default:
+			return nil, fmt.Errorf("Unable to set key '%s' to null: Expected key to be nullable in data values schema (i.e. annotated with @schema/nullable)", key)
+		}
+	}
+
+	desc := fmt.Sprintf("(data-value-null arg) %s", key)
+	overlay := s.buildOverlay(keyPieces, nil, desc, fullKey)
+
+	return datavalues.NewEnvelopeWithLibRef(overlay, libRef)
+}
+
+// schemaTypeAt locates the type of the value at keyPieces within dvSchema.
+// Returns nil type (and no error) when the value is not constrained by schema (e.g. within "any" type).
+func (s *DataValuesFlags) schemaTypeAt(keyPieces []dataValuesKeyPiece, dvSchema *datavalues.Schema) (schema.Type, error) {
+	if dvSchema == nil {
+		return nil, nil
+	}
+
+	currType := dvSchema.GetDocumentType().GetValueType()
+
+	for _, piece := range keyPieces {
+		if piece.Append {
+			return nil, fmt.Errorf("Expected array index, but was '[%s]'", dvsArrayAppend)
+		}
+		if nullType, ok := currType.(*schema.NullType); ok {
+			currType = nullType.GetValueType()
+		}
+
+		switch typed := currType.(type) {
+		case *schema.AnyType:
+			return nil, nil
+
+		case *schema.MapType:
+			if piece.IsArray {
+				return nil, fmt.Errorf("Expected array, but data values schema defines a map (at %s)", typed.Position.AsCompactString())
+			}
+			if !typed.AllowsKey(piece.MapKey) {
+				return nil, fmt.Errorf("Expected key '%s' to be defined in data values schema (allowed keys: %s)",
+					piece.MapKey, strings.Join(typed.AllowedKeys(), ", "))
+			}
+			for _, item := range typed.Items {
+				if item.Key == piece.MapKey {
+					currType = item.GetValueType()
+				}
+			}
+
+		case *schema.ArrayType:
+			if !piece.IsArray {
+				return nil, fmt.Errorf("Expected map, but data values schema defines an array (at %s)", typed.Position.AsCompactString())
+			}
+			currType = typed.GetValueType().GetValueType()
+
+		default:
+			return nil, fmt.Errorf("Expected key '%s' to be within a map or array in data values schema, but was %s", piece.MapKey, currType.String())
+		}
+	}
+
+	return currType, nil
+}
+
 func (s *DataValuesFlags) parseYAML(data string, strict bool) (interface{}, error) {
 	docSet, err := yamlmeta.NewParser(yamlmeta.ParserOpts{Strict: strict}).ParseBytes([]byte(data), "")
 	if err != nil {
@@ -293,8 +531,12 @@ func (s *DataValuesFlags) kvFile(kv string) (*datavalues.Envelope, error) {
 	if err != nil {
 		return nil, err
 	}
+	keyPieces, err := s.parseKeyPath(key)
+	if err != nil {
+		return nil, err
+	}
 	desc := fmt.Sprintf("(data-value-file arg) %s=%s", key, pieces[1])
-	overlay := s.buildOverlay(strings.Split(key, dvsMapKeySep), string(contents), desc, string(contents))
+	overlay := s.buildOverlay(keyPieces, string(contents), desc, string(contents))
 
 	return datavalues.NewEnvelopeWithLibRef(overlay, libRef)
 }
@@ -331,56 +573,152 @@ func (DataValuesFlags) libraryRefAndRemainder(arg string) (string, string, error
 	return "", arg, nil
 }
 
-func (s *DataValuesFlags) buildOverlay(keyPieces []string, value interface{}, desc string, line string) *yamlmeta.Document {
-	resultMap := &yamlmeta.Map{}
-	currMap := resultMap
-	var lastMapItem *yamlmeta.MapItem
+// dataValuesKeyPiece is a single step in a data value key path: either a map key, or an array item
+// (addressed by index or appended, e.g. "hosts[0]" or "hosts[+]").
+type dataValuesKeyPiece struct {
+	MapKey   string
+	IsArray  bool
+	Append   bool
+	ArrayIdx int64
+}
+
+// parseKeyPath splits a data value key (e.g. "ingress.hosts[0].name") into pieces.
+func (DataValuesFlags) parseKeyPath(key string) ([]dataValuesKeyPiece, error) {
+	var result []dataValuesKeyPiece
+	for _, mapKey := range strings.Split(key, dvsMapKeySep) {
+		var arrayPieces []dataValuesKeyPiece
+
+		// only trailing well-formed indexes are considered (e.g. "key[0][+]");
+		// any other use of brackets is kept as part of the map key
+		for {
+			match := dvsArrayIdxRegexp.FindStringSubmatch(mapKey)
+			if match == nil {
+				break
+			}
+			piece := dataValuesKeyPiece{IsArray: true}
+			if match[1] == dvsArrayAppend {
+				piece.Append = true
+			} else {
+				idx, err := strconv.ParseInt(match[1], 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("Parsing array index in key '%s': %s", key, err)
+				}
+				piece.ArrayIdx = idx
+			}
+			arrayPieces = append([]dataValuesKeyPiece{piece}, arrayPieces...)
+			mapKey = strings.TrimSuffix(mapKey, match[0])
+		}
+
+		if len(mapKey) == 0 && len(arrayPieces) > 0 {
+			return nil, fmt.Errorf("Expected array index in key '%s' to follow a map key (e.g. 'key[0]')", key)
+		}
+		result = append(result, dataValuesKeyPiece{MapKey: mapKey})
+		result = append(result, arrayPieces...)
+	}
+	return result, nil
+}
+
+// mapKeyPieces converts plain map keys into key pieces.
+func (DataValuesFlags) mapKeyPieces(keys []string) []dataValuesKeyPiece {
+	var result []dataValuesKeyPiece
+	for _, key := range keys {
+		result = append(result, dataValuesKeyPiece{MapKey: key})
+	}
+	return result
+}
+
+func (s *DataValuesFlags) buildOverlay(keyPieces []dataValuesKeyPiece, value interface{}, desc string, line string) *yamlmeta.Document {
+	return s.buildOverlayWithOp(keyPieces, value, yttoverlay.AnnotationReplace, desc, line)
+}
+
+// buildOverlayWithOp generates an overlay for the value at keyPieces,
+// applying lastOp (either overlay/replace or overlay/remove) to the last piece.
+func (s *DataValuesFlags) buildOverlayWithOp(keyPieces []dataValuesKeyPiece, value interface{}, lastOp template.AnnotationName, desc string, line string) *yamlmeta.Document {
+	var resultValue interface{}
+	var lastItem yamlmeta.Node
+	setLastValue := func(val interface{}) { resultValue = val }
 
 	pos := filepos.NewPosition(1)
 	pos.SetFile(desc)
 	pos.SetLine(line)
 
 	for _, piece := range keyPieces {
-		newMap := &yamlmeta.Map{}
-		lastMapItem = &yamlmeta.MapItem{Key: piece, Value: newMap, Position: pos}
-
-		// Data values schemas should be enough to provide key checking/validations.
-		lastMapItem.SetAnnotations(template.NodeAnnotations{
-			yttoverlay.AnnotationMatch: template.NodeAnnotation{
-				Kwargs: []starlark.Tuple{{
-					starlark.String(yttoverlay.MatchAnnotationKwargMissingOK),
-					starlark.Bool(true),
-				}},
-			},
-		})
-
-		currMap.Items = append(currMap.Items, lastMapItem)
-		currMap = newMap
+		var collection interface{}
+
+		if piece.IsArray {
+			arrayItem := &yamlmeta.ArrayItem{Position: pos}
+			if piece.Append {
+				arrayItem.SetAnnotations(template.NodeAnnotations{
+					yttoverlay.AnnotationAppend: template.NodeAnnotation{},
+				})
+			} else {
+				// Index must exist; missing items are not implicitly added
+				arrayItem.SetAnnotations(template.NodeAnnotations{
+					yttoverlay.AnnotationMatch: template.NodeAnnotation{
+						Kwargs: []starlark.Tuple{{
+							starlark.String(yttoverlay.MatchAnnotationKwargBy),
+							yttoverlay.IndexMatcher(piece.ArrayIdx),
+						}},
+					},
+				})
+			}
+			collection = &yamlmeta.Array{Items: []*yamlmeta.ArrayItem{arrayItem}, Position: pos}
+			setLastValue(collection)
+			setLastValue = func(val interface{}) { arrayItem.Value = val }
+			lastItem = arrayItem
+		} else {
+			mapItem := &yamlmeta.MapItem{Key: piece.MapKey, Position: pos}
+
+			// Data values schemas should be enough to provide key checking/validations.
+			mapItem.SetAnnotations(template.NodeAnnotations{
+				yttoverlay.AnnotationMatch: template.NodeAnnotation{
+					Kwargs: []starlark.Tuple{{
+						starlark.String(yttoverlay.MatchAnnotationKwargMissingOK),
+						starlark.Bool(true),
+					}},
+				},
+			})
+			collection = &yamlmeta.Map{Items: []*yamlmeta.MapItem{mapItem}, Position: pos}
+			setLastValue(collection)
+			setLastValue = func(val interface{}) { mapItem.Value = val }
+			lastItem = mapItem
+		}
 	}
 
-	lastMapItem.Value = yamlmeta.NewASTFromInterface(value)
+	setLastValue(yamlmeta.NewASTFromInterface(value))
 
 	// Explicitly replace entire value at given key
 	// (this allows to specify non-scalar data values)
-	existingAnns := template.NewAnnotations(lastMapItem)
-	existingAnns[yttoverlay.AnnotationReplace] = template.NodeAnnotation{
-		Kwargs: []starlark.Tuple{{
-			starlark.String(yttoverlay.ReplaceAnnotationKwargOrAdd),
-			starlark.Bool(true),
-		}},
+	// Appended items are added as is.
+	existingAnns := template.NewAnnotations(lastItem)
+	switch {
+	case existingAnns.Has(yttoverlay.AnnotationAppend):
+	case lastOp == yttoverlay.AnnotationRemove:
+		existingAnns[yttoverlay.AnnotationRemove] = template.NodeAnnotation{}
+	default:
+		existingAnns[yttoverlay.AnnotationReplace] = template.NodeAnnotation{
+			Kwargs: []starlark.Tuple{{
+				starlark.String(yttoverlay.ReplaceAnnotationKwargOrAdd),
+				starlark.Bool(true),
+			}},
+		}
 	}
-	lastMapItem.SetAnnotations(existingAnns)
+	lastItem.SetAnnotations(existingAnns)
 
-	return &yamlmeta.Document{Value: resultMap, Position: pos}
+	return &yamlmeta.Document{Value: resultValue, Position: pos}
 }
 
 // asFiles enumerates the files that are found at "path"
 //
 // If a DataValuesFlags.ReadFilesFunc has been injected, that service is used.
-// Otherwise, uses files.NewSortedFilesFromPaths() is used.
+// Otherwise, uses files.NewSortedFilesFromPathsWithOpts() is used.
 func (s *DataValuesFlags) asFiles(path string) ([]*files.File, error) {
 	if s.ReadFilesFunc != nil {
 		return s.ReadFilesFunc(path)
 	}
-	return files.NewSortedFilesFromPaths([]string{path}, *s.SymlinkAllowOpts)
+	httpOpts := files.HTTPSourceOpts{}
+	if s.HTTPSourceOpts != nil {
+		httpOpts = *s.HTTPSourceOpts
+	}
+	return files.NewSortedFilesFromPathsWithOpts([]string{path}, *s.SymlinkAllowOpts, httpOpts)
 }
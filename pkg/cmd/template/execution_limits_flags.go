@@ -0,0 +1,81 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package template
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/vmware-tanzu/carvel-ytt/pkg/template"
+)
+
+const (
+	maxExecutionStepsFlagName = "max-execution-steps"
+	executionTimeoutFlagName  = "execution-timeout"
+	maxMemoryFlagName         = "max-memory-mib"
+
+	bytesInMiB = 1024 * 1024
+)
+
+// ExecutionLimitsFlags bound evaluation of templates (by default there are no limits).
+type ExecutionLimitsFlags struct {
+	MaxSteps     int
+	Timeout      time.Duration
+	MaxMemoryMiB int
+}
+
+// NewExecutionLimitsFlags returns flags that default to given limits.
+func NewExecutionLimitsFlags(defaults template.ExecutionLimits) ExecutionLimitsFlags {
+	return ExecutionLimitsFlags{
+		MaxSteps:     int(defaults.MaxSteps),
+		Timeout:      defaults.Timeout,
+		MaxMemoryMiB: int(defaults.MaxMemory / bytesInMiB),
+	}
+}
+
+// Set registers execution limits flags (current values are used as defaults) and wires-up those flags
+// up to this ExecutionLimitsFlags to be set when the corresponding cobra.Command is executed.
+func (s *ExecutionLimitsFlags) Set(cmdFlags CmdFlags) {
+	cmdFlags.IntVar(&s.MaxSteps, maxExecutionStepsFlagName, s.MaxSteps,
+		"Maximum number of loop iterations and function calls while evaluating templates (0 means no limit)")
+	cmdFlags.DurationVar(&s.Timeout, executionTimeoutFlagName, s.Timeout,
+		"Maximum duration of evaluating templates (e.g. 30s) (0 means no limit)")
+	cmdFlags.IntVar(&s.MaxMemoryMiB, maxMemoryFlagName, s.MaxMemoryMiB,
+		"Maximum growth of memory (in MiB) while evaluating templates (0 means no limit)")
+}
+
+// Limits validates flags and converts them to template.ExecutionLimits.
+func (s ExecutionLimitsFlags) Limits() (template.ExecutionLimits, error) {
+	if s.MaxSteps < 0 {
+		return template.ExecutionLimits{}, fmt.Errorf("Expected --%s to be non-negative, but was %d", maxExecutionStepsFlagName, s.MaxSteps)
+	}
+	if s.Timeout < 0 {
+		return template.ExecutionLimits{}, fmt.Errorf("Expected --%s to be non-negative, but was %s", executionTimeoutFlagName, s.Timeout)
+	}
+	if s.MaxMemoryMiB < 0 {
+		return template.ExecutionLimits{}, fmt.Errorf("Expected --%s to be non-negative, but was %d", maxMemoryFlagName, s.MaxMemoryMiB)
+	}
+	return template.ExecutionLimits{
+		MaxSteps:  uint64(s.MaxSteps),
+		Timeout:   s.Timeout,
+		MaxMemory: uint64(s.MaxMemoryMiB) * bytesInMiB,
+	}, nil
+}
+
+// ExecutionLimitsAsArgs converts limits to template command flags (e.g. to pass them to a ytt subprocess).
+func ExecutionLimitsAsArgs(limits template.ExecutionLimits) []string {
+	var args []string
+	if limits.MaxSteps > 0 {
+		args = append(args, "--"+maxExecutionStepsFlagName, strconv.FormatUint(limits.MaxSteps, 10))
+	}
+	if limits.Timeout > 0 {
+		args = append(args, "--"+executionTimeoutFlagName, limits.Timeout.String())
+	}
+	if limits.MaxMemory > 0 {
+		// Round up so that limit is not lost for values smaller than 1MiB
+		args = append(args, "--"+maxMemoryFlagName, strconv.FormatUint((limits.MaxMemory+bytesInMiB-1)/bytesInMiB, 10))
+	}
+	return args
+}
@@ -0,0 +1,149 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package template_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	cmdtpl "github.com/vmware-tanzu/carvel-ytt/pkg/cmd/template"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+)
+
+func TestExecutionLimitsFailsWhenLoopTakesTooManySteps(t *testing.T) {
+	tplData := `---
+#@ for i in range(100000000):
+#@ end
+items: 0
+`
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", []byte(tplData))),
+	})
+
+	opts := cmdtpl.NewOptions()
+	opts.ExecutionLimitsFlags.MaxSteps = 1000
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+	require.Error(t, out.Err)
+	require.Contains(t, out.Err.Error(), "execution_limits: Expected evaluation to take at most 1000 steps")
+	require.Contains(t, out.Err.Error(), "tpl.yml:2 | #@ for i in range(100000000):")
+}
+
+func TestExecutionLimitsFailsWhenRecursionTakesTooManySteps(t *testing.T) {
+	tplData := `#@ load("funcs.star", "fib")
+---
+fib: #@ fib(40)
+`
+	funcsStarData := `def fib(n):
+  return n if n < 2 else fib(n-1) + fib(n-2)
+end
+`
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", []byte(tplData))),
+		files.MustNewFileFromSource(files.NewBytesSource("funcs.star", []byte(funcsStarData))),
+	})
+
+	opts := cmdtpl.NewOptions()
+	opts.ExecutionLimitsFlags.MaxSteps = 1000
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+	require.Error(t, out.Err)
+	require.Contains(t, out.Err.Error(), "Expected evaluation to take at most 1000 steps")
+	require.Contains(t, out.Err.Error(), "in fib")
+}
+
+func TestExecutionLimitsFailsWhenComprehensionTakesTooLong(t *testing.T) {
+	tplData := `---
+items: #@ len([i for i in range(100000000)])
+`
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", []byte(tplData))),
+	})
+
+	opts := cmdtpl.NewOptions()
+	opts.ExecutionLimitsFlags.Timeout = 50 * time.Millisecond
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+	require.Error(t, out.Err)
+	require.Contains(t, out.Err.Error(), "Expected evaluation to complete within 50ms")
+	require.Contains(t, out.Err.Error(), "tpl.yml:2 | items: #@ len([i for i in range(100000000)])")
+}
+
+func TestExecutionLimitsFailsWhenFilteredComprehensionTakesTooLong(t *testing.T) {
+	tplData := `---
+items: #@ len([i for i in range(100000000) if False])
+`
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", []byte(tplData))),
+	})
+
+	opts := cmdtpl.NewOptions()
+	opts.ExecutionLimitsFlags.Timeout = 50 * time.Millisecond
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+	require.Error(t, out.Err)
+	require.Contains(t, out.Err.Error(), "Expected evaluation to complete within 50ms")
+}
+
+func TestExecutionLimitsFailsWhenMemoryGrowsTooMuch(t *testing.T) {
+	tplData := `---
+items: #@ len([i for i in range(100000000)])
+`
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", []byte(tplData))),
+	})
+
+	opts := cmdtpl.NewOptions()
+	opts.ExecutionLimitsFlags.MaxMemoryMiB = 10
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+	require.Error(t, out.Err)
+	require.Contains(t, out.Err.Error(), "Expected evaluation to use at most 10485760 bytes of memory")
+}
+
+func TestExecutionLimitsSucceedsWithinLimits(t *testing.T) {
+	tplData := `#@ load("funcs.star", "fib")
+---
+fib: #@ fib(10)
+`
+	funcsStarData := `def fib(n):
+  return n if n < 2 else fib(n-1) + fib(n-2)
+end
+`
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", []byte(tplData))),
+		files.MustNewFileFromSource(files.NewBytesSource("funcs.star", []byte(funcsStarData))),
+	})
+
+	opts := cmdtpl.NewOptions()
+	opts.ExecutionLimitsFlags = cmdtpl.ExecutionLimitsFlags{MaxSteps: 1000, Timeout: time.Minute, MaxMemoryMiB: 100}
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+	require.NoError(t, out.Err)
+	require.Equal(t, "fib: 55\n", string(out.Files[0].Bytes()))
+}
+
+func TestExecutionLimitsRejectsNegativeLimits(t *testing.T) {
+	tplData := `---
+items: 0
+`
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", []byte(tplData))),
+	})
+
+	opts := cmdtpl.NewOptions()
+	opts.ExecutionLimitsFlags.MaxSteps = -1
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+	require.EqualError(t, out.Err, "Expected --max-execution-steps to be non-negative, but was -1")
+}
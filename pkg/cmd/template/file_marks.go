@@ -6,9 +6,12 @@ package template
 import (
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/yamlmeta"
 )
 
 type FileMarksOpts struct {
@@ -18,21 +21,26 @@ type FileMarksOpts struct {
 // Set registers file mark flags and wires-up those flags up to this
 // FileMarksOpts to be set when the corresponding cobra.Command is executed.
 func (s *FileMarksOpts) Set(cmdFlags CmdFlags) {
-	cmdFlags.StringArrayVar(&s.FileMarks, "file-mark", nil, "File mark (ie change file path, mark as non-template) (format: file:key=value) (can be specified multiple times)")
+	cmdFlags.StringArrayVar(&s.FileMarks, "file-mark", nil, "File mark (ie change file path, mark as non-template) (format: file:key=value) "+
+		"(file may contain '*' or '**/*', or be a glob, e.g. 'glob:**/*.secret.yml', or a regexp, e.g. 're:config/.*\\.yml') "+
+		"(can be specified multiple times; marks are applied in given order, so for the same key later marks win)")
 }
 
 func (s *FileMarksOpts) Apply(filesToProcess []*files.File) ([]*files.File, error) {
 	var exclusiveForOutputFiles []*files.File
 
 	for _, mark := range s.FileMarks {
-		pieces := strings.SplitN(mark, ":", 2)
-		if len(pieces) != 2 {
+		sepIdx := s.pathSeparatorIndex(mark)
+		if sepIdx == -1 {
 			return nil, fmt.Errorf("Expected file mark '%s' to be in format path:key=value", mark)
 		}
 
-		path := pieces[0]
+		pathMatcher, err := s.fileMarkMatcher(mark[:sepIdx])
+		if err != nil {
+			return nil, fmt.Errorf("Expected file mark '%s' path to be a valid pattern: %s", mark, err)
+		}
 
-		kv := strings.SplitN(pieces[1], "=", 2)
+		kv := strings.SplitN(mark[sepIdx+1:], "=", 2)
 		if len(kv) != 2 {
 			return nil, fmt.Errorf("Expected file mark '%s' key-value portion to be in format key=value", mark)
 		}
@@ -40,7 +48,7 @@ func (s *FileMarksOpts) Apply(filesToProcess []*files.File) ([]*files.File, erro
 		var matched bool
 
 		for i, file := range filesToProcess {
-			if s.fileMarkMatches(file, path) {
+			if pathMatcher.MatchString(file.OriginalRelativePath()) {
 				matched = true
 
 				switch kv[0] {
@@ -83,10 +91,36 @@ func (s *FileMarksOpts) Apply(filesToProcess []*files.File) ([]*files.File, erro
 					switch kv[1] {
 					case "true":
 						file.MarkForOutput(true)
+					case "false":
+						file.MarkForOutput(false)
+					default:
+						return nil, fmt.Errorf("Unknown value in file mark '%s'", mark)
+					}
+
+				case "library":
+					switch kv[1] {
+					case "true":
+						file.MarkLibrary(true)
+					case "false":
+						file.MarkLibrary(false)
 					default:
 						return nil, fmt.Errorf("Unknown value in file mark '%s'", mark)
 					}
 
+				case "priority":
+					priority, err := strconv.Atoi(kv[1])
+					if err != nil {
+						return nil, fmt.Errorf("Expected file mark '%s' priority to be an integer", mark)
+					}
+					file.MarkPriority(priority)
+
+				case "output-format":
+					_, err := workspace.OutputFormatPrinter(kv[1], yamlmeta.YAMLPrinterOpts{})
+					if err != nil {
+						return nil, fmt.Errorf("Unknown value in file mark '%s': %s", mark, err)
+					}
+					file.MarkOutputFormat(kv[1])
+
 				case "exclusive-for-output":
 					switch kv[1] {
 					case "true":
@@ -122,16 +156,68 @@ func (s *FileMarksOpts) Apply(filesToProcess []*files.File) ([]*files.File, erro
 	return filesToProcess, nil
 }
 
+const (
+	fileMarkGlobPrefix   = "glob:"
+	fileMarkRegexpPrefix = "re:"
+)
+
 var (
 	quotedMultiLevel  = regexp.QuoteMeta("**/*")
 	quotedSingleLevel = regexp.QuoteMeta("*")
+
+	fileMarkKeyValueRegexp = regexp.MustCompile(`^[a-z-]+=`)
 )
 
-func (s *FileMarksOpts) fileMarkMatches(file *files.File, path string) bool {
-	path = regexp.QuoteMeta(path)
-	path = strings.Replace(path, quotedMultiLevel, ".+", 1)
-	path = strings.Replace(path, quotedSingleLevel, "[^/]+", 1)
-	return regexp.MustCompile("^" + path + "$").MatchString(file.OriginalRelativePath())
+// pathSeparatorIndex returns index of ':' separating path from key=value (-1 if there is none).
+func (s *FileMarksOpts) pathSeparatorIndex(mark string) int {
+	if !strings.HasPrefix(mark, fileMarkRegexpPrefix) {
+		prefixLen := 0
+		if strings.HasPrefix(mark, fileMarkGlobPrefix) {
+			prefixLen = len(fileMarkGlobPrefix)
+		}
+		sepIdx := strings.Index(mark[prefixLen:], ":")
+		if sepIdx == -1 {
+			return -1
+		}
+		return prefixLen + sepIdx
+	}
+
+	// Regexp may contain ':' itself, hence separator is the one followed by key,
+	// choosing the last one since value (e.g. path) may contain ':' but not key=
+	sepIdx := -1
+	for i := len(fileMarkRegexpPrefix); i < len(mark); i++ {
+		if mark[i] == ':' && fileMarkKeyValueRegexp.MatchString(mark[i+1:]) {
+			sepIdx = i
+		}
+	}
+	return sepIdx
+}
+
+// fileMarkMatcher compiles file mark path into a regexp matched against whole relative path.
+// Path is either a regexp (prefixed with 're:'), a glob (prefixed with 'glob:', see files.GlobToRegexp) with '{a,b}' alternatives,
+// or a literal path where (first) '**/*' matches any non-empty path and (first) '*' matches any non-empty file name.
+func (s *FileMarksOpts) fileMarkMatcher(path string) (*regexp.Regexp, error) {
+	switch {
+	case strings.HasPrefix(path, fileMarkRegexpPrefix):
+		return regexp.Compile("^(?:" + strings.TrimPrefix(path, fileMarkRegexpPrefix) + ")$")
+
+	case strings.HasPrefix(path, fileMarkGlobPrefix):
+		return s.globMatcher(strings.TrimPrefix(path, fileMarkGlobPrefix))
+
+	default:
+		path = regexp.QuoteMeta(path)
+		path = strings.Replace(path, quotedMultiLevel, ".+", 1)
+		path = strings.Replace(path, quotedSingleLevel, "[^/]+", 1)
+		return regexp.Compile("^" + path + "$")
+	}
+}
+
+func (s *FileMarksOpts) globMatcher(path string) (*regexp.Regexp, error) {
+	globRegexp, err := files.GlobToRegexp(path, files.GlobOpts{Alternatives: true})
+	if err != nil {
+		return nil, err
+	}
+	return regexp.Compile("^" + globRegexp + "$")
 }
 
 func (s *FileMarksOpts) clearNils(input []*files.File) []*files.File {
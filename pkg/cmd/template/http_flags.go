@@ -0,0 +1,28 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package template
+
+import (
+	"time"
+
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+)
+
+// HTTPFlags configure how files are fetched from HTTP URLs (shared by commands that read such files).
+type HTTPFlags struct {
+	*files.HTTPSourceOpts
+}
+
+// Set registers HTTP flags and wires-up those flags up to HTTPSourceOpts
+// to be set when the corresponding cobra.Command is executed.
+func (s HTTPFlags) Set(cmdFlags CmdFlags) {
+	cmdFlags.DurationVar(&s.HTTPSourceOpts.Timeout, "http-timeout", time.Minute,
+		"Timeout for each request fetching files from HTTP URLs (pin content with URL fragment, e.g. 'https://host/config.yml#sha256=<hex digest>')")
+	cmdFlags.IntVar(&s.HTTPSourceOpts.Retries, "http-retries", 2,
+		"Number of times to retry requests fetching files from HTTP URLs (on network errors, 5xx and 429 responses)")
+	cmdFlags.StringArrayVar(&s.HTTPSourceOpts.HeadersFromEnv, "http-header-from-env", nil,
+		"Set header on requests to given host with value of env variable (format: host=Header-Name=ENV_VAR) (can be specified multiple times)")
+	cmdFlags.StringVar(&s.HTTPSourceOpts.CacheDir, "http-cache-dir", "",
+		"Cache files fetched from HTTP URLs in given directory (used for pinned URLs without any request, and for other URLs when requests fail)")
+}
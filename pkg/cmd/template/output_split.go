@@ -0,0 +1,132 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package template
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/yamlmeta"
+)
+
+var (
+	outputSplitPlaceholderRegexp = regexp.MustCompile(`\{([^{}]*)\}`)
+)
+
+// outputSplitter places each YAML document into its own output file, naming it based on
+// a format with placeholders referring to document's keys (e.g. '{kind}-{metadata.name}.yaml').
+// Documents are printed in format of their template's output file (JSON ones get '.json' or '.jsonl' extension).
+type outputSplitter struct {
+	nameFormat string
+	yamlOpts   yamlmeta.YAMLPrinterOpts
+}
+
+// Files produces output files for non-empty documents of templates.
+func (s outputSplitter) Files(tplDocSets []workspace.TemplateDocSet) ([]files.OutputFile, error) {
+	var result []files.OutputFile
+	docNumByPath := map[string]int{}
+	docNum := 0
+
+	for _, tplDocSet := range tplDocSets {
+		printerFunc, err := workspace.OutputFormatPrinter(tplDocSet.Format, s.yamlOpts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, doc := range tplDocSet.DocSet.Items {
+			docNum++
+
+			// print via document set so that empty (and injected) documents are skipped
+			docBytes, err := (&yamlmeta.DocumentSet{Items: []*yamlmeta.Document{doc}}).AsBytesWithPrinter(printerFunc)
+			if err != nil {
+				return nil, fmt.Errorf("Marshaling document %d: %s", docNum, err)
+			}
+			if len(docBytes) == 0 {
+				continue
+			}
+
+			relPath, err := s.name(doc)
+			if err != nil {
+				return nil, fmt.Errorf("Naming output file for document %d (%s): %s", docNum, doc.Position.AsCompactString(), err)
+			}
+			relPath = workspace.OutputFormatPath(relPath, tplDocSet.Format)
+
+			if prevNum, found := docNumByPath[relPath]; found {
+				return nil, fmt.Errorf("Expected output file names to be unique, but documents %d and %d are both named '%s' "+
+					"(hint: include more document keys in --output-split format)", prevNum, docNum, relPath)
+			}
+			docNumByPath[relPath] = docNum
+
+			result = append(result, files.NewOutputFile(relPath, docBytes, files.TypeYAML))
+		}
+	}
+
+	return result, nil
+}
+
+func (s outputSplitter) name(doc *yamlmeta.Document) (string, error) {
+	var placeholderErr error
+
+	name := outputSplitPlaceholderRegexp.ReplaceAllStringFunc(s.nameFormat, func(placeholder string) string {
+		keyPath := strings.Trim(placeholder, "{}")
+
+		keyVal, err := s.lookup(doc.Value, keyPath)
+		if err != nil {
+			if placeholderErr == nil {
+				placeholderErr = err
+			}
+			return ""
+		}
+		return keyVal
+	})
+	if placeholderErr != nil {
+		return "", placeholderErr
+	}
+
+	cleanName := path.Clean(name)
+	if len(name) == 0 || path.IsAbs(cleanName) || cleanName == ".." || strings.HasPrefix(cleanName, "../") || strings.HasSuffix(name, "/") {
+		return "", fmt.Errorf("Expected file name '%s' to be a relative file path within output directory", name)
+	}
+
+	return cleanName, nil
+}
+
+func (s outputSplitter) lookup(val interface{}, keyPath string) (string, error) {
+	if len(keyPath) == 0 {
+		return "", fmt.Errorf("Expected placeholder to specify document key (e.g. '{metadata.name}')")
+	}
+
+	for _, key := range strings.Split(keyPath, ".") {
+		typedMap, ok := val.(*yamlmeta.Map)
+		if !ok {
+			return "", fmt.Errorf("Expected document to have key '%s', but found %s instead of map", keyPath, yamlmeta.TypeName(val))
+		}
+
+		var found bool
+		for _, item := range typedMap.Items {
+			if item.Key == key {
+				val, found = item.Value, true
+			}
+		}
+		if !found {
+			return "", fmt.Errorf("Expected document to have key '%s', but did not", keyPath)
+		}
+	}
+
+	switch typedVal := val.(type) {
+	case string:
+		if len(typedVal) == 0 {
+			return "", fmt.Errorf("Expected document key '%s' to be non-empty", keyPath)
+		}
+		return typedVal, nil
+	case int, int64, uint64, float64, bool:
+		return fmt.Sprintf("%v", typedVal), nil
+	default:
+		return "", fmt.Errorf("Expected document key '%s' to be a string, number or boolean, but was %s", keyPath, yamlmeta.TypeName(val))
+	}
+}
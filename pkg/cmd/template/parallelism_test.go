@@ -0,0 +1,139 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package template_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cmdtpl "github.com/vmware-tanzu/carvel-ytt/pkg/cmd/template"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/template"
+)
+
+func TestParallelismProducesSameOutputAsSequentialEvaluation(t *testing.T) {
+	opts := cmdtpl.NewOptions()
+	opts.Parallelism = 1
+
+	expectedOut := opts.RunWithFiles(cmdtpl.Input{Files: parallelismTestFiles()}, ui.NewTTY(false))
+	require.NoError(t, expectedOut.Err)
+	require.Len(t, expectedOut.Files, 100)
+
+	opts = cmdtpl.NewOptions()
+	opts.Parallelism = 8
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: parallelismTestFiles()}, ui.NewTTY(false))
+	require.NoError(t, out.Err)
+	require.Equal(t, len(expectedOut.Files), len(out.Files))
+
+	for i, expectedFile := range expectedOut.Files {
+		require.Equal(t, expectedFile.RelativePath(), out.Files[i].RelativePath())
+		require.Equal(t, string(expectedFile.Bytes()), string(out.Files[i].Bytes()))
+	}
+	require.Equal(t, "id: 7\nitems:\n- 2\n- 4\n- 6\noverlaid: true\n---\nlib: tpl7\noverlaid: true\n",
+		string(out.Files[57].Bytes()))
+}
+
+func TestParallelismReportsErrorOfFirstFailingFile(t *testing.T) {
+	opts := cmdtpl.NewOptions()
+	opts.Parallelism = 1
+
+	expectedOut := opts.RunWithFiles(cmdtpl.Input{Files: parallelismTestFiles(12, 31)}, ui.NewTTY(false))
+	require.Error(t, expectedOut.Err)
+	require.Contains(t, expectedOut.Err.Error(), "tpl12.yml")
+
+	for i := 0; i < 5; i++ {
+		opts := cmdtpl.NewOptions()
+		opts.Parallelism = 8
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: parallelismTestFiles(12, 31)}, ui.NewTTY(false))
+		require.Error(t, out.Err)
+		require.Equal(t, expectedOut.Err.Error(), out.Err.Error())
+	}
+}
+
+func TestParallelismStopsEvaluatingFilesAfterFailure(t *testing.T) {
+	opts := cmdtpl.NewOptions()
+	opts.Parallelism = 8
+	opts.ProfileFlags.Profile = true
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: parallelismTestFiles(0)}, ui.NewTTY(false))
+	require.Error(t, out.Err)
+	require.Contains(t, out.Err.Error(), "tpl00.yml")
+
+	// Only some of text templates may be evaluated concurrently with the failing one
+	var evaluatedTexts []string
+	for _, entry := range out.Profile {
+		if entry.Category == template.ProfileCategoryTemplate && strings.HasSuffix(entry.Name, ".txt") {
+			evaluatedTexts = append(evaluatedTexts, entry.Name)
+		}
+	}
+	require.Less(t, len(evaluatedTexts), 25, "evaluated: %v", evaluatedTexts)
+}
+
+func TestParallelismRejectsNegativeValue(t *testing.T) {
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", []byte("a: 1\n"))),
+	})
+
+	opts := cmdtpl.NewOptions()
+	opts.Parallelism = -1
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+	require.EqualError(t, out.Err, "Expected --parallelism to be non-negative, but was -1")
+}
+
+// parallelismTestFiles returns enough templates (using Starlark files, libraries and overlays)
+// for them to be evaluated concurrently; templates with given indexes fail.
+func parallelismTestFiles(failing ...int) []*files.File {
+	result := []*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("funcs.star", []byte(`
+def double(x):
+  return [i * 2 for i in x]
+end
+`))),
+		files.MustNewFileFromSource(files.NewBytesSource("values.yml", []byte(`#@data/values
+---
+items: [1, 2, 3]
+`))),
+		files.MustNewFileFromSource(files.NewBytesSource("overlay.yml", []byte(`#@ load("@ytt:overlay", "overlay")
+#@overlay/match by=overlay.all, expects="1+"
+---
+#@overlay/match missing_ok=True
+overlaid: true
+`))),
+		files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/lib/tpl.yml", []byte(`#@ load("@ytt:data", "data")
+---
+lib: #@ data.values.name
+`))),
+		files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/lib/values.yml", []byte(`#@data/values
+---
+name: default
+`))),
+	}
+
+	for i := 0; i < 50; i++ {
+		tplData := fmt.Sprintf(`#@ load("@ytt:data", "data")
+#@ load("@ytt:library", "library")
+#@ load("funcs.star", "double")
+#@ lib = library.get("lib").with_data_values({"name": "tpl%d"})
+---
+id: %d
+items: #@ double(data.values.items)
+--- #@ lib.eval()[0]
+`, i, i)
+		for _, failingIdx := range failing {
+			if i == failingIdx {
+				tplData += fmt.Sprintf("--- #@ fail(%d)\n", i)
+			}
+		}
+		result = append(result, files.MustNewFileFromSource(files.NewBytesSource(fmt.Sprintf("tpl%02d.yml", i), []byte(tplData))))
+		result = append(result, files.MustNewFileFromSource(files.NewBytesSource(fmt.Sprintf("tpl%02d.txt", i), []byte(fmt.Sprintf("(@= str(%d) @)", i)))))
+	}
+
+	return files.NewSortedFiles(result)
+}
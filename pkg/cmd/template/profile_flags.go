@@ -0,0 +1,80 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/template"
+)
+
+// ProfileFlags configure collection of time spent during evaluation.
+type ProfileFlags struct {
+	Profile   bool
+	PprofPath string
+}
+
+// Set registers profiling flags and wires-up those flags up to this
+// ProfileFlags to be set when the corresponding cobra.Command is executed.
+func (s *ProfileFlags) Set(cmdFlags CmdFlags) {
+	cmdFlags.BoolVar(&s.Profile, "profile", false,
+		"Print time spent evaluating templates, functions, overlays and data values (to stderr)")
+	cmdFlags.StringVar(&s.PprofPath, "profile-pprof", "",
+		"Write profile of Starlark evaluation in pprof format to a file (e.g. for use with 'go tool pprof')")
+}
+
+// StartPprof starts writing pprof profile (if requested); returned function finishes writing it.
+//
+// Since Starlark profiler is global, only single evaluation should be profiled at a time.
+func (s ProfileFlags) StartPprof() (func() error, error) {
+	if len(s.PprofPath) == 0 {
+		return func() error { return nil }, nil
+	}
+
+	file, err := os.Create(s.PprofPath)
+	if err != nil {
+		return nil, fmt.Errorf("Creating pprof profile file: %s", err)
+	}
+
+	err = starlark.StartProfile(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("Starting pprof profile: %s", err)
+	}
+
+	return func() error {
+		err := starlark.StopProfile()
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("Writing pprof profile: %s", err)
+		}
+		return nil
+	}, nil
+}
+
+// ProfileAsTable formats profile entries (in given order) as a table.
+func ProfileAsTable(entries []template.ProfileEntry) string {
+	var buf bytes.Buffer
+
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Total\tCalls\tAverage\tCategory\tName\n")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", formatProfileDuration(entry.Total),
+			entry.Calls, formatProfileDuration(entry.Total/time.Duration(entry.Calls)), entry.Category, entry.Name)
+	}
+	w.Flush()
+
+	return buf.String()
+}
+
+func formatProfileDuration(d time.Duration) string {
+	return d.Round(time.Microsecond).String()
+}
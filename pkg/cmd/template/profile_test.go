@@ -0,0 +1,95 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package template_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cmdtpl "github.com/vmware-tanzu/carvel-ytt/pkg/cmd/template"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/template"
+)
+
+func TestProfile(t *testing.T) {
+	tplData := []byte(`#@ load("@ytt:data", "data")
+#@ load("funcs.star", "fib", "safe")
+---
+fib: #@ fib(data.values.num)
+safe: #@ safe()
+`)
+	funcsStarData := []byte(`load("@ytt:assert", "assert")
+def fib(n):
+  if n < 2:
+    return n
+  end
+  return fib(n-1) + fib(n-2)
+end
+def fail():
+  assert.fail("boom")
+end
+def safe():
+  return assert.try_to(fail)[1] != None
+end
+`)
+	overlayData := []byte(`#@ load("@ytt:overlay", "overlay")
+#@overlay/match by=overlay.all
+---
+#@overlay/match missing_ok=True
+added: true
+`)
+	valuesData := []byte(`#@data/values
+---
+num: 10
+`)
+
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("tpl.yml", tplData)),
+		files.MustNewFileFromSource(files.NewBytesSource("funcs.star", funcsStarData)),
+		files.MustNewFileFromSource(files.NewBytesSource("overlay.yml", overlayData)),
+		files.MustNewFileFromSource(files.NewBytesSource("values.yml", valuesData)),
+	})
+
+	pprofPath := filepath.Join(t.TempDir(), "profile.pprof")
+
+	opts := cmdtpl.NewOptions()
+	opts.ProfileFlags = cmdtpl.ProfileFlags{Profile: true, PprofPath: pprofPath}
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+	require.NoError(t, out.Err)
+	require.Equal(t, "fib: 55\nsafe: true\nadded: true\n", string(out.Files[0].Bytes()))
+
+	calls := map[string]int{}
+	for i, entry := range out.Profile {
+		calls[string(entry.Category)+" "+entry.Name] = entry.Calls
+		if i > 0 {
+			require.GreaterOrEqual(t, out.Profile[i-1].Total, entry.Total)
+		}
+	}
+	require.Equal(t, map[string]int{
+		"template tpl.yml":                1,
+		"template funcs.star":             2,
+		"template overlay.yml":            1,
+		"template values.yml":             1,
+		"function fib (funcs.star:2)":     177,
+		"function fail (funcs.star:8)":    1,
+		"function safe (funcs.star:11)":   1,
+		"overlay overlay.yml:3":           1,
+		"data-values values (values.yml)": 1,
+		"data-values schema":              1,
+	}, calls)
+
+	table := cmdtpl.ProfileAsTable(out.Profile)
+	require.Contains(t, table, "Total")
+	require.Regexp(t, `\n\S+\s+177\s+\S+\s+function\s+fib \(funcs.star:2\)\n`, table)
+
+	pprofStat, err := os.Stat(pprofPath)
+	require.NoError(t, err)
+	require.NotZero(t, pprofStat.Size())
+
+	require.Nil(t, template.NewProfiler().Entries())
+}
@@ -18,11 +18,20 @@ import (
 type RegularFilesSourceOpts struct {
 	files []string
 
-	outputDir   string
-	OutputFiles string
-	OutputType  OutputType
+	outputDir     string
+	outputSyncDir string
+	OutputFiles   string
+	OutputArchive string
+	OutputSplit   string
+	OutputType    OutputType
+
+	// OutputFilesFormat is the format of output files produced by YAML templates (see files.OutputFormats)
+	OutputFilesFormat string
+	// YAMLPrinterOpts configure style of YAML output (both standard output and output files)
+	YAMLPrinterOpts yamlmeta.YAMLPrinterOpts
 
 	*files.SymlinkAllowOpts
+	*files.HTTPSourceOpts
 }
 
 // OutputType holds the user's desire for two (2) categories of output:
@@ -35,11 +44,36 @@ type OutputType struct {
 // Set registers flags related to sourcing ordinary files/directories and wires-up those flags up to this
 // RegularFilesSourceOpts to be set when the corresponding cobra.Command is executed.
 func (s *RegularFilesSourceOpts) Set(cmdFlags CmdFlags) {
-	cmdFlags.StringArrayVarP(&s.files, "file", "f", nil, "File(s) to process {filepath, HTTP URL, or '-' (i.e. stdin)} (can be specified multiple times)")
+	cmdFlags.StringArrayVarP(&s.files, "file", "f", nil, "File(s) to process {filepath, HTTP URL, archive (.tar, .tgz, .zip), 'oci-layout:' dirpath, or '-' (i.e. stdin)} (can be specified multiple times; directories honor '.yttignore' files)")
 
 	cmdFlags.StringVar(&s.outputDir, "dangerous-emptied-output-directory", "",
 		"Delete given directory, and then create it with output files")
+	cmdFlags.StringVar(&s.outputSyncDir, "output-directory-sync", "",
+		"Sync given directory with output files (writes only changed files and removes files it previously produced; other files are left alone)")
 	cmdFlags.StringVar(&s.OutputFiles, "output-files", "", "Add output files to given directory")
+	cmdFlags.StringVar(&s.OutputArchive, "output-archive", "",
+		"Write output files into given archive (.tar, .tgz, .zip) with fixed timestamps and ordering (i.e. reproducible)")
+	cmdFlags.StringVar(&s.OutputSplit, "output-split", "",
+		"Write each YAML document into its own output file named by format referencing document keys (e.g. '{kind}-{metadata.name}.yaml') "+
+			"in output format of its template (JSON documents get '.json' or '.jsonl' extension) "+
+			"(requires one of --output-files, --output-directory-sync, --output-archive or --dangerous-emptied-output-directory)")
+
+	cmdFlags.StringVar(&s.OutputFilesFormat, "output-files-format", files.OutputFormatYAML,
+		fmt.Sprintf("Configure format of output files of YAML templates (%s) (JSON files get '.json' or '.jsonl' extension; "+
+			"can be overridden per file via --file-mark 'file:output-format=json')", strings.Join(files.OutputFormats, ", ")))
+
+	cmdFlags.IntVar(&s.YAMLPrinterOpts.Indent, "output-yaml-indent", 2,
+		"Configure number of spaces per indentation level of YAML output (2-9)")
+	cmdFlags.BoolVar(&s.YAMLPrinterOpts.IndentSequences, "output-yaml-indent-sequences", false,
+		"Indent YAML sequences that are map values relative to their keys (by default sequence items start at the same column as their key)")
+	cmdFlags.StringVar(&s.YAMLPrinterOpts.QuoteStyle, "output-yaml-quote-style", "",
+		fmt.Sprintf("Configure quotes used for YAML strings that cannot be left unquoted (%s) "+
+			"(by default strings that look like other types are double quoted, e.g. \"123\", and strings with special characters are single quoted, e.g. 'a: b')",
+			strings.Join(yamlmeta.QuoteStyles, ", ")))
+	cmdFlags.IntVar(&s.YAMLPrinterOpts.LineWidth, "output-yaml-line-width", 0,
+		"Fold long YAML strings at given line width (0 disables folding)")
+	cmdFlags.BoolVar(&s.YAMLPrinterOpts.SortKeys, "output-yaml-sort-keys", false,
+		"Sort map keys alphabetically in YAML output (instead of keeping template order)")
 
 	cmdFlags.StringSliceVarP(&s.OutputType.Types, "output", "o", []string{RegularFilesOutputTypeYAML},
 		fmt.Sprintf("Configure output format. Can specify file format (%s) and/or schema type (%s) (can be specified multiple times)",
@@ -50,6 +84,16 @@ func (s *RegularFilesSourceOpts) Set(cmdFlags CmdFlags) {
 		"Symlinks to all destinations are allowed")
 	cmdFlags.StringSliceVar(&s.SymlinkAllowOpts.AllowedDstPaths, "allow-symlink-destination", nil,
 		"File paths to which symlinks are allowed (can be specified multiple times)")
+
+	HTTPFlags{s.HTTPSourceOpts}.Set(cmdFlags)
+}
+
+// HTTPOpts returns options of fetching files from HTTP URLs (zero value, if not configured).
+func (s RegularFilesSourceOpts) HTTPOpts() files.HTTPSourceOpts {
+	if s.HTTPSourceOpts == nil {
+		return files.HTTPSourceOpts{}
+	}
+	return *s.HTTPSourceOpts
 }
 
 type RegularFilesSource struct {
@@ -65,12 +109,12 @@ func (s *RegularFilesSource) HasInput() bool  { return len(s.opts.files) > 0 }
 func (s *RegularFilesSource) HasOutput() bool { return true }
 
 func (s *RegularFilesSource) Input() (Input, error) {
-	filesToProcess, err := files.NewSortedFilesFromPaths(s.opts.files, *s.opts.SymlinkAllowOpts)
+	filesToProcess, ignoredFiles, err := files.NewSortedFilesFromPathsWithIgnored(s.opts.files, *s.opts.SymlinkAllowOpts, s.opts.HTTPOpts())
 	if err != nil {
 		return Input{}, err
 	}
 
-	return Input{Files: filesToProcess}, nil
+	return Input{Files: filesToProcess, IgnoredFiles: ignoredFiles}, nil
 }
 
 func (s *RegularFilesSource) Output(out Output) error {
@@ -78,12 +122,32 @@ func (s *RegularFilesSource) Output(out Output) error {
 		return out.Err
 	}
 
+	if destFlags := s.outputDestinationFlags(); len(destFlags) > 1 {
+		return fmt.Errorf("Expected only one of --output-files, --output-directory-sync, --output-archive or "+
+			"--dangerous-emptied-output-directory to be given, but found: %s", strings.Join(destFlags, ", "))
+	}
+
+	outputFiles := out.Files
+	if len(s.opts.OutputSplit) > 0 {
+		var err error
+		outputFiles, err = s.splitOutputFiles(out)
+		if err != nil {
+			return err
+		}
+	}
+
 	nonYamlFileNames := []string{}
 	switch {
 	case len(s.opts.outputDir) > 0:
-		return files.NewOutputDirectory(s.opts.outputDir, out.Files, s.ui).Write()
+		return files.NewOutputDirectory(s.opts.outputDir, outputFiles, s.ui).Write()
+	case len(s.opts.outputSyncDir) > 0:
+		return files.NewOutputDirectory(s.opts.outputSyncDir, outputFiles, s.ui).Sync()
 	case len(s.opts.OutputFiles) > 0:
-		return files.NewOutputDirectory(s.opts.OutputFiles, out.Files, s.ui).WriteFiles()
+		return files.NewOutputDirectory(s.opts.OutputFiles, outputFiles, s.ui).WriteFiles()
+	case len(s.opts.OutputArchive) > 0:
+		return files.NewOutputArchive(s.opts.OutputArchive, outputFiles, s.ui).Write()
+	case len(s.opts.OutputSplit) > 0:
+		return fmt.Errorf("Expected --output-split to be used with one of --output-files, --output-directory-sync, --output-archive or --dangerous-emptied-output-directory")
 	default:
 		for _, file := range out.Files {
 			if file.Type() != files.TypeYAML {
@@ -101,9 +165,15 @@ func (s *RegularFilesSource) Output(out Output) error {
 
 	switch outputType {
 	case RegularFilesOutputTypeYAML:
-		printerFunc = nil
+		printerFunc = func(w io.Writer) yamlmeta.DocumentPrinter {
+			return yamlmeta.NewYAMLPrinterWithOpts(w, s.opts.YAMLPrinterOpts)
+		}
 	case RegularFilesOutputTypeJSON:
 		printerFunc = func(w io.Writer) yamlmeta.DocumentPrinter { return yamlmeta.NewJSONPrinter(w) }
+	case RegularFilesOutputTypeJSONPretty:
+		printerFunc = func(w io.Writer) yamlmeta.DocumentPrinter { return yamlmeta.NewPrettyJSONPrinter(w) }
+	case RegularFilesOutputTypeJSONLines:
+		printerFunc = func(w io.Writer) yamlmeta.DocumentPrinter { return yamlmeta.NewJSONLinesPrinter(w) }
 	case RegularFilesOutputTypePos:
 		printerFunc = func(w io.Writer) yamlmeta.DocumentPrinter {
 			return yamlmeta.WrappedFilePositionPrinter{yamlmeta.NewFilePositionPrinter(w)}
@@ -125,11 +195,50 @@ If you want to include those results, use the --output-files or --dangerous-empt
 	return nil
 }
 
+// outputDestinationFlags returns names of given flags that write output files (instead of printing to standard output).
+func (s *RegularFilesSource) outputDestinationFlags() []string {
+	var result []string
+	for _, flag := range []struct {
+		name  string
+		value string
+	}{
+		{"--dangerous-emptied-output-directory", s.opts.outputDir},
+		{"--output-directory-sync", s.opts.outputSyncDir},
+		{"--output-files", s.opts.OutputFiles},
+		{"--output-archive", s.opts.OutputArchive},
+	} {
+		if len(flag.value) > 0 {
+			result = append(result, flag.name)
+		}
+	}
+	return result
+}
+
+// splitOutputFiles replaces YAML output files with a file per YAML document printed in format
+// of its template's output file (other output files are kept as is).
+func (s *RegularFilesSource) splitOutputFiles(out Output) ([]files.OutputFile, error) {
+	var result []files.OutputFile
+	for _, file := range out.Files {
+		if file.Type() != files.TypeYAML {
+			result = append(result, file)
+		}
+	}
+
+	docFiles, err := outputSplitter{s.opts.OutputSplit, s.opts.YAMLPrinterOpts}.Files(out.templateDocSets)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(result, docFiles...), nil
+}
+
 // When the FileSource are RegularFilesSource, indicates which file format to use when rendering the output.
 const (
-	RegularFilesOutputTypeYAML = "yaml"
-	RegularFilesOutputTypeJSON = "json"
-	RegularFilesOutputTypePos  = "pos"
+	RegularFilesOutputTypeYAML       = "yaml"
+	RegularFilesOutputTypeJSON       = "json"
+	RegularFilesOutputTypeJSONPretty = "json-pretty"
+	RegularFilesOutputTypeJSONLines  = "jsonl"
+	RegularFilesOutputTypePos        = "pos"
 )
 
 // When the FileSource are RegularFilesSource, indicates which schema type to use when rendering the output.
@@ -140,7 +249,8 @@ const (
 
 // Collections of each category of output type
 var (
-	RegularFilesOutputFormatTypes = []string{RegularFilesOutputTypeYAML, RegularFilesOutputTypeJSON, RegularFilesOutputTypePos}
+	RegularFilesOutputFormatTypes = []string{RegularFilesOutputTypeYAML, RegularFilesOutputTypeJSON,
+		RegularFilesOutputTypeJSONPretty, RegularFilesOutputTypeJSONLines, RegularFilesOutputTypePos}
 	RegularFilesOutputSchemaTypes = []string{RegularFilesOutputTypeOpenAPI}
 	RegularFilesOutputTypes       = append(RegularFilesOutputFormatTypes, RegularFilesOutputSchemaTypes...)
 )
@@ -16,6 +16,7 @@ import (
 	cmdtpl "github.com/vmware-tanzu/carvel-ytt/pkg/cmd/template"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/yamlmeta"
 )
 
 func Test_Non_YAML_Files_With_No_Output_Flag_Produces_Warning(t *testing.T) {
@@ -232,6 +233,432 @@ func TestFileMarkMultipleExcludes(t *testing.T) {
 	runAndCompareWithOpts(t, opts, filesToProcess, "config: bar\n")
 }
 
+func TestFileMarkGlobsAndRegexps(t *testing.T) {
+	newFiles := func() []*files.File {
+		return files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("a.yml", []byte(`a: bar`))),
+			files.MustNewFileFromSource(files.NewBytesSource("b.yml", []byte(`b: bar`))),
+			files.MustNewFileFromSource(files.NewBytesSource("config.yml", []byte(`config: bar`))),
+			files.MustNewFileFromSource(files.NewBytesSource("d12.yml", []byte(`d: bar`))),
+			files.MustNewFileFromSource(files.NewBytesSource("e:1.yml", []byte(`e: bar`))),
+			files.MustNewFileFromSource(files.NewBytesSource("f[1].yml", []byte(`f: bar`))),
+			files.MustNewFileFromSource(files.NewBytesSource("nested/deep/x.secret.yml", []byte(`x: bar`))),
+			files.MustNewFileFromSource(files.NewBytesSource("y.secret.yml", []byte(`y: bar`))),
+		})
+	}
+
+	t.Run("excludes files matching globs and regexps", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.FileMarksOpts.FileMarks = []string{
+			"glob:**/*.secret.yml:exclude=true",
+			"glob:{a,b}.yml:exclude=true",
+			`re:d[0-9]+\.yml:exclude=true`,
+			`re:e:[0-9]\.yml:exclude=true`,
+			"f[1].yml:exclude=true",
+		}
+
+		runAndCompareWithOpts(t, opts, newFiles(), "config: bar\n")
+	})
+
+	t.Run("keeps literal paths with '*' and '**/*' unless prefixed", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.FileMarksOpts.FileMarks = []string{
+			"{a,b}.yml:exclude=true",
+		}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: newFiles()}, ui.NewTTY(false))
+		require.EqualError(t, out.Err, "Expected file mark '{a,b}.yml:exclude=true' to match at least one file by path, but did not")
+
+		opts.FileMarksOpts.FileMarks = []string{
+			"**/*.secret.yml:exclude=true",
+			"*[1].yml:exclude=true",
+		}
+
+		out = opts.RunWithFiles(cmdtpl.Input{Files: newFiles()}, ui.NewTTY(false))
+		require.NoError(t, out.Err)
+
+		var paths []string
+		for _, file := range out.Files {
+			paths = append(paths, file.RelativePath())
+		}
+		require.Equal(t, []string{"a.yml", "b.yml", "config.yml", "d12.yml", "e:1.yml"}, paths)
+	})
+
+	t.Run("allows ':' in regexp and value", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.FileMarksOpts.FileMarks = []string{
+			`re:(?:e:1)\.yml:path=x:1.yml`,
+			"glob:{a,b,d12,f?1?,config}.yml:exclude=true",
+			"glob:**/*.secret.yml:exclude=true",
+		}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: newFiles()}, ui.NewTTY(false))
+		require.NoError(t, out.Err)
+		require.Len(t, out.Files, 1)
+		require.Equal(t, "x:1.yml", out.Files[0].RelativePath())
+		require.Equal(t, "e: bar\n", string(out.Files[0].Bytes()))
+	})
+
+	t.Run("errors on invalid patterns", func(t *testing.T) {
+		opts := cmdtpl.NewOptions()
+		opts.FileMarksOpts.FileMarks = []string{"glob:{a,b.yml:exclude=true"}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: newFiles()}, ui.NewTTY(false))
+		require.EqualError(t, out.Err, "Expected file mark 'glob:{a,b.yml:exclude=true' path to be a valid pattern: Expected alternatives to be closed with '}'")
+
+		opts.FileMarksOpts.FileMarks = []string{"re:exclude=true"}
+
+		out = opts.RunWithFiles(cmdtpl.Input{Files: newFiles()}, ui.NewTTY(false))
+		require.EqualError(t, out.Err, "Expected file mark 're:exclude=true' to be in format path:key=value")
+	})
+}
+
+func TestFileMarkLibraryPriorityAndForOutput(t *testing.T) {
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("config.yml", []byte(`
+#@ load("helpers.yml", "value")
+a: #@ value()
+b: 0`))),
+		files.MustNewFileFromSource(files.NewBytesSource("helpers.yml", []byte(`
+#@ def value():
+#@   return 1
+#@ end`))),
+		files.MustNewFileFromSource(files.NewBytesSource("other.yml", []byte(`other: 1`))),
+		files.MustNewFileFromSource(files.NewBytesSource("overlay1.yml", []byte(`
+#@ load("@ytt:overlay", "overlay")
+#@overlay/match by=overlay.subset({"a": 1})
+---
+b: 1`))),
+		files.MustNewFileFromSource(files.NewBytesSource("overlay2.yml", []byte(`
+#@ load("@ytt:overlay", "overlay")
+#@overlay/match by=overlay.subset({"a": 1})
+---
+b: 2`))),
+	})
+
+	opts := cmdtpl.NewOptions()
+	opts.FileMarksOpts.FileMarks = []string{
+		"helpers.yml:library=true",
+		"other.yml:for-output=false",
+		"overlay2.yml:priority=-1",
+	}
+
+	runAndCompareWithOpts(t, opts, filesToProcess, "a: 1\nb: 1\n")
+}
+
+func TestOutputSplitWritesEachDocumentIntoItsOwnFile(t *testing.T) {
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("config.yml", []byte(`
+kind: ConfigMap
+metadata:
+  name: app
+---
+kind: Deployment
+metadata:
+  name: app
+---
+`))),
+		files.MustNewFileFromSource(files.NewBytesSource("other.yml", []byte(`
+kind: Service
+metadata:
+  name: app`))),
+		files.MustNewFileFromSource(files.NewBytesSource("notes.txt", []byte(`notes`))),
+	})
+
+	dir, err := ioutil.TempDir("", "ytt-output-split")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	opts := cmdtpl.NewOptions()
+	opts.RegularFilesSourceOpts.OutputFiles = dir
+	opts.RegularFilesSourceOpts.OutputSplit = "{metadata.name}/{kind}.yaml"
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+	require.NoError(t, out.Err)
+
+	testUI := ui.NewCustomWriterTTY(false, &bytes.Buffer{}, &bytes.Buffer{})
+	err = cmdtpl.NewRegularFilesSource(opts.RegularFilesSourceOpts, testUI).Output(out)
+	require.NoError(t, err)
+
+	for relPath, expected := range map[string]string{
+		"app/ConfigMap.yaml":  "kind: ConfigMap\nmetadata:\n  name: app\n",
+		"app/Deployment.yaml": "kind: Deployment\nmetadata:\n  name: app\n",
+		"app/Service.yaml":    "kind: Service\nmetadata:\n  name: app\n",
+		"notes.txt":           "notes",
+	} {
+		contents, err := ioutil.ReadFile(dir + "/" + relPath)
+		require.NoError(t, err)
+		require.Equal(t, expected, string(contents))
+	}
+	_, err = os.Stat(dir + "/config.yml")
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestOutputSplitPrintsDocumentsInFormatsOfTheirFiles(t *testing.T) {
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("config.yml", []byte(`
+kind: ConfigMap
+metadata:
+  name: app
+---
+kind: Deployment
+metadata:
+  name: app`))),
+		files.MustNewFileFromSource(files.NewBytesSource("other.yml", []byte(`
+kind: Service
+metadata:
+  name: app`))),
+	})
+
+	dir, err := ioutil.TempDir("", "ytt-output-split")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	opts := cmdtpl.NewOptions()
+	opts.FileMarksOpts.FileMarks = []string{"config.yml:output-format=json"}
+	opts.RegularFilesSourceOpts.OutputFiles = dir
+	opts.RegularFilesSourceOpts.OutputSplit = "{metadata.name}/{kind}.yaml"
+	opts.RegularFilesSourceOpts.YAMLPrinterOpts = yamlmeta.YAMLPrinterOpts{Indent: 4}
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+	require.NoError(t, out.Err)
+
+	testUI := ui.NewCustomWriterTTY(false, &bytes.Buffer{}, &bytes.Buffer{})
+	err = cmdtpl.NewRegularFilesSource(opts.RegularFilesSourceOpts, testUI).Output(out)
+	require.NoError(t, err)
+
+	for relPath, expected := range map[string]string{
+		"app/ConfigMap.json":  `{"kind":"ConfigMap","metadata":{"name":"app"}}`,
+		"app/Deployment.json": `{"kind":"Deployment","metadata":{"name":"app"}}`,
+		"app/Service.yaml":    "kind: Service\nmetadata:\n    name: app\n",
+	} {
+		contents, err := ioutil.ReadFile(dir + "/" + relPath)
+		require.NoError(t, err)
+		require.Equal(t, expected, string(contents))
+	}
+}
+
+func TestOutputSplitDetectsNameCollisionsAndMissingKeys(t *testing.T) {
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("config.yml", []byte(`
+kind: ConfigMap
+metadata:
+  name: app
+---
+kind: Deployment
+metadata:
+  name: app`))),
+	})
+
+	opts := cmdtpl.NewOptions()
+	opts.RegularFilesSourceOpts.OutputFiles = "unused"
+	testUI := ui.NewCustomWriterTTY(false, &bytes.Buffer{}, &bytes.Buffer{})
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+	require.NoError(t, out.Err)
+
+	opts.RegularFilesSourceOpts.OutputSplit = "{metadata.name}.yaml"
+	err := cmdtpl.NewRegularFilesSource(opts.RegularFilesSourceOpts, testUI).Output(out)
+	require.EqualError(t, err, "Expected output file names to be unique, but documents 1 and 2 are both named 'app.yaml' "+
+		"(hint: include more document keys in --output-split format)")
+
+	opts.RegularFilesSourceOpts.OutputSplit = "{metadata.namespace}-{kind}.yaml"
+	err = cmdtpl.NewRegularFilesSource(opts.RegularFilesSourceOpts, testUI).Output(out)
+	require.EqualError(t, err, "Naming output file for document 1 (config.yml:1): Expected document to have key 'metadata.namespace', but did not")
+
+	opts.RegularFilesSourceOpts.OutputFiles = ""
+	opts.RegularFilesSourceOpts.OutputSplit = "{kind}.yaml"
+	err = cmdtpl.NewRegularFilesSource(opts.RegularFilesSourceOpts, testUI).Output(out)
+	require.EqualError(t, err, "Expected --output-split to be used with one of --output-files, --output-directory-sync, --output-archive or --dangerous-emptied-output-directory")
+}
+
+func TestOutputArchiveRejectsOtherOutputDestinations(t *testing.T) {
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("config.yml", []byte(`a: 1`))),
+	})
+
+	opts := cmdtpl.NewOptions()
+	opts.RegularFilesSourceOpts.OutputArchive = "unused.tgz"
+	opts.RegularFilesSourceOpts.OutputFiles = "unused"
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+	require.NoError(t, out.Err)
+
+	testUI := ui.NewCustomWriterTTY(false, &bytes.Buffer{}, &bytes.Buffer{})
+	err := cmdtpl.NewRegularFilesSource(opts.RegularFilesSourceOpts, testUI).Output(out)
+	require.EqualError(t, err, "Expected only one of --output-files, --output-directory-sync, --output-archive or "+
+		"--dangerous-emptied-output-directory to be given, but found: --output-files, --output-archive")
+
+	_, err = os.Stat("unused.tgz")
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestOutputFilesFormatConvertsYAMLOutputFiles(t *testing.T) {
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("config.yml", []byte(`
+a: 1
+b: [x, "y"]`))),
+		files.MustNewFileFromSource(files.NewBytesSource("multi.yaml", []byte(`
+a: 1
+---
+b: 2`))),
+		files.MustNewFileFromSource(files.NewBytesSource("notes.txt", []byte(`notes`))),
+	})
+
+	opts := cmdtpl.NewOptions()
+	opts.RegularFilesSourceOpts.OutputFilesFormat = "json-pretty"
+	opts.FileMarksOpts.FileMarks = []string{"multi.yaml:output-format=jsonl"}
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+	require.NoError(t, out.Err)
+
+	result := map[string]string{}
+	for _, file := range out.Files {
+		result[file.RelativePath()] = string(file.Bytes())
+	}
+	require.Equal(t, map[string]string{
+		"config.json": "{\n  \"a\": 1,\n  \"b\": [\n    \"x\",\n    \"y\"\n  ]\n}\n",
+		"multi.jsonl": "{\"a\":1}\n{\"b\":2}\n",
+		"notes.txt":   "notes",
+	}, result)
+}
+
+func TestOutputFilesFormatIncludesDocumentsInStandardOutput(t *testing.T) {
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("config.yml", []byte(`
+a: 1`))),
+		files.MustNewFileFromSource(files.NewBytesSource("multi.yaml", []byte(`
+b: 2
+---
+c: 3`))),
+	})
+
+	stdout := bytes.NewBufferString("")
+	stderr := bytes.NewBufferString("")
+	ui := ui.NewCustomWriterTTY(false, stdout, stderr)
+
+	opts := cmdtpl.NewOptions()
+	opts.RegularFilesSourceOpts.OutputType.Types = []string{"yaml"}
+	opts.FileMarksOpts.FileMarks = []string{"multi.yaml:output-format=jsonl"}
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+	require.NoError(t, out.Err)
+
+	err := cmdtpl.NewRegularFilesSource(opts.RegularFilesSourceOpts, ui).Output(out)
+	require.NoError(t, err)
+
+	assertStdoutAndStderr(t, stdout, stderr, "a: 1\n---\nb: 2\n---\nc: 3\n", "")
+}
+
+func TestOutputFilesFormatRequiresSingleDocumentForJSON(t *testing.T) {
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("config.yml", []byte(`
+a: 1`))),
+		files.MustNewFileFromSource(files.NewBytesSource("multi.yaml", []byte(`
+#! empty documents are not output
+---
+a: 1
+---
+---
+b: 2`))),
+	})
+
+	opts := cmdtpl.NewOptions()
+	opts.RegularFilesSourceOpts.OutputFilesFormat = "json"
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+	require.EqualError(t, out.Err, "Marshaling template result 'multi.yaml': Expected single document to output in format 'json', "+
+		"but found 2 (hint: use 'jsonl' format, or split documents via --output-split)")
+}
+
+func TestOutputFilesFormatRejectsUnknownFormats(t *testing.T) {
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("multi.yaml", []byte(`
+a: 1
+---
+b: 2`))),
+	})
+
+	opts := cmdtpl.NewOptions()
+	opts.FileMarksOpts.FileMarks = []string{"multi.yaml:output-format=xml"}
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+	require.EqualError(t, out.Err, "Unknown value in file mark 'multi.yaml:output-format=xml': "+
+		"Unknown output format 'xml' (expected one of: yaml, json, json-pretty, jsonl)")
+}
+
+func TestOutputYAMLStyleKeepsDefaultStyle(t *testing.T) {
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("config.yml", []byte(`
+z:
+  nested:
+    b: "true"
+    a: [x, "it's"]
+a: long string that should be folded`))),
+	})
+
+	opts := cmdtpl.NewOptions()
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+	require.NoError(t, out.Err)
+
+	require.Len(t, out.Files, 1)
+	require.Equal(t, `z:
+  nested:
+    b: "true"
+    a:
+    - x
+    - it's
+a: long string that should be folded
+`, string(out.Files[0].Bytes()))
+}
+
+func TestOutputYAMLStyleAppliesIndentQuotesLineWidthAndKeySorting(t *testing.T) {
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("config.yml", []byte(`
+z:
+  nested:
+    b: "true"
+    a: [x, "it's"]
+a: long string that should be folded`))),
+	})
+
+	opts := cmdtpl.NewOptions()
+	opts.RegularFilesSourceOpts.YAMLPrinterOpts = yamlmeta.YAMLPrinterOpts{
+		Indent:          4,
+		IndentSequences: true,
+		QuoteStyle:      yamlmeta.QuoteStyleSingle,
+		LineWidth:       20,
+		SortKeys:        true,
+	}
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+	require.NoError(t, out.Err)
+
+	require.Len(t, out.Files, 1)
+	require.Equal(t, `a: long string that should
+    be folded
+z:
+    nested:
+        a:
+            - x
+            - it's
+        b: 'true'
+`, string(out.Files[0].Bytes()))
+}
+
+func TestOutputYAMLStyleRejectsInvalidStyle(t *testing.T) {
+	filesToProcess := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("config.yml", []byte(`a: 1`))),
+	})
+
+	opts := cmdtpl.NewOptions()
+	opts.RegularFilesSourceOpts.YAMLPrinterOpts = yamlmeta.YAMLPrinterOpts{QuoteStyle: "backtick"}
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+	require.EqualError(t, out.Err, "Checking YAML output style flags: Expected quote style to be one of: double, single, but was 'backtick'")
+}
+
 func assertStdoutAndStderr(t *testing.T, stdout *bytes.Buffer, stderr *bytes.Buffer, expectedStdOut string, expectedStdErr string) {
 	stdoutOutput, err := ioutil.ReadAll(stdout)
 	require.NoError(t, err, "reading stdout")
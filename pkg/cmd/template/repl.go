@@ -0,0 +1,47 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package template
+
+import (
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace"
+)
+
+// NewREPL loads given files and calculates data values (the same way as RunWithFiles does)
+// to return a REPL evaluating code in the context of the root library.
+func (o *Options) NewREPL(in Input, ui ui.UI) (*workspace.REPL, error) {
+	rootLibrary, err := o.newRootLibrary(in, ui)
+	if err != nil {
+		return nil, err
+	}
+
+	rootLibraryExecution, err := o.newRootLibraryExecution(rootLibrary, ui, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	schemaOverlays, err := o.DataValuesFlags.AsSchemaOverlays(rootLibraryExecution)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, librarySchemas, err := rootLibraryExecution.Schemas(schemaOverlays)
+	if err != nil {
+		return nil, err
+	}
+
+	valuesOverlays, libraryValuesOverlays, err := o.DataValuesFlags.AsOverlays(o.StrictYAML, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	values, libraryValues, err := rootLibraryExecution.Values(valuesOverlays, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	libraryValues = append(libraryValues, libraryValuesOverlays...)
+
+	return rootLibraryExecution.REPL(values, libraryValues, librarySchemas), nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package template_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/stretchr/testify/require"
+	cmdtpl "github.com/vmware-tanzu/carvel-ytt/pkg/cmd/template"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace"
+)
+
+func TestREPLProvidesDataValuesAndYttModules(t *testing.T) {
+	valuesData := `#@data/values
+---
+name: app
+replicas: 2
+`
+
+	filesToLoad := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("values.yml", []byte(valuesData))),
+	})
+
+	opts := cmdtpl.NewOptions()
+	opts.DataValuesFlags.KVsFromStrings = []string{"name=other"}
+
+	repl, err := opts.NewREPL(cmdtpl.Input{Files: filesToLoad}, ui.NewTTY(false))
+	require.NoError(t, err)
+
+	requireREPLEval(t, repl, "data.values.name\n", `"other"`)
+	requireREPLEval(t, repl, "json.encode(struct.encode(data.values))\n", `"{\"name\":\"other\",\"replicas\":2}"`)
+	requireREPLEval(t, repl, `yaml.encode(overlay.apply({"a": 1}, {"a": 2}))`+"\n", `"a: 2\n"`)
+}
+
+func TestREPLRetainsGlobalsBetweenEvaluations(t *testing.T) {
+	valuesData := `#@data/values
+---
+replicas: 2
+`
+
+	filesToLoad := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("values.yml", []byte(valuesData))),
+	})
+
+	repl, err := cmdtpl.NewOptions().NewREPL(cmdtpl.Input{Files: filesToLoad}, ui.NewTTY(false))
+	require.NoError(t, err)
+
+	requireREPLEval(t, repl, "x = data.values.replicas + 1\n", "None")
+	requireREPLEval(t, repl, "def inc(v):\n  return v + 1\nend\n\n", "None")
+	requireREPLEval(t, repl, "inc(x)\n", "4")
+}
+
+func TestREPLEvaluatesStatementsFollowingBlocks(t *testing.T) {
+	repl, err := cmdtpl.NewOptions().NewREPL(cmdtpl.Input{Files: files.NewSortedFiles(nil)}, ui.NewTTY(false))
+	require.NoError(t, err)
+
+	// Lines are read one at a time, as they are from terminal
+	readline := replReadline("def g(a):\n", "  return a*3\n", "end\n", "y = g(2)\n", "if y > 1:\n", "  y = y + 1\n", "end\n", "g(y)\n")
+
+	var results []string
+	for {
+		val, err := repl.Eval(readline)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		results = append(results, val.String())
+	}
+	require.Equal(t, []string{"None", "None", "None", "21"}, results)
+}
+
+func TestREPLLoadsFilesAndLibraries(t *testing.T) {
+	funcsStarData := `
+def double(x):
+  return x * 2
+end
+`
+	valuesData := `#@data/values
+---
+replicas: 2
+`
+	libValuesData := `#@data/values
+---
+lib_name: default
+`
+	libTplData := `#@ load("@ytt:data", "data")
+---
+lib: #@ data.values.lib_name
+`
+
+	filesToLoad := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("values.yml", []byte(valuesData))),
+		files.MustNewFileFromSource(files.NewBytesSource("funcs.star", []byte(funcsStarData))),
+		files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/lib/values.yml", []byte(libValuesData))),
+		files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/lib/tpl.yml", []byte(libTplData))),
+	})
+
+	repl, err := cmdtpl.NewOptions().NewREPL(cmdtpl.Input{Files: filesToLoad}, ui.NewTTY(false))
+	require.NoError(t, err)
+
+	requireREPLEval(t, repl, `load("funcs.star", "double")`+"\n", "None")
+	requireREPLEval(t, repl, "double(data.values.replicas)\n", "4")
+
+	requireREPLEval(t, repl, `load("@ytt:library", lib="library")`+"\n", "None")
+	requireREPLEval(t, repl, `yaml.encode(lib.get("lib").with_data_values({"lib_name": "custom"}).eval())`+"\n", `"lib: custom\n"`)
+}
+
+func TestREPLReportsErrorsAndContinues(t *testing.T) {
+	valuesData := `#@data/values
+---
+name: app
+`
+
+	filesToLoad := files.NewSortedFiles([]*files.File{
+		files.MustNewFileFromSource(files.NewBytesSource("values.yml", []byte(valuesData))),
+	})
+
+	repl, err := cmdtpl.NewOptions().NewREPL(cmdtpl.Input{Files: filesToLoad}, ui.NewTTY(false))
+	require.NoError(t, err)
+
+	_, err = evalREPL(repl, "1 +\n\n")
+	require.Error(t, err)
+
+	_, err = evalREPL(repl, "undefined_name\n")
+	require.EqualError(t, err, "<repl>:1:1: undefined: undefined_name")
+
+	_, err = evalREPL(repl, `fail("boom")`+"\n")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "boom")
+
+	_, err = evalREPL(repl, `load("missing.star", "x")`+"\n")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "cannot load missing.star")
+
+	requireREPLEval(t, repl, "data.values.name\n", `"app"`)
+}
+
+func TestREPLReturnsEndOfInput(t *testing.T) {
+	repl, err := cmdtpl.NewOptions().NewREPL(cmdtpl.Input{Files: files.NewSortedFiles(nil)}, ui.NewTTY(false))
+	require.NoError(t, err)
+
+	_, err = evalREPL(repl, "")
+	require.Equal(t, io.EOF, err)
+}
+
+func requireREPLEval(t *testing.T, repl *workspace.REPL, src, expected string) {
+	val, err := evalREPL(repl, src)
+	require.NoError(t, err)
+	require.Equal(t, expected, val.String())
+}
+
+func evalREPL(repl *workspace.REPL, src string) (starlark.Value, error) {
+	return repl.Eval(replReadline(strings.SplitAfter(src, "\n")...))
+}
+
+func replReadline(lines ...string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		if len(lines) == 0 {
+			return nil, io.EOF
+		}
+		line := lines[0]
+		lines = lines[1:]
+		return []byte(line), nil
+	}
+}
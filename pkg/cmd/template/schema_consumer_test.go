@@ -227,6 +227,85 @@ rendered: #@ data.values
 
 		assertSucceeds(t, filesToProcess, expected, opts)
 	})
+	t.Run("when additional schema file is given via --data-values-schema-file", func(t *testing.T) {
+		schemaYAML := `#@data/values-schema
+---
+name: ""
+#@schema/type any=True
+extra: {}
+`
+		cliSchemaYAML := `#@ load("@ytt:overlay", "overlay")
+---
+#@overlay/replace
+extra:
+  replicas: 1
+#@overlay/match missing_ok=True
+#@schema/nullable
+owner: ""
+`
+		libSchemaYAML := `#@overlay/match missing_ok=True
+port: 8080
+`
+		templateYAML := `#@ load("@ytt:data", "data")
+#@ load("@ytt:library", "library")
+#@ load("@ytt:template", "template")
+---
+rendered: #@ data.values
+--- #@ template.replace(library.get("lib").eval())
+`
+		libSchemaInLibYAML := `#@data/values-schema
+---
+host: ""
+`
+		libTemplateYAML := `#@ load("@ytt:data", "data")
+---
+lib: #@ data.values
+`
+
+		expected := `rendered:
+  name: app
+  extra:
+    replicas: 3
+  owner: null
+---
+lib:
+  host: ""
+  port: 8080
+`
+
+		filesToProcess := files.NewSortedFiles([]*files.File{
+			files.MustNewFileFromSource(files.NewBytesSource("schema.yml", []byte(schemaYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("template.yml", []byte(templateYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/lib/schema.yml", []byte(libSchemaInLibYAML))),
+			files.MustNewFileFromSource(files.NewBytesSource("_ytt_lib/lib/template.yml", []byte(libTemplateYAML))),
+		})
+
+		opts := cmdtpl.NewOptions()
+		opts.DataValuesFlags = cmdtpl.DataValuesFlags{
+			SchemaFiles: []string{"cli-schema.yml", "@lib:lib-schema.yml"},
+			KVsFromYAML: []string{"name=app", "extra.replicas=3"},
+			ReadFilesFunc: func(path string) ([]*files.File, error) {
+				switch path {
+				case "cli-schema.yml":
+					return []*files.File{files.MustNewFileFromSource(files.NewBytesSource(path, []byte(cliSchemaYAML)))}, nil
+				case "lib-schema.yml":
+					return []*files.File{files.MustNewFileFromSource(files.NewBytesSource(path, []byte(libSchemaYAML)))}, nil
+				default:
+					return nil, fmt.Errorf("Unknown file '%s'", path)
+				}
+			},
+		}
+
+		out := opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui.NewTTY(false))
+		require.NoError(t, out.Err)
+
+		outBytes, err := out.DocSet.AsBytes()
+		require.NoError(t, err)
+		require.Equal(t, expected, string(outBytes))
+
+		opts.DataValuesFlags.KVsFromYAML = []string{"extra.replicas=three"}
+		assertFails(t, filesToProcess, "extra.replicas", opts)
+	})
 }
 
 func TestSchema_Reports_violations_when_DataValues_do_NOT_conform(t *testing.T) {
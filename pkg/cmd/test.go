@@ -0,0 +1,102 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/testrunner"
+)
+
+type TestOptions struct {
+	TestDirs   []string
+	Files      []string
+	Update     bool
+	Filter     string
+	StrictYAML bool
+	Debug      bool
+
+	SymlinkAllowOpts files.SymlinkAllowOpts
+}
+
+func NewTestOptions() *TestOptions {
+	return &TestOptions{}
+}
+
+func NewTestCmd(o *TestOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test",
+		Short: "Run templates against test cases and compare with expected results",
+		Long: `Run templates against test cases and compare with expected results.
+
+Test case is a directory (found within --test-dir directories) containing:
+
+  expected.yml          expected YAML output, or
+  expected-error.txt    expected error message
+  data-values.yml       (optional) plain YAML data values
+  ...                   other files (and directories) are inputs
+
+Files given via --file are inputs of all test cases. Use --update to (re)generate expected files.
+
+Functions named test_* within *_test.star files (given via --file, or within test cases) are run as Starlark unit tests
+(e.g. using assert.equals, assert.deep_equals, assert.contains, assert.raises from @ytt:assert).
+`,
+		RunE: func(_ *cobra.Command, _ []string) error { return o.Run() },
+	}
+	cmd.Flags().StringArrayVarP(&o.TestDirs, "test-dir", "d", []string{"."}, "Directory to search for test cases (can be specified multiple times)")
+	cmd.Flags().StringArrayVarP(&o.Files, "file", "f", nil, "File(s) to include as inputs of every test case (can be specified multiple times)")
+	cmd.Flags().BoolVar(&o.Update, "update", false, "Overwrite expected files with actual results")
+	cmd.Flags().StringVar(&o.Filter, "run", "", "Only run test cases with paths matching given regexp")
+	cmd.Flags().BoolVarP(&o.StrictYAML, "strict", "s", false, "Configure to use _strict_ YAML subset")
+	cmd.Flags().BoolVar(&o.Debug, "debug", false, "Enable debug output")
+
+	cmd.Flags().BoolVar(&o.SymlinkAllowOpts.AllowAll, "dangerous-allow-all-symlink-destinations", false,
+		"Symlinks to all destinations are allowed")
+	cmd.Flags().StringSliceVar(&o.SymlinkAllowOpts.AllowedDstPaths, "allow-symlink-destination", nil,
+		"File paths to which symlinks are allowed (can be specified multiple times)")
+	return cmd
+}
+
+func (o *TestOptions) Run() error {
+	ui := ui.NewTTY(o.Debug)
+
+	opts := testrunner.RunnerOpts{
+		Files:            o.Files,
+		Update:           o.Update,
+		Filter:           o.Filter,
+		StrictYAML:       o.StrictYAML,
+		SymlinkAllowOpts: o.SymlinkAllowOpts,
+	}
+
+	results, err := testrunner.NewRunner(opts, ui).Run(o.TestDirs)
+	if err != nil {
+		return err
+	}
+
+	var passed, failed, updated int
+	for _, result := range results {
+		switch {
+		case result.Updated:
+			updated++
+		case result.Passed:
+			passed++
+		default:
+			failed++
+		}
+	}
+
+	ui.Printf("\n%d passed, %d failed, %d updated\n", passed, failed, updated)
+
+	if len(results) == 0 {
+		return fmt.Errorf("Expected to find at least one test case (directory with '%s' or '%s')",
+			testrunner.ExpectedOutputFile, testrunner.ExpectedErrorFile)
+	}
+	if failed > 0 {
+		return fmt.Errorf("Expected all test cases to pass, but %d of %d failed", failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	cmdtpl "github.com/vmware-tanzu/carvel-ytt/pkg/cmd/template"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/vendoring"
+)
+
+type VendorOptions struct {
+	ManifestFile string
+	LockFile     string
+	Directory    string
+	Verify       bool
+	Debug        bool
+
+	SymlinkAllowOpts files.SymlinkAllowOpts
+	HTTPSourceOpts   files.HTTPSourceOpts
+}
+
+func NewVendorOptions() *VendorOptions {
+	return &VendorOptions{HTTPSourceOpts: files.HTTPSourceOpts{RetryInterval: time.Second}}
+}
+
+func NewVendorCmd(o *VendorOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "vendor",
+		Short: "Vendor libraries into _ytt_lib directory",
+		Long: `Vendor libraries listed in manifest into _ytt_lib directory and record their content digests in lock file.
+
+Example manifest:
+
+  libraries:
+  - name: github.com/org/lib
+    source: https://host/lib.tgz
+    path: config/lib
+    digest: sha256:<hex>
+`,
+		RunE: func(_ *cobra.Command, _ []string) error { return o.Run() },
+	}
+	cmd.Flags().StringVar(&o.ManifestFile, "file", vendoring.DefaultManifestFile, "Vendor manifest file")
+	cmd.Flags().StringVar(&o.LockFile, "lock-file", "", "Vendor lock file (defaults to '"+vendoring.DefaultLockFile+"' next to manifest)")
+	cmd.Flags().StringVar(&o.Directory, "directory", "", "Directory containing _ytt_lib directory (defaults to manifest's directory)")
+	cmd.Flags().BoolVar(&o.Verify, "verify", false, "Fail if vendored libraries do not match lock file (does not fetch any sources)")
+	cmd.Flags().BoolVar(&o.Debug, "debug", false, "Enable debug output")
+
+	cmd.Flags().BoolVar(&o.SymlinkAllowOpts.AllowAll, "dangerous-allow-all-symlink-destinations", false,
+		"Symlinks to all destinations are allowed")
+	cmd.Flags().StringSliceVar(&o.SymlinkAllowOpts.AllowedDstPaths, "allow-symlink-destination", nil,
+		"File paths to which symlinks are allowed (can be specified multiple times)")
+
+	cmdtpl.HTTPFlags{HTTPSourceOpts: &o.HTTPSourceOpts}.Set(cmd.Flags())
+	return cmd
+}
+
+func (o *VendorOptions) Run() error {
+	ui := ui.NewTTY(o.Debug)
+
+	manifest, err := vendoring.NewManifestFromFile(o.ManifestFile)
+	if err != nil {
+		return err
+	}
+
+	opts := vendoring.VendorerOpts{
+		Directory:        o.Directory,
+		LockFilePath:     o.LockFile,
+		SymlinkAllowOpts: o.SymlinkAllowOpts,
+		HTTPSourceOpts:   o.HTTPSourceOpts,
+	}
+	opts.HTTPSourceOpts.UI = ui
+	if len(opts.Directory) == 0 {
+		opts.Directory = filepath.Dir(o.ManifestFile)
+	}
+	if len(opts.LockFilePath) == 0 {
+		opts.LockFilePath = filepath.Join(filepath.Dir(o.ManifestFile), vendoring.DefaultLockFile)
+	}
+
+	vendorer := vendoring.NewVendorer(manifest, opts, ui)
+
+	if o.Verify {
+		return vendorer.Verify()
+	}
+	return vendorer.Sync()
+}
@@ -5,13 +5,16 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
+	"time"
 
 	"github.com/spf13/cobra"
 	cmdtpl "github.com/vmware-tanzu/carvel-ytt/pkg/cmd/template"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/template"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/website"
 )
 
@@ -20,11 +23,19 @@ type WebsiteOptions struct {
 	RedirectToHTTPS bool
 	BinaryPath      string
 	CheckCookie     bool
+	ExecutionLimits template.ExecutionLimits
+
+	executionLimitsFlags cmdtpl.ExecutionLimitsFlags
 }
 
+// executionGracePeriod is given to the binary on top of its execution timeout before it's killed
+// (e.g. when it's stuck in a long running builtin operation that does not check limits).
+const executionGracePeriod = 5 * time.Second
+
 func NewWebsiteOptions() *WebsiteOptions {
 	return &WebsiteOptions{
-		BinaryPath: os.Args[0],
+		BinaryPath:      os.Args[0],
+		ExecutionLimits: website.DefaultExecutionLimits,
 	}
 }
 
@@ -36,6 +47,9 @@ func NewWebsiteCmd(o *WebsiteOptions) *cobra.Command {
 	}
 	cmd.Flags().StringVar(&o.ListenAddr, "listen-addr", "localhost:8080", "Listen address")
 	cmd.Flags().BoolVar(&o.RedirectToHTTPS, "redirect-to-https", true, "Redirect to HTTPs address")
+
+	o.executionLimitsFlags = cmdtpl.NewExecutionLimitsFlags(o.ExecutionLimits)
+	o.executionLimitsFlags.Set(cmd.Flags())
 	return cmd
 }
 
@@ -43,6 +57,7 @@ func (o *WebsiteOptions) Server() *website.Server {
 	opts := website.ServerOpts{
 		ListenAddr:      o.ListenAddr,
 		RedirectToHTTPS: o.RedirectToHTTPS,
+		ExecutionLimits: o.ExecutionLimits,
 		TemplateFunc:    o.execBinary,
 		ErrorFunc:       o.bulkOutErr,
 		CheckCookie:     o.CheckCookie,
@@ -51,17 +66,35 @@ func (o *WebsiteOptions) Server() *website.Server {
 }
 
 func (o *WebsiteOptions) Run() error {
+	limits, err := o.executionLimitsFlags.Limits()
+	if err != nil {
+		return err
+	}
+	o.ExecutionLimits = limits
+
 	return o.Server().Run()
 }
 
-func (o *WebsiteOptions) execBinary(data []byte) ([]byte, error) {
+func (o *WebsiteOptions) execBinary(data []byte, limits template.ExecutionLimits) ([]byte, error) {
+	ctx := context.Background()
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.Timeout+executionGracePeriod)
+		defer cancel()
+	}
+
+	args := append([]string{"--bulk-in", string(data), "--bulk-out"}, cmdtpl.ExecutionLimitsAsArgs(limits)...)
+
 	var out, stderr bytes.Buffer
-	cmd := exec.Command(o.BinaryPath, "--bulk-in", string(data), "--bulk-out")
+	cmd := exec.CommandContext(ctx, o.BinaryPath, args...)
 	cmd.Stdout = &out
 	cmd.Stderr = &stderr
 
 	err := cmd.Run()
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("error: Expected templating to complete within %s", limits.Timeout)
+		}
 		return nil, fmt.Errorf("error: %s", stderr.String())
 	}
 
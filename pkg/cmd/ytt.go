@@ -45,6 +45,11 @@ Docs for data values: https://carvel.dev/ytt/docs/latest/ytt-data-values/`
 	cmd.AddCommand(NewCmd(cmdtpl.NewOptions())) // for backwards compat
 	cmd.AddCommand(NewFmtCmd(NewFmtOptions()))
 	cmd.AddCommand(NewWebsiteCmd(NewWebsiteOptions()))
+	cmd.AddCommand(NewVendorCmd(NewVendorOptions()))
+	cmd.AddCommand(NewTestCmd(NewTestOptions()))
+	cmd.AddCommand(NewLSPCmd(NewLSPOptions()))
+	cmd.AddCommand(NewLintCmd(NewLintOptions()))
+	cmd.AddCommand(NewREPLCmd(NewREPLOptions()))
 
 	// Reconfigure Commands
 	cobrautil.VisitCommands(cmd, cobrautil.ReconfigureCmdWithSubcmd,
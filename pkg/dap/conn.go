@@ -0,0 +1,87 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/vmware-tanzu/carvel-ytt/pkg/jsonframe"
+)
+
+type request struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+type response struct {
+	Seq        int         `json:"seq"`
+	Type       string      `json:"type"`
+	RequestSeq int         `json:"request_seq"`
+	Success    bool        `json:"success"`
+	Command    string      `json:"command"`
+	Message    string      `json:"message,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+}
+
+type event struct {
+	Seq   int         `json:"seq"`
+	Type  string      `json:"type"`
+	Event string      `json:"event"`
+	Body  interface{} `json:"body,omitempty"`
+}
+
+// conn reads and writes messages framed with Content-Length header.
+// Messages may be written concurrently (e.g. events while responding to requests).
+type conn struct {
+	reader *jsonframe.Reader
+
+	writeLock sync.Mutex
+	writer    io.Writer
+	seq       int
+}
+
+func newConn(in io.Reader, out io.Writer) *conn {
+	return &conn{reader: jsonframe.NewReader(in), writer: out}
+}
+
+func (c *conn) Read() (request, error) {
+	var req request
+	err := c.reader.Read(&req)
+	if err != nil {
+		return request{}, err
+	}
+	if req.Type != "request" {
+		return request{}, fmt.Errorf("Expected message of type 'request', but was '%s'", req.Type)
+	}
+	return req, nil
+}
+
+func (c *conn) Respond(req request, body interface{}, respErr error) error {
+	resp := &response{Type: "response", RequestSeq: req.Seq, Success: respErr == nil, Command: req.Command, Body: body}
+	if respErr != nil {
+		resp.Message = respErr.Error()
+	}
+	return c.write(func(seq int) interface{} { resp.Seq = seq; return resp })
+}
+
+func (c *conn) SendEvent(name string, body interface{}) error {
+	return c.write(func(seq int) interface{} {
+		return event{Seq: seq, Type: "event", Event: name, Body: body}
+	})
+}
+
+// write numbers messages in order they are written.
+func (c *conn) write(msgFunc func(seq int) interface{}) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	c.seq++
+
+	return jsonframe.Write(c.writer, msgFunc(c.seq))
+}
@@ -0,0 +1,26 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+Package dap implements a Debug Adapter Protocol server that steps through evaluation of ytt templates.
+
+Server speaks DAP (JSON messages framed with Content-Length headers) with a single
+client over a pair of streams (stdin and stdout, or a TCP connection). Evaluation
+starts once client sends 'configurationDone' request and pauses before template
+statements (see template.Debugger) when:
+
+  - statement is on a line with a breakpoint (set via 'setBreakpoints')
+  - client asked to step ('next', 'stepIn', 'stepOut') or to 'pause'
+  - client asked to stop on entry ('stopOnEntry' argument of 'launch' or 'attach')
+
+While paused, client can inspect call stack (frames of loaded templates included),
+variables assigned in each frame, and a template node that is being built.
+Values of dicts, lists and structs can be expanded.
+
+Templates are evaluated by a single thread (hence --parallelism and --execution-timeout
+are not allowed while debugging). Sources are identified by workspace-relative paths
+of templates (e.g. 'config/deployment.yml'); breakpoint source path matches a template
+if it is the local path that template was read from (see Server.SetFiles), or else
+if it equals template's path.
+*/
+package dap
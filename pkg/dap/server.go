@@ -0,0 +1,584 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/template"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/texttemplate"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/yamlmeta"
+)
+
+const (
+	// StdioAddress makes Listen serve client over stdin and stdout
+	StdioAddress = "stdio"
+
+	// Templates are evaluated sequentially, hence there is only a single (DAP) thread
+	threadID = 1
+)
+
+type stepKind int
+
+const (
+	stepNone stepKind = iota
+	stepIn
+	stepOver
+	stepOut
+)
+
+// threadStop is the last stop of a Starlark thread that has not finished evaluation
+// (e.g. of a template that is loading another template).
+type threadStop struct {
+	thread *starlark.Thread
+	stop   template.DebugStop
+}
+
+// nodeRef refers to a template node (as opposed to a Starlark value).
+type nodeRef struct {
+	node template.EvaluationNode
+}
+
+// Server pauses evaluation of templates on behalf of a single client.
+//
+// Server implements template.Debugger: evaluation (on one goroutine) is paused
+// within BeforeStatement while requests are handled (on another goroutine).
+type Server struct {
+	conn   *conn
+	closer io.Closer
+	ui     ui.UI
+
+	configured     chan struct{} // closed once client finishes configuration
+	configuredOnce sync.Once
+	done           chan struct{} // closed once client disconnects
+	doneOnce       sync.Once
+	resume         chan struct{}
+
+	lock           sync.Mutex
+	breakpoints    map[string]map[int]struct{} // lines by source path
+	localFiles     map[string]*files.File      // files by their local path
+	stopOnEntry    bool
+	entered        bool
+	pauseRequested bool
+	step           stepKind
+	stepDepth      int
+	threads        []threadStop // outermost first
+	paused         bool
+	resumePending  bool          // evaluation resumes once response is sent
+	refs           []interface{} // variable references (valid while paused)
+}
+
+var _ template.Debugger = &Server{}
+
+func NewServer(in io.Reader, out io.Writer, ui ui.UI) *Server {
+	return &Server{
+		conn:        newConn(in, out),
+		ui:          ui,
+		configured:  make(chan struct{}),
+		done:        make(chan struct{}),
+		resume:      make(chan struct{}, 1),
+		breakpoints: map[string]map[int]struct{}{},
+	}
+}
+
+// Listen serves client over stdio (see StdioAddress) or waits for a client to connect at given TCP address.
+func Listen(address string, ui ui.UI) (*Server, error) {
+	if address == StdioAddress {
+		return NewServer(os.Stdin, os.Stdout, ui), nil
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("Listening for debugger client: %s", err)
+	}
+	defer listener.Close()
+
+	ui.Warnf("Waiting for debugger client to connect at %s\n", listener.Addr())
+
+	clientConn, err := listener.Accept()
+	if err != nil {
+		return nil, fmt.Errorf("Accepting debugger client: %s", err)
+	}
+
+	server := NewServer(clientConn, clientConn, ui)
+	server.closer = clientConn
+	return server, nil
+}
+
+// SetFiles identifies templates that breakpoints' (local) source paths refer to.
+func (s *Server) SetFiles(filesToProcess []*files.File) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.localFiles = map[string]*files.File{}
+	for _, file := range filesToProcess {
+		if localPath, ok := file.LocalPath(); ok {
+			s.localFiles[localPath] = file
+		}
+	}
+}
+
+// Start handles client's requests in background and waits
+// until client finishes configuration (e.g. sets breakpoints).
+func (s *Server) Start() error {
+	go s.run()
+
+	select {
+	case <-s.configured:
+		return nil
+	case <-s.done:
+		return fmt.Errorf("Expected debugger client to finish configuration before disconnecting")
+	}
+}
+
+// Finish notifies client that evaluation ended (unsuccessfully, if error is given).
+func (s *Server) Finish(evalErr error) error {
+	exitCode := 0
+	if evalErr != nil {
+		exitCode = 1
+		s.sendEvent("output", OutputEventBody{Category: "stderr", Output: evalErr.Error() + "\n"})
+	}
+	s.sendEvent("exited", ExitedEventBody{ExitCode: exitCode})
+	s.sendEvent("terminated", nil)
+
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
+
+func (s *Server) run() {
+	// Evaluation continues uninterrupted once client is gone
+	defer s.doneOnce.Do(func() { close(s.done) })
+
+	for {
+		req, err := s.conn.Read()
+		if err != nil {
+			// Connection is closed by Finish
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				s.ui.Warnf("dap: reading request: %s\n", err)
+			}
+			return
+		}
+
+		s.ui.Debugf("dap: received '%s'\n", req.Command)
+
+		body, err := s.handle(req)
+
+		err = s.conn.Respond(req, body, err)
+		if err != nil {
+			s.ui.Warnf("dap: responding to '%s': %s\n", req.Command, err)
+			return
+		}
+
+		s.lock.Lock()
+		resume := s.resumePending
+		s.resumePending = false
+		s.lock.Unlock()
+
+		if resume {
+			s.resume <- struct{}{}
+		}
+
+		switch req.Command {
+		case "initialize":
+			s.sendEvent("initialized", nil)
+		case "disconnect":
+			return
+		}
+	}
+}
+
+func (s *Server) handle(req request) (interface{}, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch req.Command {
+	case "initialize":
+		return Capabilities{SupportsConfigurationDoneRequest: true}, nil
+
+	case "launch", "attach":
+		var args LaunchArguments
+		if err := s.unmarshalArgs(req, &args); err != nil {
+			return nil, err
+		}
+		s.stopOnEntry = args.StopOnEntry
+		return nil, nil
+
+	case "setBreakpoints":
+		var args SetBreakpointsArguments
+		if err := s.unmarshalArgs(req, &args); err != nil {
+			return nil, err
+		}
+		return s.setBreakpoints(args), nil
+
+	case "setExceptionBreakpoints":
+		return nil, nil
+
+	case "configurationDone":
+		s.configuredOnce.Do(func() { close(s.configured) })
+		return nil, nil
+
+	case "threads":
+		return ThreadsResponseBody{Threads: []Thread{{ID: threadID, Name: "templates"}}}, nil
+
+	case "stackTrace":
+		var args StackTraceArguments
+		if err := s.unmarshalArgs(req, &args); err != nil {
+			return nil, err
+		}
+		if !s.paused {
+			return nil, fmt.Errorf("Expected evaluation to be paused")
+		}
+		return s.stackTrace(args), nil
+
+	case "scopes":
+		var args ScopesArguments
+		if err := s.unmarshalArgs(req, &args); err != nil {
+			return nil, err
+		}
+		if !s.paused {
+			return nil, fmt.Errorf("Expected evaluation to be paused")
+		}
+		return s.scopes(args), nil
+
+	case "variables":
+		var args VariablesArguments
+		if err := s.unmarshalArgs(req, &args); err != nil {
+			return nil, err
+		}
+		if !s.paused {
+			return nil, fmt.Errorf("Expected evaluation to be paused")
+		}
+		return s.variables(args)
+
+	case "continue":
+		return ContinueResponseBody{AllThreadsContinued: true}, s.resumeWith(stepNone)
+
+	case "next":
+		return nil, s.resumeWith(stepOver)
+
+	case "stepIn":
+		return nil, s.resumeWith(stepIn)
+
+	case "stepOut":
+		return nil, s.resumeWith(stepOut)
+
+	case "pause":
+		s.pauseRequested = true
+		return nil, nil
+
+	case "disconnect":
+		s.breakpoints = map[string]map[int]struct{}{}
+		if s.paused {
+			return nil, s.resumeWith(stepNone)
+		}
+		return nil, nil
+
+	default:
+		return nil, fmt.Errorf("Unsupported command '%s'", req.Command)
+	}
+}
+
+func (s *Server) unmarshalArgs(req request, args interface{}) error {
+	if len(req.Arguments) == 0 {
+		return nil
+	}
+	err := json.Unmarshal(req.Arguments, args)
+	if err != nil {
+		return fmt.Errorf("Unmarshaling '%s' arguments: %s", req.Command, err)
+	}
+	return nil
+}
+
+func (s *Server) sendEvent(name string, body interface{}) {
+	err := s.conn.SendEvent(name, body)
+	if err != nil {
+		s.ui.Warnf("dap: sending '%s' event: %s\n", name, err)
+	}
+}
+
+// BeforeStatement pauses evaluation (until client resumes it) if there is a reason to stop at given statement.
+func (s *Server) BeforeStatement(thread *starlark.Thread, stop template.DebugStop) error {
+	s.lock.Lock()
+
+	select {
+	case <-s.done:
+		s.lock.Unlock()
+		return nil
+	default:
+	}
+
+	s.trackThread(thread, stop)
+
+	reason := s.stopReason()
+	s.entered = true
+
+	if len(reason) == 0 {
+		s.lock.Unlock()
+		return nil
+	}
+
+	s.paused = true
+	s.pauseRequested = false
+	s.refs = nil
+	s.lock.Unlock()
+
+	s.sendEvent("stopped", StoppedEventBody{Reason: reason, ThreadID: threadID, AllThreadsStopped: true})
+
+	select {
+	case <-s.resume:
+	case <-s.done:
+	}
+	return nil
+}
+
+// trackThread records stop of a thread on top of threads that are (still) evaluating.
+func (s *Server) trackThread(thread *starlark.Thread, stop template.DebugStop) {
+	var threads []threadStop
+	for _, ts := range s.threads {
+		if ts.thread == thread {
+			break // threads above have finished (e.g. loaded templates)
+		}
+		// Threads that are not on the stack anymore have finished as well (e.g. previous templates)
+		if ts.thread.CallStackDepth() > 0 {
+			threads = append(threads, ts)
+		}
+	}
+	s.threads = append(threads, threadStop{thread, stop})
+}
+
+func (s *Server) stopReason() string {
+	depth := s.depth()
+	frames := s.threads[len(s.threads)-1].stop.Frames
+
+	switch {
+	case s.pauseRequested:
+		return "pause"
+	case s.stopOnEntry && !s.entered:
+		return "entry"
+	case len(frames) > 0 && s.hasBreakpoint(frames[0].File, frames[0].Line):
+		return "breakpoint"
+	case s.step == stepIn:
+		return "step"
+	case s.step == stepOver && depth <= s.stepDepth:
+		return "step"
+	case s.step == stepOut && depth < s.stepDepth:
+		return "step"
+	default:
+		return ""
+	}
+}
+
+// depth is a number of frames across all threads that are evaluating.
+func (s *Server) depth() int {
+	var depth int
+	for _, ts := range s.threads {
+		depth += len(ts.stop.Frames)
+	}
+	return depth
+}
+
+func (s *Server) resumeWith(step stepKind) error {
+	if !s.paused {
+		return fmt.Errorf("Expected evaluation to be paused")
+	}
+
+	s.paused = false
+	s.refs = nil
+	s.step = step
+	s.stepDepth = s.depth()
+	s.resumePending = true
+	return nil
+}
+
+func (s *Server) setBreakpoints(args SetBreakpointsArguments) SetBreakpointsResponseBody {
+	lines := map[int]struct{}{}
+	result := SetBreakpointsResponseBody{Breakpoints: []Breakpoint{}}
+
+	for _, bp := range args.Breakpoints {
+		lines[bp.Line] = struct{}{}
+		result.Breakpoints = append(result.Breakpoints, Breakpoint{Verified: true, Line: bp.Line})
+	}
+
+	s.breakpoints[args.Source.Path] = lines
+	return result
+}
+
+// hasBreakpoint matches template path against workspace-relative path of breakpoints' sources.
+func (s *Server) hasBreakpoint(file string, line int) bool {
+	for srcPath, lines := range s.breakpoints {
+		if s.templatePath(srcPath) == file {
+			if _, found := lines[line]; found {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// templatePath returns workspace-relative path of a template read from given source path.
+// Source paths of templates that were not read from local filesystem are already workspace-relative.
+func (s *Server) templatePath(srcPath string) string {
+	if absPath, err := filepath.Abs(srcPath); err == nil {
+		if file, found := s.localFiles[absPath]; found {
+			// File marks may change template path
+			return file.RelativePath()
+		}
+	}
+	return filepath.ToSlash(srcPath)
+}
+
+// frames returns frames of all evaluating threads (innermost first)
+// together with thread stops they belong to.
+func (s *Server) frames() ([]template.DebugFrame, []*threadStop) {
+	var frames []template.DebugFrame
+	var stops []*threadStop
+
+	for i := len(s.threads) - 1; i >= 0; i-- {
+		for _, frame := range s.threads[i].stop.Frames {
+			frames = append(frames, frame)
+			stops = append(stops, &s.threads[i])
+		}
+	}
+	return frames, stops
+}
+
+func (s *Server) stackTrace(args StackTraceArguments) StackTraceResponseBody {
+	frames, _ := s.frames()
+	result := StackTraceResponseBody{StackFrames: []StackFrame{}, TotalFrames: len(frames)}
+
+	for i, frame := range frames {
+		if i < args.StartFrame || (args.Levels > 0 && i >= args.StartFrame+args.Levels) {
+			continue
+		}
+		result.StackFrames = append(result.StackFrames, StackFrame{
+			ID:     i + 1, // frame IDs are valid while paused
+			Name:   frame.Name,
+			Source: &Source{Name: path.Base(frame.File), Path: frame.File},
+			Line:   frame.Line,
+			Column: 1,
+		})
+	}
+	return result
+}
+
+// scopes are only known for innermost frames of each thread.
+func (s *Server) scopes(args ScopesArguments) ScopesResponseBody {
+	frames, stops := s.frames()
+	result := ScopesResponseBody{Scopes: []Scope{}}
+
+	idx := args.FrameID - 1
+	if idx < 0 || idx >= len(frames) || (idx > 0 && stops[idx-1] == stops[idx]) {
+		return result
+	}
+
+	stop := stops[idx].stop
+
+	result.Scopes = append(result.Scopes, Scope{Name: "Locals", VariablesReference: s.newRef(stop.Locals)})
+	if stop.Node != nil {
+		result.Scopes = append(result.Scopes, Scope{Name: "Template node", VariablesReference: s.newRef(nodeRef{stop.Node})})
+	}
+	return result
+}
+
+func (s *Server) variables(args VariablesArguments) (VariablesResponseBody, error) {
+	result := VariablesResponseBody{Variables: []Variable{}}
+
+	idx := args.VariablesReference - 1
+	if idx < 0 || idx >= len(s.refs) {
+		return result, fmt.Errorf("Expected variables reference %d to be known", args.VariablesReference)
+	}
+
+	switch typedRef := s.refs[idx].(type) {
+	case []template.DebugVariable:
+		for _, variable := range typedRef {
+			result.Variables = append(result.Variables, s.variable(variable.Name, variable.Value))
+		}
+
+	case nodeRef:
+		result.Variables = append(result.Variables, Variable{
+			Name:  "node",
+			Value: s.nodeString(typedRef.node),
+			Type:  fmt.Sprintf("%T", typedRef.node),
+		})
+
+	case starlark.Value:
+		for _, child := range s.children(typedRef) {
+			result.Variables = append(result.Variables, s.variable(child.Name, child.Value))
+		}
+	}
+	return result, nil
+}
+
+func (s *Server) newRef(ref interface{}) int {
+	s.refs = append(s.refs, ref)
+	return len(s.refs)
+}
+
+func (s *Server) variable(name string, val starlark.Value) Variable {
+	result := Variable{Name: name, Value: val.String(), Type: val.Type()}
+	if len(s.children(val)) > 0 {
+		result.VariablesReference = s.newRef(val)
+	}
+	return result
+}
+
+// children returns entries of dicts, items of lists and tuples, and attributes of structs.
+func (s *Server) children(val starlark.Value) []template.DebugVariable {
+	var result []template.DebugVariable
+
+	switch typedVal := val.(type) {
+	case starlark.String:
+		// strings are indexable, but are shown as a whole
+
+	case starlark.IterableMapping:
+		for _, item := range typedVal.Items() {
+			result = append(result, template.DebugVariable{Name: item[0].String(), Value: item[1]})
+		}
+
+	case starlark.Indexable:
+		for i := 0; i < typedVal.Len(); i++ {
+			result = append(result, template.DebugVariable{Name: fmt.Sprintf("[%d]", i), Value: typedVal.Index(i)})
+		}
+
+	case starlark.HasAttrs:
+		for _, name := range typedVal.AttrNames() {
+			attr, err := typedVal.Attr(name)
+			if err != nil || attr == nil {
+				continue
+			}
+			// Methods of builtin types (e.g. set.union) are not interesting
+			if builtin, ok := attr.(*starlark.Builtin); ok && builtin.Receiver() != nil {
+				continue
+			}
+			result = append(result, template.DebugVariable{Name: name, Value: attr})
+		}
+	}
+	return result
+}
+
+func (s *Server) nodeString(node template.EvaluationNode) string {
+	switch typedNode := node.(type) {
+	case yamlmeta.Node:
+		return yamlmeta.NewPrinterWithOpts(nil, yamlmeta.PrinterOpts{ExcludeRefs: true}).PrintStr(typedNode)
+	case *texttemplate.NodeRoot:
+		return typedNode.AsString()
+	case *texttemplate.NodeText:
+		return typedNode.Content
+	default:
+		return fmt.Sprintf("%T", node)
+	}
+}
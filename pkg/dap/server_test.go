@@ -0,0 +1,346 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package dap_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	cmdtpl "github.com/vmware-tanzu/carvel-ytt/pkg/cmd/template"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/dap"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/jsonframe"
+)
+
+const tplYAML = `#@ load("@ytt:data", "data")
+#@ load("helpers.star", "labels")
+
+#@ def port(base):
+#@   offset = 1
+#@   return base + offset
+#@ end
+---
+name: #@ data.values.name
+#@ items = [1, 2]
+ports:
+#@ for i in items:
+- #@ port(i)
+#@ end
+labels: #@ labels(data.values.name)
+`
+
+const helpersStar = `def labels(name):
+  result = {"app": name}
+  return result
+end
+`
+
+const valuesYAML = `#@data/values
+---
+name: app
+`
+
+func TestServer(t *testing.T) {
+	client := startClient(t, tplYAML)
+
+	var caps dap.Capabilities
+	client.Call(t, "initialize", map[string]interface{}{"adapterID": "ytt"}, &caps)
+	require.True(t, caps.SupportsConfigurationDoneRequest)
+	client.Event(t, "initialized")
+
+	var bps dap.SetBreakpointsResponseBody
+	client.Call(t, "setBreakpoints", dap.SetBreakpointsArguments{
+		Source:      dap.Source{Path: filepath.Join(client.dir, "tpl.yml")},
+		Breakpoints: []dap.SourceBreakpoint{{Line: 13}},
+	}, &bps)
+	require.Equal(t, []dap.Breakpoint{{Verified: true, Line: 13}}, bps.Breakpoints)
+
+	// Template with same name in another directory is a different source
+	client.Call(t, "setBreakpoints", dap.SetBreakpointsArguments{
+		Source:      dap.Source{Path: filepath.Join(t.TempDir(), "tpl.yml")},
+		Breakpoints: []dap.SourceBreakpoint{{Line: 9}},
+	}, nil)
+
+	client.Call(t, "launch", dap.LaunchArguments{}, nil)
+	client.Call(t, "configurationDone", nil, nil)
+
+	t.Run("stops at breakpoint with locals and template node", func(t *testing.T) {
+		require.Equal(t, "breakpoint", client.Stopped(t).Reason)
+		require.Equal(t, []string{"<toplevel> tpl.yml:13"}, client.StackTrace(t))
+
+		scopes := client.Scopes(t, 1)
+		require.Equal(t, []string{"Locals", "Template node"}, []string{scopes[0].Name, scopes[1].Name})
+
+		locals := client.Variables(t, scopes[0].VariablesReference)
+		require.Equal(t, []string{"data", "labels", "port", "items", "i"}, variableNames(locals))
+		require.Equal(t, "[1, 2]", locals[3].Value)
+		require.Equal(t, "1", locals[4].Value)
+
+		items := client.Variables(t, locals[3].VariablesReference)
+		require.Equal(t, []string{"[0]=1", "[1]=2"}, variableNameValues(items))
+
+		node := client.Variables(t, scopes[1].VariablesReference)
+		require.Len(t, node, 1)
+		require.Equal(t, "*yamlmeta.Array", node[0].Type)
+	})
+
+	t.Run("steps into and out of functions", func(t *testing.T) {
+		client.Call(t, "stepIn", map[string]interface{}{"threadId": 1}, nil)
+		require.Equal(t, "step", client.Stopped(t).Reason)
+		require.Equal(t, []string{"port tpl.yml:5", "<toplevel> tpl.yml:13"}, client.StackTrace(t))
+		require.Equal(t, []string{"base=1"}, variableNameValues(client.Variables(t, client.Scopes(t, 1)[0].VariablesReference)))
+
+		client.Call(t, "next", map[string]interface{}{"threadId": 1}, nil)
+		require.Equal(t, "step", client.Stopped(t).Reason)
+		require.Equal(t, []string{"port tpl.yml:6", "<toplevel> tpl.yml:13"}, client.StackTrace(t))
+		require.Equal(t, []string{"base=1", "offset=1"}, variableNameValues(client.Variables(t, client.Scopes(t, 1)[0].VariablesReference)))
+
+		// Next loop iteration is also at a breakpoint
+		client.Call(t, "stepOut", map[string]interface{}{"threadId": 1}, nil)
+		require.Equal(t, "breakpoint", client.Stopped(t).Reason)
+		require.Equal(t, []string{"<toplevel> tpl.yml:13"}, client.StackTrace(t))
+		require.Contains(t, variableNameValues(client.Variables(t, client.Scopes(t, 1)[0].VariablesReference)), "i=2")
+	})
+
+	t.Run("steps over functions and into loaded files", func(t *testing.T) {
+		client.Call(t, "next", map[string]interface{}{"threadId": 1}, nil)
+		require.Equal(t, "step", client.Stopped(t).Reason)
+		require.Equal(t, []string{"<toplevel> tpl.yml:15"}, client.StackTrace(t))
+
+		client.Call(t, "stepIn", map[string]interface{}{"threadId": 1}, nil)
+		require.Equal(t, "step", client.Stopped(t).Reason)
+		require.Equal(t, []string{"labels helpers.star:2", "<toplevel> tpl.yml:15"}, client.StackTrace(t))
+
+		client.Call(t, "next", map[string]interface{}{"threadId": 1}, nil)
+		require.Equal(t, "step", client.Stopped(t).Reason)
+		require.Equal(t, []string{"labels helpers.star:3", "<toplevel> tpl.yml:15"}, client.StackTrace(t))
+
+		locals := client.Variables(t, client.Scopes(t, 1)[0].VariablesReference)
+		require.Equal(t, []string{`name="app"`, `result={"app": "app"}`}, variableNameValues(locals))
+		require.Equal(t, []string{`"app"="app"`}, variableNameValues(client.Variables(t, locals[1].VariablesReference)))
+
+		// Outer frame only provides position
+		require.Empty(t, client.Scopes(t, 2))
+	})
+
+	t.Run("continues until evaluation ends", func(t *testing.T) {
+		client.Call(t, "continue", map[string]interface{}{"threadId": 1}, nil)
+		require.Equal(t, 0, client.Exited(t))
+		client.Event(t, "terminated")
+
+		out := client.Wait(t)
+		require.NoError(t, out.Err)
+		require.Equal(t, `name: app
+ports:
+- 2
+- 3
+labels:
+  app: app
+`, string(out.Files[0].Bytes()))
+	})
+}
+
+func TestServerReportsErrors(t *testing.T) {
+	client := startClient(t, "---\nfoo: #@ 1 + \"a\"\n")
+
+	client.Call(t, "initialize", map[string]interface{}{"adapterID": "ytt"}, nil)
+	client.Call(t, "launch", dap.LaunchArguments{StopOnEntry: true}, nil)
+	client.Call(t, "configurationDone", nil, nil)
+
+	require.Equal(t, "entry", client.Stopped(t).Reason)
+
+	client.Call(t, "continue", map[string]interface{}{"threadId": 1}, nil)
+	require.Contains(t, client.Event(t, "output"), "unknown binary op: int + string")
+	require.Equal(t, 1, client.Exited(t))
+	client.Event(t, "terminated")
+
+	require.Error(t, client.Wait(t).Err)
+}
+
+func TestServerRejectsExecutionTimeout(t *testing.T) {
+	opts := cmdtpl.NewOptions()
+	opts.ExecutionLimitsFlags.Timeout = time.Minute
+
+	client := startClientWithOpts(t, tplYAML, opts)
+
+	client.Call(t, "initialize", map[string]interface{}{"adapterID": "ytt"}, nil)
+	client.Call(t, "launch", dap.LaunchArguments{}, nil)
+	client.Call(t, "configurationDone", nil, nil)
+
+	require.Contains(t, client.Event(t, "output"), "Expected --execution-timeout to be 0 when debugging, but was 1m0s")
+	require.Equal(t, 1, client.Exited(t))
+	client.Event(t, "terminated")
+
+	require.EqualError(t, client.Wait(t).Err, "Expected --execution-timeout to be 0 when debugging, but was 1m0s")
+}
+
+type testClient struct {
+	writer   io.Writer
+	messages chan map[string]json.RawMessage
+	done     chan cmdtpl.Output
+	nextSeq  int
+	dir      string // workspace with templates
+}
+
+func startClient(t *testing.T, tpl string) *testClient {
+	return startClientWithOpts(t, tpl, cmdtpl.NewOptions())
+}
+
+func startClientWithOpts(t *testing.T, tpl string, opts *cmdtpl.Options) *testClient {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	client := &testClient{
+		writer:   clientWriter,
+		messages: make(chan map[string]json.RawMessage, 100),
+		done:     make(chan cmdtpl.Output, 1),
+		dir:      t.TempDir(),
+	}
+
+	for name, data := range map[string]string{"tpl.yml": tpl, "helpers.star": helpersStar, "values.yml": valuesYAML} {
+		require.NoError(t, os.WriteFile(filepath.Join(client.dir, name), []byte(data), 0600))
+	}
+
+	filesToProcess, err := files.NewSortedFilesFromPaths([]string{client.dir}, files.SymlinkAllowOpts{})
+	require.NoError(t, err)
+
+	ui := ui.NewCustomWriterTTY(false, &bytes.Buffer{}, &bytes.Buffer{})
+
+	opts.DebugServer = dap.NewServer(serverReader, serverWriter, ui)
+
+	go func() {
+		client.done <- opts.RunWithFiles(cmdtpl.Input{Files: filesToProcess}, ui)
+		serverWriter.Close()
+	}()
+
+	go func() {
+		reader := jsonframe.NewReader(clientReader)
+		for {
+			var msg map[string]json.RawMessage
+			if reader.Read(&msg) != nil {
+				close(client.messages)
+				return
+			}
+			client.messages <- msg
+		}
+	}()
+
+	return client
+}
+
+// Call sends request and waits for its response (skipping any events).
+func (c *testClient) Call(t *testing.T, command string, args interface{}, body interface{}) {
+	c.nextSeq++
+	msg, err := json.Marshal(map[string]interface{}{"seq": c.nextSeq, "type": "request", "command": command, "arguments": args})
+	require.NoError(t, err)
+	_, err = fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	require.NoError(t, err)
+
+	for {
+		resp := c.receive(t)
+		if string(resp["type"]) != `"response"` {
+			continue
+		}
+		require.Equal(t, strconv.Itoa(c.nextSeq), string(resp["request_seq"]))
+		require.Equal(t, "true", string(resp["success"]), "Expected successful response to '%s': %s", command, resp["message"])
+		if body != nil {
+			require.NoError(t, json.Unmarshal(resp["body"], body))
+		}
+		return
+	}
+}
+
+// Event waits for an event with given name (skipping any other events) and returns its body.
+func (c *testClient) Event(t *testing.T, name string) string {
+	for {
+		msg := c.receive(t)
+		if string(msg["type"]) == `"event"` && string(msg["event"]) == strconv.Quote(name) {
+			return string(msg["body"])
+		}
+	}
+}
+
+func (c *testClient) Stopped(t *testing.T) dap.StoppedEventBody {
+	var body dap.StoppedEventBody
+	require.NoError(t, json.Unmarshal([]byte(c.Event(t, "stopped")), &body))
+	require.Equal(t, 1, body.ThreadID)
+	return body
+}
+
+func (c *testClient) Exited(t *testing.T) int {
+	var body dap.ExitedEventBody
+	require.NoError(t, json.Unmarshal([]byte(c.Event(t, "exited")), &body))
+	return body.ExitCode
+}
+
+// StackTrace returns frames formatted as 'name file:line'.
+func (c *testClient) StackTrace(t *testing.T) []string {
+	var body dap.StackTraceResponseBody
+	c.Call(t, "stackTrace", dap.StackTraceArguments{ThreadID: 1}, &body)
+	require.Equal(t, len(body.StackFrames), body.TotalFrames)
+
+	var result []string
+	for _, frame := range body.StackFrames {
+		result = append(result, fmt.Sprintf("%s %s:%d", frame.Name, frame.Source.Path, frame.Line))
+	}
+	return result
+}
+
+func (c *testClient) Scopes(t *testing.T, frameID int) []dap.Scope {
+	var body dap.ScopesResponseBody
+	c.Call(t, "scopes", dap.ScopesArguments{FrameID: frameID}, &body)
+	return body.Scopes
+}
+
+func (c *testClient) Variables(t *testing.T, ref int) []dap.Variable {
+	var body dap.VariablesResponseBody
+	c.Call(t, "variables", dap.VariablesArguments{VariablesReference: ref}, &body)
+	return body.Variables
+}
+
+func (c *testClient) receive(t *testing.T) map[string]json.RawMessage {
+	select {
+	case msg, ok := <-c.messages:
+		require.True(t, ok, "Expected server to keep connection open")
+		return msg
+	case <-time.After(30 * time.Second):
+		require.FailNow(t, "Timed out waiting for server message")
+		return nil
+	}
+}
+
+func (c *testClient) Wait(t *testing.T) cmdtpl.Output {
+	select {
+	case out := <-c.done:
+		return out
+	case <-time.After(30 * time.Second):
+		require.FailNow(t, "Timed out waiting for evaluation to end")
+		return cmdtpl.Output{}
+	}
+}
+
+func variableNames(vars []dap.Variable) []string {
+	var result []string
+	for _, v := range vars {
+		result = append(result, v.Name)
+	}
+	return result
+}
+
+func variableNameValues(vars []dap.Variable) []string {
+	var result []string
+	for _, v := range vars {
+		result = append(result, v.Name+"="+v.Value)
+	}
+	return result
+}
@@ -4,6 +4,8 @@
 package filepos
 
 import (
+	"bytes"
+	"encoding/binary"
 	"fmt"
 )
 
@@ -122,3 +124,84 @@ func (p *Position) IsNextTo(otherPostion *Position) bool {
 	}
 	return false
 }
+
+const (
+	gobFlagLineNum byte = 1 << iota
+	gobFlagKnown
+	gobFlagFromMemory
+)
+
+// GobEncode allows Position to be serialized via encoding/gob (e.g. to cache compiled templates).
+func (p *Position) GobEncode() ([]byte, error) {
+	var flags byte
+	if p.lineNum != nil {
+		flags |= gobFlagLineNum
+	}
+	if p.known {
+		flags |= gobFlagKnown
+	}
+	if p.fromMemory {
+		flags |= gobFlagFromMemory
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(flags)
+	if p.lineNum != nil {
+		gobWriteVarint(&buf, int64(*p.lineNum))
+	}
+	gobWriteVarint(&buf, int64(len(p.file)))
+	buf.WriteString(p.file)
+	gobWriteVarint(&buf, int64(len(p.line)))
+	buf.WriteString(p.line)
+
+	return buf.Bytes(), nil
+}
+
+// GobDecode restores Position previously serialized via GobEncode.
+func (p *Position) GobDecode(data []byte) error {
+	buf := bytes.NewReader(data)
+
+	flags, err := buf.ReadByte()
+	if err != nil {
+		return fmt.Errorf("Decoding position: %s", err)
+	}
+
+	*p = Position{known: flags&gobFlagKnown != 0, fromMemory: flags&gobFlagFromMemory != 0}
+
+	if flags&gobFlagLineNum != 0 {
+		lineNum, err := binary.ReadVarint(buf)
+		if err != nil {
+			return fmt.Errorf("Decoding position line number: %s", err)
+		}
+		lineNumInt := int(lineNum)
+		p.lineNum = &lineNumInt
+	}
+
+	p.file, err = gobReadString(buf)
+	if err != nil {
+		return fmt.Errorf("Decoding position file: %s", err)
+	}
+	p.line, err = gobReadString(buf)
+	if err != nil {
+		return fmt.Errorf("Decoding position line: %s", err)
+	}
+	return nil
+}
+
+func gobWriteVarint(buf *bytes.Buffer, val int64) {
+	var varintBs [binary.MaxVarintLen64]byte
+	buf.Write(varintBs[:binary.PutVarint(varintBs[:], val)])
+}
+
+func gobReadString(buf *bytes.Reader) (string, error) {
+	length, err := binary.ReadVarint(buf)
+	if err != nil {
+		return "", err
+	}
+	if length < 0 || length > int64(buf.Len()) {
+		return "", fmt.Errorf("Expected string length to be within data, but was %d", length)
+	}
+	strBs := make([]byte, length)
+	_, err = buf.Read(strBs)
+	return string(strBs), err
+}
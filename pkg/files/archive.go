@@ -0,0 +1,297 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package files
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+var (
+	tarExts    = []string{".tar"}
+	tarGzExts  = []string{".tgz", ".tar.gz"}
+	zipExts    = []string{".zip"}
+	gzipMagic  = []byte{0x1f, 0x8b}
+	maxSymlink = 40 // same as Linux's MAXSYMLINKS
+)
+
+// ArchiveSource is a single file found within an archive (e.g. tarball, zip or OCI image layout).
+type ArchiveSource struct {
+	archiveDesc string
+	path        string
+	data        []byte
+}
+
+func (s ArchiveSource) Description() string {
+	return fmt.Sprintf("file '%s' in %s", s.path, s.archiveDesc)
+}
+func (s ArchiveSource) RelativePath() (string, error) { return s.path, nil }
+func (s ArchiveSource) Bytes() ([]byte, error)        { return s.data, nil }
+
+// IsArchivePath reports whether path names an archive supported by NewArchiveSources (based on its extension).
+func IsArchivePath(path string) bool {
+	for _, exts := range [][]string{tarExts, tarGzExts, zipExts} {
+		if hasAnyExt(path, exts) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewArchiveSources expands the contents of an archive (tar, gzipped tar, or zip; determined by the extension of
+// archivePath) into sources, one per regular file.
+//
+// Entries that would be placed outside of the archive root (e.g. '../x' or '/x') are rejected.
+// Symlinks (and hard links) are only followed when opts.AllowAll is set, and only to files within the same archive.
+func NewArchiveSources(archivePath string, data []byte, opts SymlinkAllowOpts) ([]ArchiveSource, error) {
+	entries := newArchiveEntries(fmt.Sprintf("archive '%s'", archivePath))
+
+	var err error
+	switch {
+	case hasAnyExt(archivePath, tarGzExts):
+		err = entries.addTarGz(data)
+	case hasAnyExt(archivePath, tarExts):
+		err = entries.addTar(bytes.NewReader(data))
+	case hasAnyExt(archivePath, zipExts):
+		err = entries.addZip(data)
+	default:
+		err = fmt.Errorf("Unknown archive type (expected one of extensions: %s)",
+			strings.Join(append(append(append([]string{}, tarExts...), tarGzExts...), zipExts...), ", "))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Reading %s: %s", entries.desc, err)
+	}
+
+	return entries.AsSources(opts)
+}
+
+type archiveEntry struct {
+	data []byte
+
+	isLink     bool
+	linkTarget string // relative to archive root
+}
+
+type archiveEntries struct {
+	desc   string
+	byPath map[string]archiveEntry
+}
+
+func newArchiveEntries(desc string) *archiveEntries {
+	return &archiveEntries{desc: desc, byPath: map[string]archiveEntry{}}
+}
+
+func (e *archiveEntries) addTarGz(data []byte) error {
+	gzReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("Decompressing: %s", err)
+	}
+	defer gzReader.Close()
+
+	return e.addTar(gzReader)
+}
+
+func (e *archiveEntries) addTar(reader io.Reader) error {
+	return e.walkTar(reader, func(name string, entry archiveEntry) error {
+		return e.add(name, entry)
+	})
+}
+
+// walkTar invokes addFunc for each regular file and link in the tar stream; link targets are relative to archive root.
+func (e *archiveEntries) walkTar(reader io.Reader, addFunc func(string, archiveEntry) error) error {
+	tarReader := tar.NewReader(reader)
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeReg:
+			data, err := ioutil.ReadAll(tarReader)
+			if err != nil {
+				return fmt.Errorf("Reading entry '%s': %s", header.Name, err)
+			}
+			err = addFunc(header.Name, archiveEntry{data: data})
+			if err != nil {
+				return err
+			}
+
+		case tar.TypeSymlink:
+			if path.IsAbs(header.Linkname) {
+				return fmt.Errorf("Expected symlink '%s' -> '%s' to point within archive, but was absolute", header.Name, header.Linkname)
+			}
+			target := path.Join(path.Dir(header.Name), header.Linkname)
+			err = addFunc(header.Name, archiveEntry{isLink: true, linkTarget: target})
+			if err != nil {
+				return err
+			}
+
+		case tar.TypeLink:
+			err = addFunc(header.Name, archiveEntry{isLink: true, linkTarget: header.Linkname})
+			if err != nil {
+				return err
+			}
+
+		case tar.TypeDir, tar.TypeXGlobalHeader:
+			// do nothing
+
+		default:
+			return fmt.Errorf("Expected entry '%s' to be a regular file, directory or link, but was not", header.Name)
+		}
+	}
+}
+
+func (e *archiveEntries) addZip(data []byte) error {
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	for _, zipFile := range zipReader.File {
+		mode := zipFile.FileInfo().Mode()
+		if mode.IsDir() {
+			continue
+		}
+
+		reader, err := zipFile.Open()
+		if err != nil {
+			return fmt.Errorf("Opening entry '%s': %s", zipFile.Name, err)
+		}
+		contents, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("Reading entry '%s': %s", zipFile.Name, err)
+		}
+
+		entry := archiveEntry{data: contents}
+
+		switch {
+		case mode.IsRegular():
+			// do nothing
+		case mode&os.ModeSymlink != 0:
+			linkTarget := string(contents)
+			if path.IsAbs(linkTarget) {
+				return fmt.Errorf("Expected symlink '%s' -> '%s' to point within archive, but was absolute", zipFile.Name, linkTarget)
+			}
+			entry = archiveEntry{isLink: true, linkTarget: path.Join(path.Dir(zipFile.Name), linkTarget)}
+		default:
+			return fmt.Errorf("Expected entry '%s' to be a regular file, directory or symlink, but was not", zipFile.Name)
+		}
+
+		err = e.add(zipFile.Name, entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *archiveEntries) add(name string, entry archiveEntry) error {
+	cleanPath, err := cleanArchivePath(name)
+	if err != nil {
+		return err
+	}
+
+	if entry.isLink {
+		entry.linkTarget, err = cleanArchivePath(entry.linkTarget)
+		if err != nil {
+			return fmt.Errorf("Checking link '%s': %s", name, err)
+		}
+	}
+
+	e.byPath[cleanPath] = entry
+	return nil
+}
+
+// remove deletes entry at cleanPath along with any entries nested under it.
+func (e *archiveEntries) remove(cleanPath string) {
+	for entryPath := range e.byPath {
+		if entryPath == cleanPath || strings.HasPrefix(entryPath, cleanPath+pathSeparator) {
+			delete(e.byPath, entryPath)
+		}
+	}
+}
+
+// AsSources produces a source for each regular file (and allowed link to such file) sorted by path.
+func (e *archiveEntries) AsSources(opts SymlinkAllowOpts) ([]ArchiveSource, error) {
+	var paths []string
+	for entryPath := range e.byPath {
+		paths = append(paths, entryPath)
+	}
+	sort.Strings(paths)
+
+	var result []ArchiveSource
+
+	for _, entryPath := range paths {
+		entry := e.byPath[entryPath]
+
+		if entry.isLink {
+			if !opts.AllowAll {
+				return nil, fmt.Errorf("Expected symlink file '%s' -> '%s' in %s to be allowed, but was not "+
+					"(hint: symlinks are disallowed as a security feature, use '--dangerous-allow-all-symlink-destinations' flag to override)",
+					entryPath, entry.linkTarget, e.desc)
+			}
+
+			var err error
+			entry, err = e.resolveLink(entryPath, entry)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		result = append(result, ArchiveSource{archiveDesc: e.desc, path: entryPath, data: entry.data})
+	}
+
+	return result, nil
+}
+
+func (e *archiveEntries) resolveLink(entryPath string, entry archiveEntry) (archiveEntry, error) {
+	for i := 0; i < maxSymlink; i++ {
+		target, found := e.byPath[entry.linkTarget]
+		if !found {
+			return archiveEntry{}, fmt.Errorf("Expected link '%s' -> '%s' in %s to point to a file within archive, but did not",
+				entryPath, entry.linkTarget, e.desc)
+		}
+		if !target.isLink {
+			return target, nil
+		}
+		entry = target
+	}
+	return archiveEntry{}, fmt.Errorf("Resolving link '%s' in %s: too many levels of links", entryPath, e.desc)
+}
+
+// cleanArchivePath normalizes an entry name, ensuring that it stays within the archive root.
+func cleanArchivePath(name string) (string, error) {
+	cleanPath := path.Clean(strings.Replace(name, "\\", pathSeparator, -1))
+
+	if path.IsAbs(cleanPath) || cleanPath == ".." || strings.HasPrefix(cleanPath, "../") || cleanPath == "." {
+		return "", fmt.Errorf("Expected entry '%s' to be within archive, but was not", name)
+	}
+
+	return cleanPath, nil
+}
+
+func hasAnyExt(filePath string, exts []string) bool {
+	for _, ext := range exts {
+		if strings.HasSuffix(strings.ToLower(filePath), ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package files_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+)
+
+type testArchiveEntry struct {
+	name     string
+	content  string
+	linkname string
+}
+
+func TestArchiveFileSources(t *testing.T) {
+	entries := []testArchiveEntry{
+		{name: "config/", content: ""},
+		{name: "config/b.yml", content: "b: 1"},
+		{name: "./config/a.yml", content: "a: 1"},
+		{name: "_ytt_lib/lib/values.yml", content: "v: 1"},
+	}
+
+	t.Run("reads gzipped tarball", func(t *testing.T) {
+		path := writeTestFile(t, "bundle.tgz", newTestTarGz(t, entries))
+
+		filesFromPaths, err := files.NewSortedFilesFromPaths([]string{path}, files.SymlinkAllowOpts{})
+		require.NoError(t, err)
+		requireFileContents(t, map[string]string{
+			"_ytt_lib/lib/values.yml": "v: 1",
+			"config/a.yml":            "a: 1",
+			"config/b.yml":            "b: 1",
+		}, filesFromPaths)
+	})
+
+	t.Run("reads zip", func(t *testing.T) {
+		path := writeTestFile(t, "bundle.zip", newTestZip(t, entries))
+
+		filesFromPaths, err := files.NewSortedFilesFromPaths([]string{path}, files.SymlinkAllowOpts{})
+		require.NoError(t, err)
+		requireFileContents(t, map[string]string{
+			"_ytt_lib/lib/values.yml": "v: 1",
+			"config/a.yml":            "a: 1",
+			"config/b.yml":            "b: 1",
+		}, filesFromPaths)
+	})
+
+	t.Run("rejects relative path assignment", func(t *testing.T) {
+		path := writeTestFile(t, "bundle.tgz", newTestTarGz(t, entries))
+
+		_, err := files.NewSortedFilesFromPaths([]string{"other.tgz=" + path}, files.SymlinkAllowOpts{})
+		require.EqualError(t, err, "Expected archive '"+path+"' to not have relative path 'other.tgz' assigned "+
+			"(relative path assignment is not supported for archives)")
+	})
+
+	t.Run("rejects entries outside of archive", func(t *testing.T) {
+		path := writeTestFile(t, "bundle.tar.gz", newTestTarGz(t, []testArchiveEntry{{name: "../../etc/x.yml", content: "x"}}))
+
+		_, err := files.NewSortedFilesFromPaths([]string{path}, files.SymlinkAllowOpts{})
+		require.EqualError(t, err, "Reading archive '"+path+"': Expected entry '../../etc/x.yml' to be within archive, but was not")
+	})
+
+	t.Run("follows symlinks within archive only when allowed", func(t *testing.T) {
+		path := writeTestFile(t, "bundle.tgz", newTestTarGz(t, []testArchiveEntry{
+			{name: "a.yml", content: "a: 1"},
+			{name: "link/b.yml", linkname: "../a.yml"},
+		}))
+
+		_, err := files.NewSortedFilesFromPaths([]string{path}, files.SymlinkAllowOpts{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Expected symlink file 'link/b.yml' -> 'a.yml' in archive")
+
+		filesFromPaths, err := files.NewSortedFilesFromPaths([]string{path}, files.SymlinkAllowOpts{AllowAll: true})
+		require.NoError(t, err)
+		requireFileContents(t, map[string]string{"a.yml": "a: 1", "link/b.yml": "a: 1"}, filesFromPaths)
+
+		path = writeTestFile(t, "escape.tgz", newTestTarGz(t, []testArchiveEntry{{name: "b.yml", linkname: "../../a.yml"}}))
+		_, err = files.NewSortedFilesFromPaths([]string{path}, files.SymlinkAllowOpts{AllowAll: true})
+		require.EqualError(t, err, "Reading archive '"+path+"': Checking link 'b.yml': Expected entry '../../a.yml' to be within archive, but was not")
+	})
+}
+
+func TestOCILayoutFileSources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ytt-oci-layout")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeBlob := func(data []byte) string {
+		sum := sha256.Sum256(data)
+		hexDigest := hex.EncodeToString(sum[:])
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "blobs", "sha256"), 0700))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "blobs", "sha256", hexDigest), data, 0600))
+		return "sha256:" + hexDigest
+	}
+
+	layer1 := writeBlob(newTestTarGz(t, []testArchiveEntry{
+		{name: "config/a.yml", content: "a: 1"},
+		{name: "config/b.yml", content: "b: 1"},
+		{name: "other/x.yml", content: "x: 1"},
+	}))
+	// Whiteouts come after sibling entries to check that they only apply to lower layers
+	layer2 := writeBlob(newTestTar(t, []testArchiveEntry{
+		{name: "config/c.yml", content: "c: 1"},
+		{name: "config/.wh.b.yml", content: ""},
+		{name: "other/y.yml", content: "y: 1"},
+		{name: "other/.wh..wh..opq", content: ""},
+	}))
+
+	manifest, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"layers": []map[string]interface{}{
+			{"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": layer1},
+			{"mediaType": "application/vnd.oci.image.layer.v1.tar", "digest": layer2},
+		},
+	})
+	require.NoError(t, err)
+
+	index, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"manifests": []map[string]interface{}{
+			{"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": writeBlob(manifest)},
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "index.json"), index, 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "oci-layout"), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0600))
+
+	filesFromPaths, err := files.NewSortedFilesFromPaths([]string{"oci-layout:" + dir}, files.SymlinkAllowOpts{})
+	require.NoError(t, err)
+	requireFileContents(t, map[string]string{"config/a.yml": "a: 1", "config/c.yml": "c: 1", "other/y.yml": "y: 1"}, filesFromPaths)
+
+	t.Run("fails when blob does not match digest", func(t *testing.T) {
+		hexDigest := layer1[len("sha256:"):]
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "blobs", "sha256", hexDigest), []byte("tampered"), 0600))
+
+		_, err := files.NewSortedFilesFromPaths([]string{"oci-layout:" + dir}, files.SymlinkAllowOpts{})
+		require.EqualError(t, err, "Reading OCI layout '"+dir+"': Applying layer '"+layer1+"': Expected blob '"+layer1+"' to match its digest, but did not")
+	})
+}
+
+func requireFileContents(t *testing.T, expected map[string]string, actual []*files.File) {
+	t.Helper()
+
+	result := map[string]string{}
+	for _, file := range actual {
+		contents, err := file.Bytes()
+		require.NoError(t, err)
+		result[file.RelativePath()] = string(contents)
+	}
+	require.Equal(t, expected, result)
+}
+
+func writeTestFile(t *testing.T, name string, data []byte) string {
+	dir, err := ioutil.TempDir("", "ytt-archive")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, data, 0600))
+	return path
+}
+
+func newTestTar(t *testing.T, entries []testArchiveEntry) []byte {
+	var buf bytes.Buffer
+	tarWriter := tar.NewWriter(&buf)
+
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0600, Size: int64(len(entry.content)), Typeflag: tar.TypeReg}
+		switch {
+		case len(entry.linkname) > 0:
+			header = &tar.Header{Name: entry.name, Linkname: entry.linkname, Mode: 0600, Typeflag: tar.TypeSymlink}
+		case entry.name[len(entry.name)-1] == '/':
+			header = &tar.Header{Name: entry.name, Mode: 0700, Typeflag: tar.TypeDir}
+		}
+		require.NoError(t, tarWriter.WriteHeader(header))
+		if header.Typeflag == tar.TypeReg {
+			_, err := tarWriter.Write([]byte(entry.content))
+			require.NoError(t, err)
+		}
+	}
+
+	require.NoError(t, tarWriter.Close())
+	return buf.Bytes()
+}
+
+func newTestTarGz(t *testing.T, entries []testArchiveEntry) []byte {
+	var buf bytes.Buffer
+	gzWriter := gzip.NewWriter(&buf)
+	_, err := gzWriter.Write(newTestTar(t, entries))
+	require.NoError(t, err)
+	require.NoError(t, gzWriter.Close())
+	return buf.Bytes()
+}
+
+func newTestZip(t *testing.T, entries []testArchiveEntry) []byte {
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+
+	for _, entry := range entries {
+		writer, err := zipWriter.Create(entry.name)
+		require.NoError(t, err)
+		_, err = writer.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, zipWriter.Close())
+	return buf.Bytes()
+}
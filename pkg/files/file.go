@@ -6,9 +6,11 @@ package files
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 )
 
 var (
@@ -16,6 +18,20 @@ var (
 	starlarkExts = []string{".star"}
 	textExts     = []string{".txt"}
 	libraryExt   = "lib" // eg .lib.yaml
+
+	starlarkTestSuffix = "_test.star"
+)
+
+// Output formats of YAML templates (see File.MarkOutputFormat)
+const (
+	OutputFormatYAML       = "yaml"
+	OutputFormatJSON       = "json"
+	OutputFormatJSONPretty = "json-pretty"
+	OutputFormatJSONLines  = "jsonl"
+)
+
+var (
+	OutputFormats = []string{OutputFormatYAML, OutputFormatJSON, OutputFormatJSONPretty, OutputFormatJSONLines}
 )
 
 type Type int
@@ -35,12 +51,34 @@ type File struct {
 	markedType      *Type
 	markedTemplate  *bool
 	markedForOutput *bool
+	markedLibrary   *bool
 
-	order int // lowest comes first; 0 is used to indicate unsorted
+	// data values files of libraries are marked as not for output
+	// while templates may be evaluated concurrently
+	markedForOutputLock sync.Mutex
+
+	markedOutputFormat *string
+
+	priority int // lowest comes first; takes precedence over order
+	order    int // lowest comes first; 0 is used to indicate unsorted
 }
 
 func NewSortedFilesFromPaths(paths []string, opts SymlinkAllowOpts) ([]*File, error) {
+	files, _, err := NewSortedFilesFromPathsWithIgnored(paths, opts, HTTPSourceOpts{})
+	return files, err
+}
+
+// NewSortedFilesFromPathsWithOpts is like NewSortedFilesFromPaths, also configuring how files are fetched over HTTP.
+func NewSortedFilesFromPathsWithOpts(paths []string, opts SymlinkAllowOpts, httpOpts HTTPSourceOpts) ([]*File, error) {
+	files, _, err := NewSortedFilesFromPathsWithIgnored(paths, opts, httpOpts)
+	return files, err
+}
+
+// NewSortedFilesFromPathsWithIgnored is like NewSortedFilesFromPathsWithOpts, also returning
+// files that were skipped when walking directories due to rules in ignore files (i.e. .yttignore).
+func NewSortedFilesFromPathsWithIgnored(paths []string, opts SymlinkAllowOpts, httpOpts HTTPSourceOpts) ([]*File, []IgnoredFile, error) {
 	var groupedFiles [][]*File
+	var ignoredFiles []IgnoredFile
 
 	for _, path := range paths {
 		var files []*File
@@ -55,24 +93,55 @@ func NewSortedFilesFromPaths(paths []string, opts SymlinkAllowOpts) ([]*File, er
 			relativePath = pathPieces[0]
 			path = pathPieces[1]
 		default:
-			return nil, fmt.Errorf("Expected file '%s' to only have single '=' sign to for relative path assignment", path)
+			return nil, nil, fmt.Errorf("Expected file '%s' to only have single '=' sign to for relative path assignment", path)
 		}
 
 		switch {
 		case path == "-":
 			file, err := NewFileFromSource(NewCachedSource(NewStdinSource()))
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			if len(relativePath) > 0 {
 				file.MarkRelativePath(relativePath)
 			}
 			files = append(files, file)
 
+		case strings.HasPrefix(path, OCILayoutPathPrefix):
+			if err := checkArchiveRelativePath(path, relativePath); err != nil {
+				return nil, nil, err
+			}
+			srcs, err := NewOCILayoutSources(strings.TrimPrefix(path, OCILayoutPathPrefix), opts)
+			if err != nil {
+				return nil, nil, err
+			}
+			files, err = newFilesFromArchiveSources(srcs)
+			if err != nil {
+				return nil, nil, err
+			}
+
+		case (strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")) && isHTTPArchivePath(path):
+			if err := checkArchiveRelativePath(path, relativePath); err != nil {
+				return nil, nil, err
+			}
+			data, err := NewHTTPSourceWithOpts(path, httpOpts).Bytes()
+			if err != nil {
+				return nil, nil, err
+			}
+			url, _ := splitHTTPDigest(path)
+			srcs, err := NewArchiveSources(url, data, opts)
+			if err != nil {
+				return nil, nil, err
+			}
+			files, err = newFilesFromArchiveSources(srcs)
+			if err != nil {
+				return nil, nil, err
+			}
+
 		case strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://"):
-			file, err := NewFileFromSource(NewCachedSource(NewHTTPSource(path)))
+			file, err := NewFileFromSource(NewCachedSource(NewHTTPSourceWithOpts(path, httpOpts)))
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			if len(relativePath) > 0 {
 				file.MarkRelativePath(relativePath)
@@ -82,37 +151,47 @@ func NewSortedFilesFromPaths(paths []string, opts SymlinkAllowOpts) ([]*File, er
 		default:
 			fileInfo, err := os.Lstat(path)
 			if err != nil {
-				return nil, fmt.Errorf("Checking file '%s': %s", path, err)
+				return nil, nil, fmt.Errorf("Checking file '%s': %s", path, err)
 			}
 
-			if fileInfo.IsDir() {
-				err := filepath.Walk(path, func(walkedPath string, fi os.FileInfo, err error) error {
-					if err != nil || fi.IsDir() {
-						return err
-					}
-					regLocalSource, err := NewRegularFileLocalSource(walkedPath, path, fi, opts)
-					if err != nil {
-						return err
-					}
-					file, err := NewFileFromSource(NewCachedSource(regLocalSource))
-					if err != nil {
-						return err
-					}
-					// TODO relative path for directories?
-					files = append(files, file)
-					return nil
-				})
+			switch {
+			case !fileInfo.IsDir() && IsArchivePath(path):
+				if err := checkArchiveRelativePath(path, relativePath); err != nil {
+					return nil, nil, err
+				}
+				regLocalSource, err := NewRegularFileLocalSource(path, "", fileInfo, opts)
+				if err != nil {
+					return nil, nil, err
+				}
+				data, err := regLocalSource.Bytes()
+				if err != nil {
+					return nil, nil, fmt.Errorf("Reading archive '%s': %s", path, err)
+				}
+				srcs, err := NewArchiveSources(path, data, opts)
+				if err != nil {
+					return nil, nil, err
+				}
+				files, err = newFilesFromArchiveSources(srcs)
 				if err != nil {
-					return nil, fmt.Errorf("Listing files '%s': %s", path, err)
+					return nil, nil, err
 				}
-			} else {
+
+			case fileInfo.IsDir():
+				dirFiles, dirIgnoredFiles, err := newFilesFromDir(path, opts)
+				if err != nil {
+					return nil, nil, fmt.Errorf("Listing files '%s': %s", path, err)
+				}
+				files = append(files, dirFiles...)
+				ignoredFiles = append(ignoredFiles, dirIgnoredFiles...)
+
+			default:
 				regLocalSource, err := NewRegularFileLocalSource(path, "", fileInfo, opts)
 				if err != nil {
-					return nil, err
+					return nil, nil, err
 				}
 				file, err := NewFileFromSource(NewCachedSource(regLocalSource))
 				if err != nil {
-					return nil, err
+					return nil, nil, err
 				}
 				if len(relativePath) > 0 {
 					file.MarkRelativePath(relativePath)
@@ -141,7 +220,124 @@ func NewSortedFilesFromPaths(paths []string, opts SymlinkAllowOpts) ([]*File, er
 		allFiles = append(allFiles, files...)
 	}
 
-	return allFiles, nil
+	return allFiles, ignoredFiles, nil
+}
+
+// checkArchiveRelativePath rejects relative path assignment (e.g. name=bundle.tgz) for archives
+// since they produce multiple files with their own relative paths.
+func checkArchiveRelativePath(path, relativePath string) error {
+	if len(relativePath) > 0 {
+		return fmt.Errorf("Expected archive '%s' to not have relative path '%s' assigned "+
+			"(relative path assignment is not supported for archives)", path, relativePath)
+	}
+	return nil
+}
+
+// newFilesFromDir walks dir skipping paths excluded by ignore files found along the way.
+// Rules in an ignore file apply to its directory and all nested directories (including private libraries).
+func newFilesFromDir(dir string, opts SymlinkAllowOpts) ([]*File, []IgnoredFile, error) {
+	var result []*File
+	var ignoredFiles []IgnoredFile
+
+	rulesByDir := map[string]ignoreRules{}
+
+	err := filepath.Walk(dir, func(walkedPath string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(dir, walkedPath)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		parentRelPath := path.Dir(relPath)
+		if parentRelPath == "." {
+			parentRelPath = ""
+		}
+
+		if fi.IsDir() {
+			if relPath == "." {
+				relPath = ""
+			} else if rule, ignored := rulesByDir[parentRelPath].IsIgnored(relPath, true); ignored {
+				ignoredFiles = append(ignoredFiles, IgnoredFile{Path: relPath + pathSeparator, Rule: rule})
+				return filepath.SkipDir
+			}
+
+			rules, err := readIgnoreRules(walkedPath, relPath, opts)
+			if err != nil {
+				return err
+			}
+			rulesByDir[relPath] = append(append(ignoreRules{}, rulesByDir[parentRelPath]...), rules...)
+			return nil
+		}
+
+		if fi.Name() == IgnoreFileName {
+			return nil
+		}
+		if rule, ignored := rulesByDir[parentRelPath].IsIgnored(relPath, false); ignored {
+			ignoredFiles = append(ignoredFiles, IgnoredFile{Path: relPath, Rule: rule})
+			return nil
+		}
+
+		regLocalSource, err := NewRegularFileLocalSource(walkedPath, dir, fi, opts)
+		if err != nil {
+			return err
+		}
+		file, err := NewFileFromSource(NewCachedSource(regLocalSource))
+		if err != nil {
+			return err
+		}
+		// TODO relative path for directories?
+		result = append(result, file)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return result, ignoredFiles, nil
+}
+
+func readIgnoreRules(dirPath, relDirPath string, opts SymlinkAllowOpts) (ignoreRules, error) {
+	ignoreFilePath := filepath.Join(dirPath, IgnoreFileName)
+
+	fileInfo, err := os.Lstat(ignoreFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	regLocalSource, err := NewRegularFileLocalSource(ignoreFilePath, "", fileInfo, opts)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := regLocalSource.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return newIgnoreRules(contents, path.Join(relDirPath, IgnoreFileName), relDirPath)
+}
+
+func isHTTPArchivePath(path string) bool {
+	url, _ := splitHTTPDigest(path)
+	return IsArchivePath(url)
+}
+
+func newFilesFromArchiveSources(srcs []ArchiveSource) ([]*File, error) {
+	var result []*File
+	for _, src := range srcs {
+		file, err := NewFileFromSource(src)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, file)
+	}
+	return result, nil
 }
 
 func NewSortedFiles(files []*File) []*File {
@@ -174,6 +370,23 @@ func (r *File) Description() string { return r.src.Description() }
 
 func (r *File) OriginalRelativePath() string { return r.relPath }
 
+// LocalPath returns absolute path of a file that was read from local filesystem.
+func (r *File) LocalPath() (string, bool) {
+	fileSrc := r.src
+	if cachedSrc, ok := fileSrc.(*CachedSource); ok {
+		fileSrc = cachedSrc.src
+	}
+	src, ok := fileSrc.(LocalSource)
+	if !ok {
+		return "", false
+	}
+	path, err := filepath.Abs(src.path)
+	if err != nil {
+		return "", false
+	}
+	return path, true
+}
+
 func (r *File) MarkRelativePath(relPath string) { r.markedRelPath = &relPath }
 
 func (r *File) RelativePath() string {
@@ -204,11 +417,20 @@ func (r *File) Type() Type {
 	}
 }
 
-func (r *File) MarkForOutput(forOutput bool) { r.markedForOutput = &forOutput }
+func (r *File) MarkForOutput(forOutput bool) {
+	r.markedForOutputLock.Lock()
+	defer r.markedForOutputLock.Unlock()
+
+	r.markedForOutput = &forOutput
+}
 
 func (r *File) IsForOutput() bool {
-	if r.markedForOutput != nil {
-		return *r.markedForOutput
+	r.markedForOutputLock.Lock()
+	markedForOutput := r.markedForOutput
+	r.markedForOutputLock.Unlock()
+
+	if markedForOutput != nil {
+		return *markedForOutput
 	}
 	if r.markedTemplate != nil {
 		// it may still be for output, even though it's not a template
@@ -219,6 +441,17 @@ func (r *File) IsForOutput() bool {
 	return r.isTemplate()
 }
 
+// MarkOutputFormat changes format in which results of a YAML template are written to output files (see OutputFormats).
+func (r *File) MarkOutputFormat(format string) { r.markedOutputFormat = &format }
+
+// OutputFormat returns format in which results of a YAML template are written to output files.
+func (r *File) OutputFormat() (string, bool) {
+	if r.markedOutputFormat != nil {
+		return *r.markedOutputFormat, true
+	}
+	return OutputFormatYAML, false
+}
+
 func (r *File) MarkTemplate(template bool) { r.markedTemplate = &template }
 
 func (r *File) IsTemplate() bool {
@@ -239,7 +472,13 @@ func (r *File) IsImplied() bool {
 	return strings.ContainsRune(r.OriginalRelativePath(), os.PathSeparator)
 }
 
+func (r *File) MarkLibrary(library bool) { r.markedLibrary = &library }
+
 func (r *File) IsLibrary() bool {
+	if r.markedLibrary != nil {
+		return *r.markedLibrary
+	}
+
 	exts := strings.Split(filepath.Base(r.RelativePath()), ".")
 
 	if len(exts) > 2 && exts[len(exts)-2] == libraryExt {
@@ -250,6 +489,12 @@ func (r *File) IsLibrary() bool {
 	return r.matchesExt(starlarkExts)
 }
 
+// IsStarlarkTest reports whether this file contains Starlark unit tests (i.e. *_test.star);
+// such files are only evaluated when running tests.
+func (r *File) IsStarlarkTest() bool {
+	return r.Type() == TypeStarlark && strings.HasSuffix(filepath.Base(r.RelativePath()), starlarkTestSuffix)
+}
+
 func (r *File) matchesExt(exts []string) bool {
 	filename := filepath.Base(r.RelativePath())
 	for _, ext := range exts {
@@ -260,10 +505,16 @@ func (r *File) matchesExt(exts []string) bool {
 	return false
 }
 
+// MarkPriority changes when this file is evaluated relative to other files (default is 0; lowest comes first).
+func (r *File) MarkPriority(priority int) { r.priority = priority }
+
 func (r *File) OrderLess(otherFile *File) bool {
 	if r.order == 0 || otherFile.order == 0 {
 		panic("Missing file order assignment")
 	}
+	if r.priority != otherFile.priority {
+		return r.priority < otherFile.priority
+	}
 	return r.order < otherFile.order
 }
 
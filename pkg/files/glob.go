@@ -0,0 +1,72 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package files
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// GlobOpts configures syntax accepted by GlobToRegexp.
+type GlobOpts struct {
+	// Alternatives allows '{a,b}' to match any of comma separated patterns.
+	Alternatives bool
+}
+
+// GlobToRegexp translates glob pattern matched against slash separated paths into (unanchored) regexp.
+//
+// '*' matches any characters within a path segment and '?' matches a single one; '**/' matches any
+// number of directories and trailing '/**' matches everything within a directory (other '**' are
+// same as '*'); '[...]' matches a character class ('[!...]' negates it); '\' escapes following character.
+func GlobToRegexp(pattern string, opts GlobOpts) (string, error) {
+	var result strings.Builder
+	var braceDepth int
+
+	for i := 0; i < len(pattern); i++ {
+		ch := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/") && (i == 0 || pattern[i-1] == '/'):
+			result.WriteString("(?:.*/)?")
+			i += 2
+		case pattern[i:] == "**" && i > 0 && pattern[i-1] == '/':
+			result.WriteString(".*")
+			i++
+		case ch == '*':
+			result.WriteString("[^/]*")
+		case ch == '?':
+			result.WriteString("[^/]")
+		case ch == '[':
+			end := strings.Index(pattern[i+1:], "]")
+			if end == -1 {
+				return "", fmt.Errorf("Expected character class to be closed with ']'")
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			result.WriteString("[" + strings.Replace(class, "\\", "\\\\", -1) + "]")
+			i += end + 1
+		case ch == '\\' && i+1 < len(pattern):
+			i++
+			result.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		case ch == '{' && opts.Alternatives:
+			braceDepth++
+			result.WriteString("(?:")
+		case ch == ',' && braceDepth > 0:
+			result.WriteString("|")
+		case ch == '}' && braceDepth > 0:
+			braceDepth--
+			result.WriteString(")")
+		default:
+			result.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+
+	if braceDepth > 0 {
+		return "", fmt.Errorf("Expected alternatives to be closed with '}'")
+	}
+
+	return result.String(), nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package files_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+)
+
+func TestGlobToRegexp(t *testing.T) {
+	cases := []struct {
+		pattern    string
+		opts       files.GlobOpts
+		matches    []string
+		notMatches []string
+	}{
+		{pattern: "*.yml", matches: []string{"a.yml"}, notMatches: []string{"dir/a.yml", "a.yaml"}},
+		{pattern: "a?.yml", matches: []string{"ab.yml"}, notMatches: []string{"a.yml", "a/.yml"}},
+		{pattern: "**/*.yml", matches: []string{"a.yml", "dir/sub/a.yml"}, notMatches: []string{"a.txt"}},
+		{pattern: "dir/**", matches: []string{"dir/a", "dir/sub/a"}, notMatches: []string{"dir", "other/a"}},
+		{pattern: "a**b", matches: []string{"ab", "axxb"}, notMatches: []string{"a/b"}},
+		{pattern: "[ab].yml", matches: []string{"a.yml", "b.yml"}, notMatches: []string{"c.yml"}},
+		{pattern: "[!ab].yml", matches: []string{"c.yml"}, notMatches: []string{"a.yml"}},
+		{pattern: `\*.yml`, matches: []string{"*.yml"}, notMatches: []string{"a.yml"}},
+		{pattern: "{a,b}.yml", matches: []string{"{a,b}.yml"}, notMatches: []string{"a.yml"}},
+		{pattern: "{a,b/*}.yml", opts: files.GlobOpts{Alternatives: true},
+			matches: []string{"a.yml", "b/c.yml"}, notMatches: []string{"b.yml", "{a,b}.yml"}},
+	}
+
+	for _, tc := range cases {
+		globRegexp, err := files.GlobToRegexp(tc.pattern, tc.opts)
+		require.NoError(t, err, tc.pattern)
+		re := regexp.MustCompile("^" + globRegexp + "$")
+
+		for _, path := range tc.matches {
+			require.True(t, re.MatchString(path), "expected '%s' to match '%s'", tc.pattern, path)
+		}
+		for _, path := range tc.notMatches {
+			require.False(t, re.MatchString(path), "expected '%s' to not match '%s'", tc.pattern, path)
+		}
+	}
+
+	_, err := files.GlobToRegexp("[ab.yml", files.GlobOpts{})
+	require.EqualError(t, err, "Expected character class to be closed with ']'")
+
+	_, err = files.GlobToRegexp("{a,b.yml", files.GlobOpts{Alternatives: true})
+	require.EqualError(t, err, "Expected alternatives to be closed with '}'")
+}
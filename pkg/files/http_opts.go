@@ -0,0 +1,190 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package files
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+)
+
+const (
+	httpDigestFragmentPrefix = "#sha256="
+	httpHeaderFromEnvSep     = "="
+	httpMaxRedirects         = 10 // same as default of http.Client
+	httpDefaultTimeout       = time.Minute
+)
+
+var (
+	httpDigestRegexp = regexp.MustCompile(`^[a-f0-9]{64}$`)
+)
+
+// HTTPSourceOpts configures how files are fetched over HTTP(S).
+type HTTPSourceOpts struct {
+	Timeout       time.Duration // defaults to a minute
+	Retries       int
+	RetryInterval time.Duration // multiplied by the attempt number
+
+	// HeadersFromEnv are request headers (format: host=Header-Name=ENV_VAR) whose values are read from env
+	// variables, so that credentials do not appear in arguments. Headers are only sent to the given host.
+	HeadersFromEnv []string
+	LookupEnvFunc  func(string) (string, bool)
+
+	// CacheDir (when set) is the directory of a content-addressed cache of fetched files.
+	CacheDir string
+	// UI (when set) is used to warn when cached content is used instead of failed request's response.
+	UI ui.UI
+}
+
+func (o HTTPSourceOpts) addHeaders(req *http.Request) error {
+	lookupEnvFunc := o.LookupEnvFunc
+	if lookupEnvFunc == nil {
+		lookupEnvFunc = os.LookupEnv
+	}
+
+	for _, headerFromEnv := range o.HeadersFromEnv {
+		host, name, envVar, err := o.splitHeaderFromEnv(headerFromEnv)
+		if err != nil {
+			return err
+		}
+
+		if host != req.URL.Host {
+			continue
+		}
+
+		val, found := lookupEnvFunc(envVar)
+		if !found {
+			return fmt.Errorf("Expected env variable '%s' (for header '%s') to be set", envVar, name)
+		}
+		req.Header.Set(name, val)
+	}
+
+	return nil
+}
+
+// checkRedirect makes sure that headers from env are not sent to other hosts when following redirects
+// (HTTP client copies headers of the original request, only removing well-known sensitive ones).
+func (o HTTPSourceOpts) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= httpMaxRedirects {
+		return fmt.Errorf("stopped after %d redirects", httpMaxRedirects)
+	}
+
+	for _, headerFromEnv := range o.HeadersFromEnv {
+		_, name, _, err := o.splitHeaderFromEnv(headerFromEnv)
+		if err != nil {
+			return err
+		}
+		req.Header.Del(name)
+	}
+
+	return o.addHeaders(req)
+}
+
+func (o HTTPSourceOpts) splitHeaderFromEnv(headerFromEnv string) (string, string, string, error) {
+	pieces := strings.Split(headerFromEnv, httpHeaderFromEnvSep)
+	if len(pieces) != 3 || len(pieces[0]) == 0 || len(pieces[1]) == 0 || len(pieces[2]) == 0 {
+		return "", "", "", fmt.Errorf("Expected header from env '%s' to be in format: host=Header-Name=ENV_VAR", headerFromEnv)
+	}
+	return pieces[0], pieces[1], pieces[2], nil
+}
+
+// splitHTTPDigest separates pinned digest (if any) from the URL.
+func splitHTTPDigest(url string) (string, string) {
+	idx := strings.LastIndex(url, httpDigestFragmentPrefix)
+	if idx == -1 {
+		return url, ""
+	}
+	return url[:idx], url[idx+len(httpDigestFragmentPrefix):]
+}
+
+func httpDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// httpCache stores fetched content by its digest (blobs/<digest>) and remembers
+// the digest most recently fetched for each URL (urls/<digest of URL>).
+type httpCache struct {
+	dir string
+}
+
+func (c httpCache) Blob(digest string) ([]byte, bool) {
+	if len(c.dir) == 0 {
+		return nil, false
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(c.dir, "blobs", digest))
+	if err != nil {
+		return nil, false
+	}
+	// guard against corrupted cache entries
+	if httpDigest(data) != digest {
+		return nil, false
+	}
+	return data, true
+}
+
+func (c httpCache) BlobForURL(url string) ([]byte, bool) {
+	if len(c.dir) == 0 {
+		return nil, false
+	}
+
+	digest, err := ioutil.ReadFile(filepath.Join(c.dir, "urls", httpDigest([]byte(url))))
+	if err != nil {
+		return nil, false
+	}
+	return c.Blob(string(digest))
+}
+
+func (c httpCache) Store(url string, data []byte) error {
+	if len(c.dir) == 0 {
+		return nil
+	}
+
+	digest := httpDigest(data)
+
+	for _, entry := range []struct {
+		subDir   string
+		name     string
+		contents []byte
+	}{
+		{"blobs", digest, data},
+		{"urls", httpDigest([]byte(url)), []byte(digest)},
+	} {
+		dir := filepath.Join(c.dir, entry.subDir)
+		err := os.MkdirAll(dir, 0700)
+		if err != nil {
+			return err
+		}
+
+		// write to temporary file first so that concurrent runs never observe partial content
+		tmpFile, err := ioutil.TempFile(dir, ".tmp-")
+		if err != nil {
+			return err
+		}
+		_, err = tmpFile.Write(entry.contents)
+		closeErr := tmpFile.Close()
+		if err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmpFile.Name(), filepath.Join(dir, entry.name))
+		}
+		if err != nil {
+			os.Remove(tmpFile.Name())
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package files
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// IgnoreFileName is the name of files (in gitignore syntax) listing paths to skip when walking directories.
+	IgnoreFileName = ".yttignore"
+)
+
+// IgnoredFile is a file (or directory, with trailing '/') skipped when walking a directory.
+type IgnoredFile struct {
+	Path string
+	Rule string // describes rule that excluded the path
+}
+
+// ignoreRule is a single pattern from an ignore file.
+type ignoreRule struct {
+	desc    string
+	baseDir string // directory (relative to walked directory) containing ignore file; empty for top level
+	negate  bool
+	dirOnly bool
+	regexp  *regexp.Regexp
+}
+
+// ignoreRules are applied in order; last matching rule determines whether path is ignored.
+type ignoreRules []ignoreRule
+
+// newIgnoreRules parses ignore file contents found in baseDir.
+func newIgnoreRules(contents []byte, filePath, baseDir string) (ignoreRules, error) {
+	var rules ignoreRules
+
+	for i, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+
+		switch {
+		case len(strings.TrimSpace(line)) == 0 || strings.HasPrefix(line, "#"):
+			continue
+		case !strings.HasSuffix(line, "\\ "):
+			line = strings.TrimRight(line, " ")
+		}
+
+		rule := ignoreRule{desc: fmt.Sprintf("'%s' on line %s:%d", line, filePath, i+1), baseDir: baseDir}
+
+		pattern := line
+		switch {
+		case strings.HasPrefix(pattern, "!"):
+			rule.negate = true
+			pattern = pattern[1:]
+		case strings.HasPrefix(pattern, "\\!") || strings.HasPrefix(pattern, "\\#"):
+			pattern = pattern[1:]
+		}
+
+		if strings.HasSuffix(pattern, "/") {
+			rule.dirOnly = true
+			pattern = strings.TrimSuffix(pattern, "/")
+		}
+
+		// patterns with a separator (other than trailing) are relative to ignore file's directory
+		anchored := strings.Contains(pattern, "/")
+		pattern = strings.TrimPrefix(pattern, "/")
+
+		if len(pattern) == 0 {
+			return nil, fmt.Errorf("Expected pattern %s to not be empty", rule.desc)
+		}
+
+		re, err := ignorePatternToRegexp(pattern, anchored)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing pattern %s: %s", rule.desc, err)
+		}
+		rule.regexp = re
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// IsIgnored reports whether path (relative to walked directory) is ignored and by which rule.
+func (r ignoreRules) IsIgnored(path string, isDir bool) (string, bool) {
+	for i := len(r) - 1; i >= 0; i-- {
+		if r[i].matches(path, isDir) {
+			if r[i].negate {
+				return "", false
+			}
+			return r[i].desc, true
+		}
+	}
+	return "", false
+}
+
+func (r ignoreRule) matches(path string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if len(r.baseDir) > 0 {
+		if !strings.HasPrefix(path, r.baseDir+pathSeparator) {
+			return false
+		}
+		path = strings.TrimPrefix(path, r.baseDir+pathSeparator)
+	}
+	return r.regexp.MatchString(path)
+}
+
+func ignorePatternToRegexp(pattern string, anchored bool) (*regexp.Regexp, error) {
+	globRegexp, err := GlobToRegexp(pattern, GlobOpts{})
+	if err != nil {
+		return nil, err
+	}
+	if anchored {
+		return regexp.Compile("^" + globRegexp + "$")
+	}
+	return regexp.Compile("^(?:.*/)?" + globRegexp + "$")
+}
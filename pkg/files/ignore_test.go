@@ -0,0 +1,82 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package files_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+)
+
+func TestIgnoreFilesInDirectories(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ytt-ignore")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for relPath, content := range map[string]string{
+		".yttignore": `
+# docs and editor backups
+*.md
+*~
+!KEEP.md
+/tests/
+build/**
+`,
+		"config.yml":                    "a: 1",
+		"config.yml~":                   "a: 0",
+		"README.md":                     "readme",
+		"KEEP.md":                       "keep",
+		"tests/test.yml":                "t: 1",
+		"build/out.yml":                 "o: 1",
+		"nested/tests/kept.yml":         "k: 1",
+		"nested/README.md":              "readme",
+		"_ytt_lib/lib/.yttignore":       "\\#literal.yml\n[a-c].yml\n!b.yml\n",
+		"_ytt_lib/lib/#literal.yml":     "l: 1",
+		"_ytt_lib/lib/a.yml":            "a: 1",
+		"_ytt_lib/lib/b.yml":            "b: 1",
+		"_ytt_lib/lib/d.yml":            "d: 1",
+		"_ytt_lib/lib/docs/example.md":  "example",
+		"_ytt_lib/other/a.yml":          "a: 1",
+		"_ytt_lib/other/deep/a/x/y.yml": "y: 1",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(relPath))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	}
+
+	filesFromPaths, ignoredFiles, err := files.NewSortedFilesFromPathsWithIgnored([]string{dir}, files.SymlinkAllowOpts{}, files.HTTPSourceOpts{})
+	require.NoError(t, err)
+
+	requireFileContents(t, map[string]string{
+		"KEEP.md":                       "keep",
+		"config.yml":                    "a: 1",
+		"nested/tests/kept.yml":         "k: 1",
+		"_ytt_lib/lib/b.yml":            "b: 1",
+		"_ytt_lib/lib/d.yml":            "d: 1",
+		"_ytt_lib/other/a.yml":          "a: 1",
+		"_ytt_lib/other/deep/a/x/y.yml": "y: 1",
+	}, filesFromPaths)
+
+	require.Equal(t, []files.IgnoredFile{
+		{Path: "README.md", Rule: "'*.md' on line .yttignore:3"},
+		{Path: "_ytt_lib/lib/#literal.yml", Rule: "'\\#literal.yml' on line _ytt_lib/lib/.yttignore:1"},
+		{Path: "_ytt_lib/lib/a.yml", Rule: "'[a-c].yml' on line _ytt_lib/lib/.yttignore:2"},
+		{Path: "_ytt_lib/lib/docs/example.md", Rule: "'*.md' on line .yttignore:3"},
+		{Path: "build/out.yml", Rule: "'build/**' on line .yttignore:7"},
+		{Path: "config.yml~", Rule: "'*~' on line .yttignore:4"},
+		{Path: "nested/README.md", Rule: "'*.md' on line .yttignore:3"},
+		{Path: "tests/", Rule: "'/tests/' on line .yttignore:6"},
+	}, ignoredFiles)
+
+	t.Run("reports invalid patterns", func(t *testing.T) {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".yttignore"), []byte("[a-c.yml"), 0600))
+
+		_, err := files.NewSortedFilesFromPaths([]string{dir}, files.SymlinkAllowOpts{})
+		require.EqualError(t, err, "Listing files '"+dir+"': Parsing pattern '[a-c.yml' on line .yttignore:1: Expected character class to be closed with ']'")
+	})
+}
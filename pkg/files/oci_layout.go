@@ -0,0 +1,225 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package files
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// OCILayoutPathPrefix marks an input path as an OCI image layout directory (e.g. 'oci-layout:/path/to/dir').
+	OCILayoutPathPrefix = "oci-layout:"
+
+	ociLayoutFile      = "oci-layout"
+	ociIndexFile       = "index.json"
+	ociImageIndexType  = "application/vnd.oci.image.index.v1+json"
+	ociWhiteoutPrefix  = ".wh."
+	ociWhiteoutOpaque  = ".wh..wh..opq"
+	ociBlobsDir        = "blobs"
+	ociRefNameAnnotKey = "org.opencontainers.image.ref.name"
+)
+
+var (
+	ociDigestRegexp = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+type ociIndex struct {
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+type ociManifest struct {
+	Layers []ociDescriptor `json:"layers"`
+}
+
+// NewOCILayoutSources expands the file system of the image found in an OCI image layout directory into sources.
+//
+// The layout must contain exactly one image manifest. Its layers are applied in order (honoring whiteout files);
+// each blob is verified against its digest. Same restrictions on paths and links as NewArchiveSources apply.
+func NewOCILayoutSources(dir string, opts SymlinkAllowOpts) ([]ArchiveSource, error) {
+	layout := ociLayout{dir: dir, opts: opts}
+	entries := newArchiveEntries(fmt.Sprintf("OCI layout '%s'", dir))
+
+	err := layout.addTo(entries)
+	if err != nil {
+		return nil, fmt.Errorf("Reading %s: %s", entries.desc, err)
+	}
+
+	return entries.AsSources(opts)
+}
+
+type ociLayout struct {
+	dir  string
+	opts SymlinkAllowOpts
+}
+
+func (l ociLayout) addTo(entries *archiveEntries) error {
+	_, err := l.readFile(ociLayoutFile)
+	if err != nil {
+		return fmt.Errorf("Expected directory to be an OCI image layout: %s", err)
+	}
+
+	indexBytes, err := l.readFile(ociIndexFile)
+	if err != nil {
+		return err
+	}
+
+	var index ociIndex
+	err = json.Unmarshal(indexBytes, &index)
+	if err != nil {
+		return fmt.Errorf("Unmarshaling '%s': %s", ociIndexFile, err)
+	}
+
+	if len(index.Manifests) != 1 {
+		var refs []string
+		for _, desc := range index.Manifests {
+			refs = append(refs, fmt.Sprintf("%s (%s)", desc.Digest, desc.Annotations[ociRefNameAnnotKey]))
+		}
+		return fmt.Errorf("Expected exactly one manifest in '%s', but found %d: %s",
+			ociIndexFile, len(index.Manifests), strings.Join(refs, ", "))
+	}
+	if index.Manifests[0].MediaType == ociImageIndexType {
+		return fmt.Errorf("Expected manifest '%s' to be an image manifest, but was an image index", index.Manifests[0].Digest)
+	}
+
+	manifestBytes, err := l.readBlob(index.Manifests[0].Digest)
+	if err != nil {
+		return err
+	}
+
+	var manifest ociManifest
+	err = json.Unmarshal(manifestBytes, &manifest)
+	if err != nil {
+		return fmt.Errorf("Unmarshaling manifest '%s': %s", index.Manifests[0].Digest, err)
+	}
+
+	for _, layer := range manifest.Layers {
+		err := l.addLayer(entries, layer)
+		if err != nil {
+			return fmt.Errorf("Applying layer '%s': %s", layer.Digest, err)
+		}
+	}
+
+	return nil
+}
+
+func (l ociLayout) addLayer(entries *archiveEntries, layer ociDescriptor) error {
+	data, err := l.readBlob(layer.Digest)
+	if err != nil {
+		return err
+	}
+
+	// Whiteouts only hide entries of lower layers (regardless of their position within this layer),
+	// hence they are applied before this layer's entries are added
+	layerEntries := newArchiveEntries(entries.desc)
+	var opaqueDirs, whiteoutPaths []string
+
+	addFunc := func(name string, entry archiveEntry) error {
+		cleanPath, err := cleanArchivePath(name)
+		if err != nil {
+			return err
+		}
+
+		dir, base := path.Dir(cleanPath), path.Base(cleanPath)
+		switch {
+		case base == ociWhiteoutOpaque:
+			opaqueDirs = append(opaqueDirs, dir)
+			return nil
+
+		case strings.HasPrefix(base, ociWhiteoutPrefix):
+			whiteoutPaths = append(whiteoutPaths, path.Join(dir, strings.TrimPrefix(base, ociWhiteoutPrefix)))
+			return nil
+
+		default:
+			return layerEntries.add(cleanPath, entry)
+		}
+	}
+
+	err = l.walkLayer(data, layerEntries, addFunc)
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range opaqueDirs {
+		for entryPath := range entries.byPath {
+			if dir == "." || strings.HasPrefix(entryPath, dir+pathSeparator) {
+				delete(entries.byPath, entryPath)
+			}
+		}
+	}
+	for _, whiteoutPath := range whiteoutPaths {
+		entries.remove(whiteoutPath)
+	}
+	for entryPath, entry := range layerEntries.byPath {
+		entries.byPath[entryPath] = entry
+	}
+
+	return nil
+}
+
+func (l ociLayout) walkLayer(data []byte, entries *archiveEntries, addFunc func(string, archiveEntry) error) error {
+	reader := bytes.NewReader(data)
+
+	if bytes.HasPrefix(data, gzipMagic) {
+		gzReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return fmt.Errorf("Decompressing: %s", err)
+		}
+		defer gzReader.Close()
+
+		return entries.walkTar(gzReader, addFunc)
+	}
+
+	return entries.walkTar(reader, addFunc)
+}
+
+func (l ociLayout) readBlob(digest string) ([]byte, error) {
+	if !ociDigestRegexp.MatchString(digest) {
+		return nil, fmt.Errorf("Expected digest '%s' to be in format 'sha256:<hex>'", digest)
+	}
+
+	hexDigest := strings.TrimPrefix(digest, "sha256:")
+	data, err := l.readFile(path.Join(ociBlobsDir, "sha256", hexDigest))
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != hexDigest {
+		return nil, fmt.Errorf("Expected blob '%s' to match its digest, but did not", digest)
+	}
+
+	return data, nil
+}
+
+func (l ociLayout) readFile(relPath string) ([]byte, error) {
+	filePath := filepath.Join(l.dir, filepath.FromSlash(relPath))
+
+	fileInfo, err := os.Lstat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("Checking file '%s': %s", relPath, err)
+	}
+
+	src, err := NewRegularFileLocalSource(filePath, "", fileInfo, l.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return src.Bytes()
+}
@@ -0,0 +1,165 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package files
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+)
+
+var (
+	// outputArchiveModTime is used for all entries so that archive digest only depends on file paths and contents
+	// (zip format cannot represent times before 1980).
+	outputArchiveModTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+const (
+	outputArchiveFileMode = 0644
+)
+
+// OutputArchive writes output files into a single archive (tar, gzipped tar, or zip; determined by extension).
+// Entries are sorted by path and carry fixed timestamps and permissions, hence same files always produce
+// byte-for-byte identical archive.
+type OutputArchive struct {
+	path  string
+	files []OutputFile
+	ui    ui.UI
+}
+
+func NewOutputArchive(path string, files []OutputFile, ui ui.UI) *OutputArchive {
+	return &OutputArchive{path, files, ui}
+}
+
+func (a *OutputArchive) Write() error {
+	if !IsArchivePath(a.path) {
+		return fmt.Errorf("Expected output archive path '%s' to have one of extensions: %s", a.path,
+			strings.Join(append(append(append([]string{}, tarExts...), tarGzExts...), zipExts...), ", "))
+	}
+
+	sortedFiles := append([]OutputFile{}, a.files...)
+	sort.SliceStable(sortedFiles, func(i, j int) bool {
+		return sortedFiles[i].RelativePath() < sortedFiles[j].RelativePath()
+	})
+
+	for i := 1; i < len(sortedFiles); i++ {
+		if sortedFiles[i].RelativePath() == sortedFiles[i-1].RelativePath() {
+			return fmt.Errorf("Multiple files have same output destination paths: %s", sortedFiles[i].RelativePath())
+		}
+	}
+
+	dir := filepath.Dir(a.path)
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	// write to temporary file first so that partially written archive never replaces existing one
+	tmpFile, err := ioutil.TempFile(dir, ".ytt-archive-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	switch {
+	case hasAnyExt(a.path, tarGzExts):
+		err = a.writeTarGz(tmpFile, sortedFiles)
+	case hasAnyExt(a.path, tarExts):
+		err = a.writeTar(tmpFile, sortedFiles)
+	default:
+		err = a.writeZip(tmpFile, sortedFiles)
+	}
+
+	closeErr := tmpFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("Writing output archive '%s': %s", a.path, err)
+	}
+
+	err = os.Chmod(tmpFile.Name(), outputArchiveFileMode)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), a.path)
+}
+
+func (a *OutputArchive) writeTarGz(writer io.Writer, files []OutputFile) error {
+	// gzip header intentionally has no name or modification time
+	gzWriter := gzip.NewWriter(writer)
+
+	err := a.writeTar(gzWriter, files)
+	if err != nil {
+		return err
+	}
+
+	return gzWriter.Close()
+}
+
+func (a *OutputArchive) writeTar(writer io.Writer, files []OutputFile) error {
+	tarWriter := tar.NewWriter(writer)
+
+	for _, file := range files {
+		a.ui.Printf("archiving: %s\n", file.RelativePath())
+
+		header := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     file.RelativePath(),
+			Size:     int64(len(file.Bytes())),
+			Mode:     outputArchiveFileMode,
+			ModTime:  outputArchiveModTime,
+		}
+
+		err := tarWriter.WriteHeader(header)
+		if err != nil {
+			return err
+		}
+
+		_, err = tarWriter.Write(file.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+
+	return tarWriter.Close()
+}
+
+func (a *OutputArchive) writeZip(writer io.Writer, files []OutputFile) error {
+	zipWriter := zip.NewWriter(writer)
+
+	for _, file := range files {
+		a.ui.Printf("archiving: %s\n", file.RelativePath())
+
+		header := &zip.FileHeader{
+			Name:     file.RelativePath(),
+			Method:   zip.Deflate,
+			Modified: outputArchiveModTime,
+		}
+		header.SetMode(outputArchiveFileMode)
+
+		fileWriter, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		_, err = fileWriter.Write(file.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+
+	return zipWriter.Close()
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package files_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+)
+
+func TestOutputArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ytt-output-archive")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	outputFiles := []files.OutputFile{
+		files.NewOutputFile("z.yml", []byte("z: 1\n"), files.TypeYAML),
+		files.NewOutputFile("config/a.yml", []byte("a: 1\n"), files.TypeYAML),
+		files.NewOutputFile("config/notes.txt", []byte("notes"), files.TypeText),
+	}
+	testUI := ui.NewCustomWriterTTY(false, &bytes.Buffer{}, &bytes.Buffer{})
+
+	for _, name := range []string{"out.tgz", "out.tar", "out.zip"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "nested", name)
+
+			require.NoError(t, files.NewOutputArchive(path, outputFiles, testUI).Write())
+			firstBytes, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+
+			// same content written later (and in different order) produces identical archive
+			time.Sleep(1100 * time.Millisecond)
+			reversedFiles := []files.OutputFile{outputFiles[2], outputFiles[1], outputFiles[0]}
+			require.NoError(t, files.NewOutputArchive(path, reversedFiles, testUI).Write())
+			secondBytes, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+			require.Equal(t, firstBytes, secondBytes)
+
+			srcs, err := files.NewArchiveSources(path, secondBytes, files.SymlinkAllowOpts{})
+			require.NoError(t, err)
+
+			result := map[string]string{}
+			for _, src := range srcs {
+				relPath, err := src.RelativePath()
+				require.NoError(t, err)
+				data, err := src.Bytes()
+				require.NoError(t, err)
+				result[relPath] = string(data)
+			}
+			require.Equal(t, map[string]string{"config/a.yml": "a: 1\n", "config/notes.txt": "notes", "z.yml": "z: 1\n"}, result)
+		})
+	}
+
+	t.Run("rejects unknown extensions and duplicate paths", func(t *testing.T) {
+		err := files.NewOutputArchive(filepath.Join(dir, "out.rar"), outputFiles, testUI).Write()
+		require.EqualError(t, err, "Expected output archive path '"+filepath.Join(dir, "out.rar")+"' to have one of extensions: .tar, .tgz, .tar.gz, .zip")
+
+		duplicateFiles := append([]files.OutputFile{files.NewOutputFile("z.yml", nil, files.TypeYAML)}, outputFiles...)
+		err = files.NewOutputArchive(filepath.Join(dir, "out.zip"), duplicateFiles, testUI).Write()
+		require.EqualError(t, err, "Multiple files have same output destination paths: z.yml")
+	})
+}
@@ -4,8 +4,13 @@
 package files
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
@@ -15,6 +20,13 @@ var (
 	suspiciousOutputDirectoryPaths = []string{"/", ".", "./", ""}
 )
 
+const (
+	// OutputDirectoryManifestFile lists files produced by the last sync of an output directory.
+	OutputDirectoryManifestFile = ".ytt-output-files"
+
+	outputDirectoryManifestHeader = "# files written by ytt (used to remove them once they are no longer produced)"
+)
+
 type OutputDirectory struct {
 	path  string
 	files []OutputFile
@@ -28,6 +40,106 @@ func NewOutputDirectory(path string, files []OutputFile, ui ui.UI) *OutputDirect
 func (d *OutputDirectory) Files() []OutputFile { return d.files }
 
 func (d *OutputDirectory) Write() error {
+	err := d.checkUniquePaths()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range suspiciousOutputDirectoryPaths {
+		if d.path == path {
+			return fmt.Errorf("Expected output directory path to not be one of '%s'",
+				strings.Join(suspiciousOutputDirectoryPaths, "', '"))
+		}
+	}
+
+	err = os.RemoveAll(d.path)
+	if err != nil {
+		return err
+	}
+
+	return d.WriteFiles()
+}
+
+// Sync brings directory up to date with output files without deleting it: only changed files
+// are written, and files produced by previous sync (but not anymore) are removed.
+// Files that were not produced by ytt are left alone.
+func (d *OutputDirectory) Sync() error {
+	err := d.checkUniquePaths()
+	if err != nil {
+		return err
+	}
+
+	prevPaths, err := d.readManifest()
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(d.path, 0700)
+	if err != nil {
+		return err
+	}
+
+	var added, updated, removed, unchanged int
+	currPaths := map[string]struct{}{}
+
+	for _, file := range d.files {
+		relPath := file.RelativePath()
+		if path.Clean(filepath.ToSlash(relPath)) == OutputDirectoryManifestFile {
+			return fmt.Errorf("Expected output file path to not be '%s' as it is reserved", OutputDirectoryManifestFile)
+		}
+		currPaths[relPath] = struct{}{}
+
+		existingBytes, err := ioutil.ReadFile(file.Path(d.path))
+		switch {
+		case err == nil && bytes.Equal(existingBytes, file.Bytes()):
+			unchanged++
+			continue
+		case err == nil:
+			d.ui.Printf("updating: %s\n", file.Path(d.path))
+			updated++
+		case os.IsNotExist(err):
+			d.ui.Printf("adding: %s\n", file.Path(d.path))
+			added++
+		default:
+			return err
+		}
+
+		err = file.Create(d.path)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, prevPath := range prevPaths {
+		if _, found := currPaths[prevPath]; found {
+			continue
+		}
+
+		fullPath := filepath.Join(d.path, filepath.FromSlash(prevPath))
+
+		err := os.Remove(fullPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		d.ui.Printf("removing: %s\n", fullPath)
+		removed++
+
+		d.removeEmptyParentDirs(fullPath)
+	}
+
+	err = d.writeManifest(currPaths)
+	if err != nil {
+		return err
+	}
+
+	d.ui.Printf("synced %s: %d added, %d updated, %d removed, %d unchanged\n", d.path, added, updated, removed, unchanged)
+	return nil
+}
+
+func (d *OutputDirectory) checkUniquePaths() error {
 	filePaths := map[string]struct{}{}
 
 	for _, file := range d.files {
@@ -37,20 +149,59 @@ func (d *OutputDirectory) Write() error {
 		}
 		filePaths[path] = struct{}{}
 	}
+	return nil
+}
 
-	for _, path := range suspiciousOutputDirectoryPaths {
-		if d.path == path {
-			return fmt.Errorf("Expected output directory path to not be one of '%s'",
-				strings.Join(suspiciousOutputDirectoryPaths, "', '"))
+func (d *OutputDirectory) readManifest() ([]string, error) {
+	manifestPath := filepath.Join(d.path, OutputDirectoryManifestFile)
+
+	contents, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
 		}
+		return nil, fmt.Errorf("Reading output directory manifest: %s", err)
 	}
 
-	err := os.RemoveAll(d.path)
-	if err != nil {
-		return err
+	var paths []string
+	for _, line := range strings.Split(string(contents), "\n") {
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// never remove files outside of output directory, even if manifest was tampered with
+		cleanPath := path.Clean(line)
+		if path.IsAbs(cleanPath) || cleanPath == ".." || strings.HasPrefix(cleanPath, "../") {
+			return nil, fmt.Errorf("Expected output directory manifest '%s' to only contain paths within directory, but found '%s'", manifestPath, line)
+		}
+		paths = append(paths, line)
 	}
+	return paths, nil
+}
 
-	return d.WriteFiles()
+func (d *OutputDirectory) writeManifest(currPaths map[string]struct{}) error {
+	var paths []string
+	for relPath := range currPaths {
+		paths = append(paths, filepath.ToSlash(relPath))
+	}
+	sort.Strings(paths)
+
+	contents := strings.Join(append([]string{outputDirectoryManifestHeader}, paths...), "\n") + "\n"
+
+	return ioutil.WriteFile(filepath.Join(d.path, OutputDirectoryManifestFile), []byte(contents), 0600)
+}
+
+// removeEmptyParentDirs removes directories (within output directory) left empty after removing filePath.
+func (d *OutputDirectory) removeEmptyParentDirs(filePath string) {
+	for dir := filepath.Dir(filePath); ; dir = filepath.Dir(dir) {
+		relDir, err := filepath.Rel(d.path, dir)
+		if err != nil || relDir == "." || strings.HasPrefix(relDir, "..") {
+			return
+		}
+		// fails for non-empty directories
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
 }
 
 func (d *OutputDirectory) WriteFiles() error {
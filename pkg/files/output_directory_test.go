@@ -0,0 +1,83 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package files_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+)
+
+func TestOutputDirectorySync(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ytt-output-sync")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	outDir := filepath.Join(dir, "out")
+	require.NoError(t, os.MkdirAll(outDir, 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(outDir, "unknown.txt"), []byte("not from ytt"), 0600))
+
+	sync := func(outputFiles ...files.OutputFile) string {
+		var stdout bytes.Buffer
+		err := files.NewOutputDirectory(outDir, outputFiles, ui.NewCustomWriterTTY(false, &stdout, &bytes.Buffer{})).Sync()
+		require.NoError(t, err)
+		return stdout.String()
+	}
+
+	stdout := sync(
+		files.NewOutputFile("a.yml", []byte("a: 1\n"), files.TypeYAML),
+		files.NewOutputFile("nested/b.yml", []byte("b: 1\n"), files.TypeYAML),
+	)
+	require.Equal(t, "adding: "+filepath.Join(outDir, "a.yml")+"\n"+
+		"adding: "+filepath.Join(outDir, "nested/b.yml")+"\n"+
+		"synced "+outDir+": 2 added, 0 updated, 0 removed, 0 unchanged\n", stdout)
+
+	aInfo, err := os.Stat(filepath.Join(outDir, "a.yml"))
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+
+	stdout = sync(
+		files.NewOutputFile("a.yml", []byte("a: 1\n"), files.TypeYAML),
+		files.NewOutputFile("c.yml", []byte("c: 1\n"), files.TypeYAML),
+	)
+	require.Equal(t, "adding: "+filepath.Join(outDir, "c.yml")+"\n"+
+		"removing: "+filepath.Join(outDir, "nested/b.yml")+"\n"+
+		"synced "+outDir+": 1 added, 0 updated, 1 removed, 1 unchanged\n", stdout)
+
+	// unchanged files are not rewritten
+	aInfoAfter, err := os.Stat(filepath.Join(outDir, "a.yml"))
+	require.NoError(t, err)
+	require.Equal(t, aInfo.ModTime(), aInfoAfter.ModTime())
+
+	// empty directories of removed files are cleaned up, but unknown files stay
+	_, err = os.Stat(filepath.Join(outDir, "nested"))
+	require.True(t, os.IsNotExist(err))
+	contents, err := ioutil.ReadFile(filepath.Join(outDir, "unknown.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "not from ytt", string(contents))
+
+	stdout = sync(files.NewOutputFile("a.yml", []byte("a: 2\n"), files.TypeYAML))
+	require.Equal(t, "updating: "+filepath.Join(outDir, "a.yml")+"\n"+
+		"removing: "+filepath.Join(outDir, "c.yml")+"\n"+
+		"synced "+outDir+": 0 added, 1 updated, 1 removed, 0 unchanged\n", stdout)
+
+	manifest, err := ioutil.ReadFile(filepath.Join(outDir, files.OutputDirectoryManifestFile))
+	require.NoError(t, err)
+	require.Equal(t, "# files written by ytt (used to remove them once they are no longer produced)\na.yml\n", string(manifest))
+
+	t.Run("refuses to remove files outside of directory", func(t *testing.T) {
+		manifestPath := filepath.Join(outDir, files.OutputDirectoryManifestFile)
+		require.NoError(t, ioutil.WriteFile(manifestPath, []byte("../unknown.txt\n"), 0600))
+
+		err := files.NewOutputDirectory(outDir, nil, ui.NewCustomWriterTTY(false, &bytes.Buffer{}, &bytes.Buffer{})).Sync()
+		require.EqualError(t, err, "Expected output directory manifest '"+manifestPath+"' to only contain paths within directory, but found '../unknown.txt'")
+	})
+}
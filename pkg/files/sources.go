@@ -11,6 +11,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 type Source interface {
@@ -20,7 +21,7 @@ type Source interface {
 }
 
 var _ []Source = []Source{BytesSource{}, StdinSource{},
-	LocalSource{}, HTTPSource{}, &CachedSource{}}
+	LocalSource{}, HTTPSource{}, ArchiveSource{}, &CachedSource{}}
 
 type BytesSource struct {
 	path string
@@ -85,11 +86,27 @@ func (s LocalSource) Bytes() ([]byte, error) { return ioutil.ReadFile(s.path) }
 
 type HTTPSource struct {
 	url    string
+	digest string // hex-encoded SHA256 of expected content (if pinned)
+	opts   HTTPSourceOpts
 	Client *http.Client
 }
 
 // NewHTTPSource returns a new source of type HTTP
-func NewHTTPSource(path string) HTTPSource { return HTTPSource{path, &http.Client{}} }
+func NewHTTPSource(path string) HTTPSource { return NewHTTPSourceWithOpts(path, HTTPSourceOpts{}) }
+
+// NewHTTPSourceWithOpts returns a new source of type HTTP configured with opts.
+//
+// Content may be pinned to a specific digest via URL fragment (e.g. 'https://host/config.yml#sha256=<hex digest>').
+func NewHTTPSourceWithOpts(path string, opts HTTPSourceOpts) HTTPSource {
+	url, digest := splitHTTPDigest(path)
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = httpDefaultTimeout
+	}
+
+	return HTTPSource{url, digest, opts, &http.Client{Timeout: timeout, CheckRedirect: opts.checkRedirect}}
+}
 
 func (s HTTPSource) Description() string {
 	return fmt.Sprintf("HTTP URL '%s'", s.url)
@@ -98,23 +115,91 @@ func (s HTTPSource) Description() string {
 func (s HTTPSource) RelativePath() (string, error) { return path.Base(s.url), nil }
 
 func (s HTTPSource) Bytes() ([]byte, error) {
-	resp, err := s.Client.Get(s.url)
+	cache := httpCache{s.opts.CacheDir}
+
+	if len(s.digest) > 0 {
+		if !httpDigestRegexp.MatchString(s.digest) {
+			return nil, fmt.Errorf("Expected digest of URL '%s' to be a hex-encoded SHA256, but was '%s'", s.url, s.digest)
+		}
+		// content-addressed: no need to reach out to the server
+		if result, found := cache.Blob(s.digest); found {
+			return result, nil
+		}
+	}
+
+	result, retryable, err := s.fetch()
+	if err != nil {
+		// Cached content is only used when server cannot be reached
+		// (not when e.g. file was removed or access to it was revoked)
+		if len(s.digest) == 0 && retryable {
+			if cached, found := cache.BlobForURL(s.url); found {
+				if s.opts.UI != nil {
+					s.opts.UI.Warnf("Warning: Using cached content of URL '%s' since request failed: %s\n", s.url, err)
+				}
+				return cached, nil
+			}
+		}
+		return nil, err
+	}
+
+	if len(s.digest) > 0 {
+		if actualDigest := httpDigest(result); actualDigest != s.digest {
+			return nil, fmt.Errorf("Expected URL '%s' to have digest 'sha256=%s', but was 'sha256=%s'", s.url, s.digest, actualDigest)
+		}
+	}
+
+	err = cache.Store(s.url, result)
+	if err != nil {
+		return nil, fmt.Errorf("Caching URL '%s': %s", s.url, err)
+	}
+
+	return result, nil
+}
+
+// fetch retries requests (if configured); returns whether last failed request was worth retrying.
+func (s HTTPSource) fetch() ([]byte, bool, error) {
+	for attempt := 0; ; attempt++ {
+		result, retryable, err := s.fetchOnce()
+		if err == nil {
+			return result, false, nil
+		}
+		if !retryable || attempt >= s.opts.Retries {
+			return nil, retryable, err
+		}
+		time.Sleep(s.opts.RetryInterval * time.Duration(attempt+1))
+	}
+}
+
+// fetchOnce makes a single request for the URL; returns whether a failed request is worth retrying.
+func (s HTTPSource) fetchOnce() ([]byte, bool, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("Requesting URL '%s': %s", s.url, err)
+		return nil, false, fmt.Errorf("Requesting URL '%s': %s", s.url, err)
+	}
+
+	err = s.opts.addHeaders(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("Requesting URL '%s': %s", s.url, err)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, true, fmt.Errorf("Requesting URL '%s': %s", s.url, err)
 	}
 
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		return nil, fmt.Errorf("Requesting URL '%s': %s", s.url, resp.Status)
+		retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return nil, retryable, fmt.Errorf("Requesting URL '%s': %s", s.url, resp.Status)
 	}
 
 	result, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("Reading URL '%s': %s", s.url, err)
+		return nil, true, fmt.Errorf("Reading URL '%s': %s", s.url, err)
 	}
 
-	return result, nil
+	return result, false, nil
 }
 
 type CachedSource struct {
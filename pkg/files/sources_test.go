@@ -5,12 +5,17 @@ package files_test
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
 )
 
@@ -70,6 +75,140 @@ func TestHTTPFileSources(t *testing.T) {
 	require.EqualError(t, err, fmt.Sprintf("Requesting URL '%s': %s", url, status))
 }
 
+func TestHTTPFileSourcesWithOpts(t *testing.T) {
+	content := []byte("foo: bar")
+	sum := sha256.Sum256(content)
+	digest := hex.EncodeToString(sum[:])
+
+	var requests int
+	var failuresLeft int
+	failureStatus := http.StatusServiceUnavailable
+	var lastAuthHeader string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		lastAuthHeader = req.Header.Get("Authorization")
+		if failuresLeft > 0 {
+			failuresLeft--
+			w.WriteHeader(failureStatus)
+			return
+		}
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	url := server.URL + "/config.yml"
+
+	t.Run("verifies pinned digest", func(t *testing.T) {
+		body, err := files.NewHTTPSourceWithOpts(url+"#sha256="+digest, files.HTTPSourceOpts{}).Bytes()
+		require.NoError(t, err)
+		require.Equal(t, content, body)
+
+		badDigest := "0000000000000000000000000000000000000000000000000000000000000000"
+		_, err = files.NewHTTPSourceWithOpts(url+"#sha256="+badDigest, files.HTTPSourceOpts{}).Bytes()
+		require.EqualError(t, err, fmt.Sprintf("Expected URL '%s' to have digest 'sha256=%s', but was 'sha256=%s'", url, badDigest, digest))
+
+		relPath, err := files.NewHTTPSourceWithOpts(url+"#sha256="+digest, files.HTTPSourceOpts{}).RelativePath()
+		require.NoError(t, err)
+		require.Equal(t, "config.yml", relPath)
+	})
+
+	t.Run("sets headers from env only for given host", func(t *testing.T) {
+		opts := files.HTTPSourceOpts{
+			HeadersFromEnv: []string{server.Listener.Addr().String() + "=Authorization=TEST_TOKEN"},
+			LookupEnvFunc: func(name string) (string, bool) {
+				require.Equal(t, "TEST_TOKEN", name)
+				return "Bearer secret", true
+			},
+		}
+		_, err := files.NewHTTPSourceWithOpts(url, opts).Bytes()
+		require.NoError(t, err)
+		require.Equal(t, "Bearer secret", lastAuthHeader)
+
+		opts.HeadersFromEnv = []string{"other.example.com=Authorization=TEST_TOKEN"}
+		_, err = files.NewHTTPSourceWithOpts(url, opts).Bytes()
+		require.NoError(t, err)
+		require.Equal(t, "", lastAuthHeader)
+	})
+
+	t.Run("does not send headers from env to other hosts on redirect", func(t *testing.T) {
+		var otherHeaders http.Header
+		otherServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			otherHeaders = req.Header
+			w.Write(content)
+		}))
+		defer otherServer.Close()
+
+		redirectServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			require.Equal(t, "secret", req.Header.Get("X-Api-Key"))
+			http.Redirect(w, req, otherServer.URL+"/config.yml", http.StatusFound)
+		}))
+		defer redirectServer.Close()
+
+		opts := files.HTTPSourceOpts{
+			HeadersFromEnv: []string{
+				redirectServer.Listener.Addr().String() + "=X-Api-Key=TEST_TOKEN",
+				redirectServer.Listener.Addr().String() + "=Private-Token=TEST_TOKEN",
+			},
+			LookupEnvFunc: func(string) (string, bool) { return "secret", true },
+		}
+		body, err := files.NewHTTPSourceWithOpts(redirectServer.URL+"/config.yml", opts).Bytes()
+		require.NoError(t, err)
+		require.Equal(t, content, body)
+		require.NotNil(t, otherHeaders)
+		require.Empty(t, otherHeaders.Get("X-Api-Key"))
+		require.Empty(t, otherHeaders.Get("Private-Token"))
+	})
+
+	t.Run("retries on server errors", func(t *testing.T) {
+		requests = 0
+		failuresLeft = 2
+
+		body, err := files.NewHTTPSourceWithOpts(url, files.HTTPSourceOpts{Retries: 2}).Bytes()
+		require.NoError(t, err)
+		require.Equal(t, content, body)
+		require.Equal(t, 3, requests)
+
+		failuresLeft = 2
+		_, err = files.NewHTTPSourceWithOpts(url, files.HTTPSourceOpts{Retries: 1}).Bytes()
+		require.EqualError(t, err, fmt.Sprintf("Requesting URL '%s': 503 Service Unavailable", url))
+		failuresLeft = 0
+	})
+
+	t.Run("uses cache when offline", func(t *testing.T) {
+		cacheDir, err := ioutil.TempDir("", "ytt-http-cache")
+		require.NoError(t, err)
+		defer os.RemoveAll(cacheDir)
+
+		warnings := &bytes.Buffer{}
+		opts := files.HTTPSourceOpts{CacheDir: cacheDir, UI: ui.NewCustomWriterTTY(false, nil, warnings)}
+
+		_, err = files.NewHTTPSourceWithOpts(url, opts).Bytes()
+		require.NoError(t, err)
+
+		requests = 0
+		body, err := files.NewHTTPSourceWithOpts(url+"#sha256="+digest, opts).Bytes()
+		require.NoError(t, err)
+		require.Equal(t, content, body)
+		require.Equal(t, 0, requests, "expected pinned content to be served from cache")
+
+		failuresLeft = 1
+		body, err = files.NewHTTPSourceWithOpts(url, opts).Bytes()
+		require.NoError(t, err)
+		require.Equal(t, content, body)
+		require.Equal(t, fmt.Sprintf("Warning: Using cached content of URL '%s' since request failed: "+
+			"Requesting URL '%s': 503 Service Unavailable\n", url, url), warnings.String())
+
+		for _, status := range []int{http.StatusNotFound, http.StatusForbidden} {
+			failureStatus = status
+			failuresLeft = 1
+			_, err = files.NewHTTPSourceWithOpts(url, opts).Bytes()
+			require.EqualError(t, err, fmt.Sprintf("Requesting URL '%s': %d %s", url, status, http.StatusText(status)))
+		}
+		failureStatus = http.StatusServiceUnavailable
+	})
+}
+
 // NewTestClient returns *http.Client with Transport replaced to avoid making real calls
 func NewTestClient(fn RoundTripFunc) *http.Client {
 	return &http.Client{
@@ -0,0 +1,62 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package jsonframe reads and writes JSON messages framed with Content-Length header
+// (base protocol of both Language Server Protocol and Debug Adapter Protocol).
+package jsonframe
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+)
+
+// Reader reads framed messages one at a time.
+type Reader struct {
+	reader *textproto.Reader
+}
+
+func NewReader(in io.Reader) *Reader {
+	return &Reader{reader: textproto.NewReader(bufio.NewReader(in))}
+}
+
+// Read unmarshals next message into msg.
+func (r *Reader) Read(msg interface{}) error {
+	headers, err := r.reader.ReadMIMEHeader()
+	if err != nil {
+		return err
+	}
+
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil {
+		return fmt.Errorf("Expected valid Content-Length header: %s", err)
+	}
+	if length < 0 {
+		return fmt.Errorf("Expected Content-Length header to be non-negative, but was %d", length)
+	}
+
+	body := make([]byte, length)
+	_, err = io.ReadFull(r.reader.R, body)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(body, msg)
+	if err != nil {
+		return fmt.Errorf("Unmarshaling message: %s", err)
+	}
+	return nil
+}
+
+// Write marshals msg and writes it with Content-Length header.
+func Write(out io.Writer, msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
@@ -0,0 +1,40 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package jsonframe_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/jsonframe"
+)
+
+func TestReadWrite(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, jsonframe.Write(&buf, map[string]int{"a": 1}))
+	require.NoError(t, jsonframe.Write(&buf, map[string]int{"b": 2}))
+	require.Equal(t, "Content-Length: 7\r\n\r\n{\"a\":1}Content-Length: 7\r\n\r\n{\"b\":2}", buf.String())
+
+	reader := jsonframe.NewReader(&buf)
+
+	var msg map[string]int
+	require.NoError(t, reader.Read(&msg))
+	require.Equal(t, map[string]int{"a": 1}, msg)
+
+	msg = nil
+	require.NoError(t, reader.Read(&msg))
+	require.Equal(t, map[string]int{"b": 2}, msg)
+}
+
+func TestReadRejectsInvalidContentLength(t *testing.T) {
+	var msg map[string]int
+
+	err := jsonframe.NewReader(strings.NewReader("Content-Length: -1\r\n\r\n{}")).Read(&msg)
+	require.EqualError(t, err, "Expected Content-Length header to be non-negative, but was -1")
+
+	err = jsonframe.NewReader(strings.NewReader("Content-Length: x\r\n\r\n{}")).Read(&msg)
+	require.EqualError(t, err, `Expected valid Content-Length header: strconv.Atoi: parsing "x": invalid syntax`)
+}
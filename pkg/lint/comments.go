@@ -0,0 +1,104 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package lint
+
+import (
+	"github.com/vmware-tanzu/carvel-ytt/pkg/schema"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/spell"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/template"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/validations"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace/datavalues"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace/ref"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/yamlmeta"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/yamltemplate"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/yttlibrary/overlay"
+)
+
+// knownAnnotations are names of all annotations understood by ytt.
+var knownAnnotations = []template.AnnotationName{
+	template.AnnotationCode,
+	template.AnnotationValue,
+
+	yamltemplate.AnnotationTextTemplatedStrings,
+	yamltemplate.AnnotationMapKeyOverride,
+
+	overlay.AnnotationMerge,
+	overlay.AnnotationRemove,
+	overlay.AnnotationReplace,
+	overlay.AnnotationInsert,
+	overlay.AnnotationAppend,
+	overlay.AnnotationAssert,
+	overlay.AnnotationMatch,
+	overlay.AnnotationMatchChildDefaults,
+
+	datavalues.AnnotationDataValues,
+	datavalues.AnnotationDataValuesSchema,
+
+	ref.AnnotationLibraryRef,
+
+	schema.AnnotationNullable,
+	schema.AnnotationType,
+	schema.AnnotationDefault,
+	schema.AnnotationDescription,
+	schema.AnnotationTitle,
+	schema.AnnotationExamples,
+	schema.AnnotationDeprecated,
+	schema.AnnotationValidation,
+
+	validations.AnnotationAssertValidate,
+}
+
+// checkComments finds comments that would fail --ignore-unknown-comments=false
+// and annotations with unknown names.
+func checkComments(docSet *yamlmeta.DocumentSet) []Finding {
+	visitor := &commentsVisitor{}
+	_ = yamlmeta.Walk(docSet, visitor)
+	return visitor.findings
+}
+
+type commentsVisitor struct {
+	findings []Finding
+}
+
+var _ yamlmeta.Visitor = &commentsVisitor{}
+
+func (v *commentsVisitor) Visit(node yamlmeta.Node) error {
+	for _, comment := range node.GetComments() {
+		ann, err := template.NewAnnotationFromComment(comment.Data, comment.Position, template.MetaOpts{})
+		if err != nil {
+			v.findings = append(v.findings, newFinding(CheckUnknownComment, comment.Position,
+				"Comment '#%s' is not ytt-formatted (hint: use '#!' for comments, '#@' for annotations or code)", comment.Data))
+			continue
+		}
+
+		// Shorthand code/value annotations have no name
+		if len(ann.Name) == 0 || ann.Name == template.AnnotationComment || isKnownAnnotation(ann.Name) {
+			continue
+		}
+
+		finding := newFinding(CheckUnknownAnnotation, comment.Position, "Unknown annotation '@%s'", ann.Name)
+		if nearest := nearestKnownAnnotation(ann.Name); len(nearest) > 0 {
+			finding.Message += " (hint: did you mean '@" + nearest + "'?)"
+		}
+		v.findings = append(v.findings, finding)
+	}
+	return nil
+}
+
+func isKnownAnnotation(name template.AnnotationName) bool {
+	for _, knownName := range knownAnnotations {
+		if name == knownName {
+			return true
+		}
+	}
+	return false
+}
+
+func nearestKnownAnnotation(name template.AnnotationName) string {
+	var candidates []string
+	for _, knownName := range knownAnnotations {
+		candidates = append(candidates, string(knownName))
+	}
+	return spell.Nearest(string(name), candidates)
+}
@@ -0,0 +1,110 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package lint
+
+import (
+	"github.com/k14s/starlark-go/syntax"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/filepos"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/template"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/texttemplate"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/yamlmeta"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/yamltemplate"
+)
+
+// compiledFile is a template compiled into Starlark code (and its syntax tree).
+type compiledFile struct {
+	file     *files.File
+	template *template.CompiledTemplate
+	ast      *syntax.File
+}
+
+// compile compiles templates the same way as they are compiled during evaluation;
+// problems that prevent compilation are returned as findings (nil compiledFile is returned for non-templates).
+func (l *Linter) compile(file *files.File) (*compiledFile, []Finding, error) {
+	if !file.IsTemplate() && !file.IsLibrary() {
+		return nil, nil, nil
+	}
+
+	fileBs, err := file.Bytes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	relPath := file.RelativePath()
+	filePos := filepos.NewPosition(1)
+	filePos.SetFile(relPath)
+
+	var findings []Finding
+	var compiledTemplate *template.CompiledTemplate
+
+	switch file.Type() {
+	case files.TypeYAML:
+		docSetOpts := yamlmeta.DocSetOpts{AssociatedName: relPath, Strict: l.opts.StrictYAML}
+
+		docSet, err := yamlmeta.NewDocumentSetFromBytes(fileBs, docSetOpts)
+		if err != nil {
+			return nil, []Finding{newFinding(CheckEvaluation, filePos, "Unmarshaling YAML template: %s", err)}, nil
+		}
+
+		findings = checkComments(docSet)
+
+		if !yamltemplate.HasTemplating(docSet) {
+			return nil, findings, nil
+		}
+
+		// Unknown comments were already reported above
+		tplOpts := yamltemplate.TemplateOpts{IgnoreUnknownComments: true}
+
+		compiledTemplate, err = yamltemplate.NewTemplate(relPath, tplOpts).Compile(docSet)
+		if err != nil {
+			return nil, append(findings, newFinding(CheckEvaluation, filePos, "Compiling YAML template: %s", err)), nil
+		}
+
+	case files.TypeStarlark:
+		instructions := template.NewInstructionSet()
+		compiledTemplate = template.NewCompiledTemplate(
+			relPath, template.NewCodeFromBytes(fileBs, instructions),
+			instructions, template.NewNodes(), template.EvaluationCtxDialects{})
+
+	case files.TypeText:
+		textRoot, err := texttemplate.NewParser().Parse(fileBs, relPath)
+		if err != nil {
+			return nil, []Finding{newFinding(CheckEvaluation, filePos, "Parsing text template: %s", err)}, nil
+		}
+
+		compiledTemplate, err = texttemplate.NewTemplate(relPath).Compile(textRoot)
+		if err != nil {
+			return nil, []Finding{newFinding(CheckEvaluation, filePos, "Compiling text template: %s", err)}, nil
+		}
+
+	default:
+		return nil, nil, nil
+	}
+
+	compiled := &compiledFile{file: file, template: compiledTemplate}
+
+	compiled.ast, err = syntax.Parse(relPath, compiledTemplate.CodeAsString(), syntax.BlockScanner)
+	if err != nil {
+		if syntaxErr, ok := err.(syntax.Error); ok {
+			// Error message refers to position within compiled code
+			return nil, append(findings, newFinding(CheckEvaluation, compiled.position(syntaxErr.Pos), "Parsing code: %s", syntaxErr.Msg)), nil
+		}
+		return nil, append(findings, newFinding(CheckEvaluation, filePos, "Parsing code: %s", err)), nil
+	}
+
+	return compiled, findings, nil
+}
+
+// position maps position within compiled code to position within template.
+func (f *compiledFile) position(pos syntax.Position) *filepos.Position {
+	line := f.template.CodeAtLine(filepos.NewPosition(int(pos.Line)))
+	if line == nil || line.SourceLine == nil || !line.SourceLine.Position.IsKnown() {
+		return filepos.NewUnknownPosition()
+	}
+	// Starlark files do not associate their positions with a file
+	result := line.SourceLine.Position.DeepCopy()
+	result.SetFile(f.file.RelativePath())
+	return result
+}
@@ -0,0 +1,145 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/k14s/starlark-go/syntax"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/schema"
+)
+
+// dataValuesRead is a path of keys that was read from data values
+// (e.g. ["db", "port"] for data.values.db.port; empty when data.values is used as a whole).
+type dataValuesRead []string
+
+type dataValuesReads []dataValuesRead
+
+// DataValuesReads finds all reads of data values (via data module loaded from '@ytt:data').
+func (f *compiledFile) DataValuesReads() []dataValuesRead {
+	dataAliases := map[string]bool{}
+	for _, loadStmt := range f.loads() {
+		if loadStmt.Module.Value != "@ytt:data" {
+			continue
+		}
+		for i, from := range loadStmt.From {
+			if from.Name == "data" {
+				dataAliases[loadStmt.To[i].Name] = true
+			}
+		}
+	}
+	if len(dataAliases) == 0 {
+		return nil
+	}
+
+	var reads []dataValuesRead
+
+	var visit func(syntax.Node) bool
+	visit = func(node syntax.Node) bool {
+		switch typedNode := node.(type) {
+		case *syntax.LoadStmt:
+			return false
+
+		case *syntax.DotExpr:
+			// Only outermost expression of a.b.c chain is considered
+			var names []string
+			var expr syntax.Expr = typedNode
+			for {
+				dotExpr, ok := expr.(*syntax.DotExpr)
+				if !ok {
+					break
+				}
+				names = append([]string{dotExpr.Name.Name}, names...)
+				expr = dotExpr.X
+			}
+
+			ident, ok := expr.(*syntax.Ident)
+			if !ok {
+				syntax.Walk(expr, visit)
+				return false
+			}
+			if dataAliases[ident.Name] && names[0] == "values" {
+				reads = append(reads, dataValuesRead(names[1:]))
+			}
+			return false
+
+		case *syntax.Ident:
+			// Data module may be passed around as a whole
+			if dataAliases[typedNode.Name] {
+				reads = append(reads, dataValuesRead{})
+			}
+		}
+		return true
+	}
+	syntax.Walk(f.ast, visit)
+
+	return reads
+}
+
+// Unused finds data values (defined in given schema) that are never read.
+// Data value is considered to be read if it, any of its parents, or any of its children are read.
+func (r dataValuesReads) Unused(docType *schema.DocumentType) []Finding {
+	var findings []Finding
+
+	var check func(typ schema.Type, path []string)
+	check = func(typ schema.Type, path []string) {
+		mapType, ok := asMapType(typ)
+		if !ok {
+			return
+		}
+		for _, item := range mapType.Items {
+			itemPath := append(append([]string{}, path...), fmt.Sprintf("%s", item.Key))
+
+			read, childRead := r.find(itemPath)
+			switch {
+			case read:
+				continue
+			case childRead:
+				check(item.ValueType, itemPath)
+			default:
+				findings = append(findings, newFinding(CheckUnusedDataValue, item.Position,
+					"Data value '%s' is defined in schema, but never read", strings.Join(itemPath, ".")))
+			}
+		}
+	}
+	check(docType.GetValueType(), nil)
+
+	return findings
+}
+
+// find checks whether given path (or any of its parents) is read, or whether only some of its children are read.
+func (r dataValuesReads) find(path []string) (bool, bool) {
+	var childRead bool
+	for _, read := range r {
+		switch {
+		case isKeyPrefix(read, path):
+			return true, false
+		case isKeyPrefix(path, read):
+			childRead = true
+		}
+	}
+	return false, childRead
+}
+
+func isKeyPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func asMapType(typ schema.Type) (*schema.MapType, bool) {
+	// Nullable values wrap their actual type
+	if nullType, ok := typ.(*schema.NullType); ok {
+		typ = nullType.ValueType
+	}
+	mapType, ok := typ.(*schema.MapType)
+	return mapType, ok
+}
@@ -0,0 +1,17 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+Package lint finds common mistakes in ytt templates.
+
+Templates are parsed and compiled the same way as when they are evaluated
+(and then evaluated once with default data values) to find:
+
+  - load(...) symbols that are never used
+  - data values defined in (root library's) schema that are never read
+  - document overlays (@overlay/match without expects=...) that matched nothing
+  - annotations with unknown names (e.g. @overlay/mathc)
+  - comments that are neither ytt comments (#!) nor annotations/code (#@)
+  - functions that shadow loaded symbols, builtins or other functions
+*/
+package lint
@@ -0,0 +1,68 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/vmware-tanzu/carvel-ytt/pkg/filepos"
+)
+
+// Names of checks (as reported in Finding.Check).
+const (
+	CheckUnusedLoad        = "unused-load"
+	CheckUnusedDataValue   = "unused-data-value"
+	CheckUnmatchedOverlay  = "unmatched-overlay"
+	CheckUnknownAnnotation = "unknown-annotation"
+	CheckUnknownComment    = "unknown-comment"
+	CheckShadowedFunction  = "shadowed-function"
+	// CheckEvaluation reports errors that prevented other checks from running (e.g. invalid YAML)
+	CheckEvaluation = "evaluation"
+)
+
+// Finding is a single problem found in templates.
+type Finding struct {
+	Check   string `json:"check"`
+	File    string `json:"file"`
+	Line    int    `json:"line"` // 1-based; 0 if unknown
+	Message string `json:"message"`
+}
+
+func newFinding(check string, pos *filepos.Position, msg string, args ...interface{}) Finding {
+	finding := Finding{Check: check, Message: fmt.Sprintf(msg, args...)}
+	if pos.IsKnown() {
+		finding.File = pos.GetFile()
+		finding.Line = pos.LineNum()
+	}
+	return finding
+}
+
+// Position returns location of the finding in 'file:line' format.
+func (f Finding) Position() string {
+	switch {
+	case len(f.File) == 0:
+		return "?"
+	case f.Line == 0:
+		return f.File
+	default:
+		return fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s (%s)", f.Position(), f.Message, f.Check)
+}
+
+func sortFindings(findings []Finding) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		if findings[i].Line != findings[j].Line {
+			return findings[i].Line < findings[j].Line
+		}
+		return findings[i].Check < findings[j].Check
+	})
+}
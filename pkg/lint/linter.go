@@ -0,0 +1,127 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package lint
+
+import (
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/filepos"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace"
+)
+
+// LinterOpts configures how templates are parsed.
+type LinterOpts struct {
+	StrictYAML bool
+}
+
+// Linter runs all checks over a set of files.
+type Linter struct {
+	opts LinterOpts
+	ui   ui.UI
+}
+
+func NewLinter(opts LinterOpts, ui ui.UI) *Linter {
+	return &Linter{opts: opts, ui: ui}
+}
+
+// Lint checks given files (as if they were given to ytt via --file);
+// returned findings are sorted by their position.
+func (l *Linter) Lint(filesToLint []*files.File) ([]Finding, error) {
+	var findings []Finding
+	var compiledFiles []*compiledFile
+
+	for _, file := range filesToLint {
+		compiled, fileFindings, err := l.compile(file)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, fileFindings...)
+		if compiled != nil {
+			compiledFiles = append(compiledFiles, compiled)
+		}
+	}
+
+	for _, compiled := range compiledFiles {
+		findings = append(findings, compiled.UnusedLoads()...)
+		findings = append(findings, compiled.ShadowedFunctions()...)
+	}
+
+	rootLibrary := workspace.NewRootLibrary(filesToLint)
+
+	findings = append(findings, l.unusedDataValues(rootLibrary, compiledFiles)...)
+	findings = append(findings, l.unmatchedOverlays(rootLibrary)...)
+
+	sortFindings(findings)
+	return findings, nil
+}
+
+// unusedDataValues finds data values in root library's schema that are not read by any of root library's files.
+func (l *Linter) unusedDataValues(rootLibrary *workspace.Library, compiledFiles []*compiledFile) []Finding {
+	libraryExecution := l.newLibraryExecutionFactory(nil).New(
+		workspace.LibraryExecutionContext{Current: rootLibrary, Root: rootLibrary})
+
+	dataValuesSchema, _, err := libraryExecution.Schemas(nil)
+	if err != nil {
+		// Same error is reported when evaluating templates
+		return nil
+	}
+
+	rootFiles := map[*files.File]bool{}
+	for _, fileInLib := range rootLibrary.ListAccessibleFiles() {
+		rootFiles[fileInLib.File] = true
+	}
+
+	var reads dataValuesReads
+	for _, compiled := range compiledFiles {
+		if rootFiles[compiled.file] {
+			reads = append(reads, compiled.DataValuesReads()...)
+		}
+	}
+
+	return reads.Unused(dataValuesSchema.GetDocumentType())
+}
+
+// unmatchedOverlays evaluates templates (with default data values) to find overlays that had no effect.
+func (l *Linter) unmatchedOverlays(rootLibrary *workspace.Library) []Finding {
+	var findings []Finding
+
+	unmatchedFunc := func(pos *filepos.Position) {
+		findings = append(findings, newFinding(CheckUnmatchedOverlay, pos,
+			"Overlay did not match any documents (hint: fix its matcher, or set expects=... if that's expected)"))
+	}
+
+	libraryExecution := l.newLibraryExecutionFactory(unmatchedFunc).New(
+		workspace.LibraryExecutionContext{Current: rootLibrary, Root: rootLibrary})
+
+	err := l.eval(libraryExecution)
+	if err != nil {
+		findings = append(findings, newFinding(CheckEvaluation, filepos.NewUnknownPosition(),
+			"Evaluating templates (with default data values): %s", err))
+	}
+	return findings
+}
+
+func (l *Linter) eval(libraryExecution *workspace.LibraryExecution) error {
+	schema, librarySchemas, err := libraryExecution.Schemas(nil)
+	if err != nil {
+		return err
+	}
+
+	values, libraryValues, err := libraryExecution.Values(nil, schema)
+	if err != nil {
+		return err
+	}
+
+	_, err = libraryExecution.Eval(values, libraryValues, librarySchemas)
+	return err
+}
+
+func (l *Linter) newLibraryExecutionFactory(unmatchedFunc func(*filepos.Position)) *workspace.LibraryExecutionFactory {
+	return workspace.NewLibraryExecutionFactory(l.ui, workspace.TemplateLoaderOpts{
+		// unknown comments are reported separately
+		IgnoreUnknownComments: true,
+		StrictYAML:            l.opts.StrictYAML,
+		UnmatchedOverlayFunc:  unmatchedFunc,
+	}, false)
+}
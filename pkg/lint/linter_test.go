@@ -0,0 +1,147 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package lint_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/lint"
+)
+
+func TestLinterFindsProblems(t *testing.T) {
+	configTpl := `#@ load("@ytt:data", "data")
+#@ load("@ytt:overlay", "overlay")
+#@ load("@ytt:base64", "base64")
+#@ load("helpers.star", "greet", "unused")
+
+# plain comment
+---
+#@overlay/mathc by=overlay.all
+name: #@ greet(data.values.name)
+port: #@ data.values.db.port
+#@ def len():
+#@   return 1
+#@ end
+`
+	overlayTpl := `#@ load("@ytt:overlay", "overlay")
+#@overlay/match by=overlay.subset({"kind": "Missing"}), missing_ok=True
+---
+kind: Missing
+`
+	helpersStar := `def greet(name):
+  def greet(x):
+    return x
+  end
+  return "hello " + greet(name)
+end
+def unused():
+  return 1
+end
+def greet(n):
+  return n
+end
+`
+	schemaYAML := `#@data/values-schema
+---
+name: app
+db:
+  port: 5432
+  host: localhost
+extra:
+  a: 1
+`
+
+	findings := lintFiles(t, map[string]string{
+		"config.yml":   configTpl,
+		"overlay.yml":  overlayTpl,
+		"helpers.star": helpersStar,
+		"schema.yml":   schemaYAML,
+	})
+
+	require.Equal(t, []lint.Finding{
+		{Check: lint.CheckUnusedLoad, File: "config.yml", Line: 3, Message: "Symbol 'base64' is loaded from '@ytt:base64', but never used"},
+		{Check: lint.CheckUnusedLoad, File: "config.yml", Line: 4, Message: "Symbol 'unused' is loaded from 'helpers.star', but never used"},
+		{Check: lint.CheckUnknownComment, File: "config.yml", Line: 6, Message: "Comment '# plain comment' is not ytt-formatted (hint: use '#!' for comments, '#@' for annotations or code)"},
+		{Check: lint.CheckUnknownAnnotation, File: "config.yml", Line: 8, Message: "Unknown annotation '@overlay/mathc' (hint: did you mean '@overlay/match'?)"},
+		{Check: lint.CheckShadowedFunction, File: "config.yml", Line: 11, Message: "Function 'len' shadows builtin 'len'"},
+		{Check: lint.CheckShadowedFunction, File: "helpers.star", Line: 2, Message: "Function 'greet' shadows function defined at line 1"},
+		{Check: lint.CheckShadowedFunction, File: "helpers.star", Line: 10, Message: "Function 'greet' redefines function defined at line 1"},
+		{Check: lint.CheckUnmatchedOverlay, File: "overlay.yml", Line: 3, Message: "Overlay did not match any documents (hint: fix its matcher, or set expects=... if that's expected)"},
+		{Check: lint.CheckUnusedDataValue, File: "schema.yml", Line: 6, Message: "Data value 'db.host' is defined in schema, but never read"},
+		{Check: lint.CheckUnusedDataValue, File: "schema.yml", Line: 7, Message: "Data value 'extra' is defined in schema, but never read"},
+	}, findings)
+}
+
+func TestLinterAcceptsCleanTemplates(t *testing.T) {
+	configTpl := `#@ load("@ytt:data", "data")
+#@ load("@ytt:overlay", "overlay")
+#@ load("helpers.star", hello="greet")
+
+#! ytt comment
+---
+kind: Service
+#@yaml/text-templated-strings
+name: (@= hello(data.values.name) @)
+db: #@ data.values.db
+---
+#@ dv = data
+port: #@ dv.values.port
+---
+#@overlay/match by=overlay.subset({"kind": "Service"})
+---
+#@overlay/match missing_ok=True
+kind: Service
+`
+	helpersStar := `def greet(name):
+  def format(prefix):
+    return prefix + name
+  end
+  return format("hello ")
+end
+`
+	schemaYAML := `#@data/values-schema
+---
+name: app
+port: 8080
+db:
+  host: localhost
+`
+
+	findings := lintFiles(t, map[string]string{
+		"config.yml":   configTpl,
+		"helpers.star": helpersStar,
+		"schema.yml":   schemaYAML,
+	})
+	require.Empty(t, findings)
+}
+
+func TestLinterReportsEvaluationErrors(t *testing.T) {
+	findings := lintFiles(t, map[string]string{"config.yml": "a: [1\n"})
+
+	require.Len(t, findings, 2)
+	require.Equal(t, lint.CheckEvaluation, findings[0].Check)
+	require.Equal(t, "?", findings[0].Position())
+	require.Contains(t, findings[0].Message, "Evaluating templates (with default data values)")
+
+	require.Equal(t, lint.CheckEvaluation, findings[1].Check)
+	require.Equal(t, "config.yml:1", findings[1].Position())
+	require.Contains(t, findings[1].Message, "Unmarshaling YAML template")
+}
+
+func lintFiles(t *testing.T, filesByPath map[string]string) []lint.Finding {
+	var filesToLint []*files.File
+	for path, content := range filesByPath {
+		filesToLint = append(filesToLint, files.MustNewFileFromSource(files.NewBytesSource(path, []byte(content))))
+	}
+
+	linter := lint.NewLinter(lint.LinterOpts{}, ui.NewCustomWriterTTY(false, &bytes.Buffer{}, &bytes.Buffer{}))
+
+	findings, err := linter.Lint(files.NewSortedFiles(filesToLint))
+	require.NoError(t, err)
+	return findings
+}
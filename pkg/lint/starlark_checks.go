@@ -0,0 +1,124 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package lint
+
+import (
+	"fmt"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/syntax"
+)
+
+// loads returns all load(...) statements of the file.
+func (f *compiledFile) loads() []*syntax.LoadStmt {
+	var result []*syntax.LoadStmt
+	syntax.Walk(f.ast, func(node syntax.Node) bool {
+		if loadStmt, ok := node.(*syntax.LoadStmt); ok {
+			result = append(result, loadStmt)
+			return false
+		}
+		return true
+	})
+	return result
+}
+
+// UnusedLoads finds loaded symbols that are never referenced.
+func (f *compiledFile) UnusedLoads() []Finding {
+	used := map[string]bool{}
+
+	var visit func(syntax.Node) bool
+	visit = func(node syntax.Node) bool {
+		switch typedNode := node.(type) {
+		case *syntax.LoadStmt:
+			return false
+		case *syntax.DotExpr:
+			// Attribute names (e.g. 'x' in 'a.x') do not refer to symbols
+			syntax.Walk(typedNode.X, visit)
+			return false
+		case *syntax.Ident:
+			used[typedNode.Name] = true
+		}
+		return true
+	}
+	syntax.Walk(f.ast, visit)
+
+	var findings []Finding
+	for _, loadStmt := range f.loads() {
+		for _, to := range loadStmt.To {
+			if !used[to.Name] {
+				findings = append(findings, newFinding(CheckUnusedLoad, f.position(to.NamePos),
+					"Symbol '%s' is loaded from '%s', but never used", to.Name, loadStmt.Module.Value))
+			}
+		}
+	}
+	return findings
+}
+
+// ShadowedFunctions finds functions that shadow loaded symbols, builtins,
+// earlier definitions of top level functions, or (for nested functions) top level functions.
+func (f *compiledFile) ShadowedFunctions() []Finding {
+	loaded := map[string]syntax.Position{}
+	for _, loadStmt := range f.loads() {
+		for _, to := range loadStmt.To {
+			loaded[to.Name] = to.NamePos
+		}
+	}
+
+	topLevel := map[string]syntax.Position{}
+	var findings []Finding
+
+	check := func(def *syntax.DefStmt, nested bool) {
+		name := def.Name.Name
+		var msg string
+
+		if pos, found := loaded[name]; found {
+			msg = fmt.Sprintf("Function '%s' shadows symbol loaded at line %d", name, f.lineNum(pos))
+		} else if _, found := starlark.Universe[name]; found {
+			msg = fmt.Sprintf("Function '%s' shadows builtin '%s'", name, name)
+		} else if pos, found := topLevel[name]; found {
+			if nested {
+				msg = fmt.Sprintf("Function '%s' shadows function defined at line %d", name, f.lineNum(pos))
+			} else {
+				msg = fmt.Sprintf("Function '%s' redefines function defined at line %d", name, f.lineNum(pos))
+			}
+		}
+
+		if len(msg) > 0 {
+			findings = append(findings, newFinding(CheckShadowedFunction, f.position(def.Name.NamePos), "%s", msg))
+		}
+	}
+
+	syntax.Walk(f.ast, func(node syntax.Node) bool {
+		def, ok := node.(*syntax.DefStmt)
+		if !ok {
+			return true
+		}
+
+		check(def, false)
+
+		if _, found := topLevel[def.Name.Name]; !found {
+			topLevel[def.Name.Name] = def.Name.NamePos
+		}
+
+		for _, stmt := range def.Body {
+			syntax.Walk(stmt, func(node syntax.Node) bool {
+				if nestedDef, ok := node.(*syntax.DefStmt); ok {
+					check(nestedDef, true)
+				}
+				return true
+			})
+		}
+		return false
+	})
+
+	return findings
+}
+
+func (f *compiledFile) lineNum(pos syntax.Position) int {
+	filePos := f.position(pos)
+	if !filePos.IsKnown() {
+		return 0
+	}
+	return filePos.LineNum()
+}
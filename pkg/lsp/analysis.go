@@ -0,0 +1,396 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/schema"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace/datavalues"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/yttlibrary"
+)
+
+const (
+	yttModulePrefix = "@ytt:"
+	dataModule      = yttModulePrefix + "data"
+)
+
+var (
+	loadRegexp       = regexp.MustCompile(`load\(\s*"([^"]*)"((?:\s*,\s*(?:\w+\s*=\s*)?"[^"]*")*)\s*,?\s*\)`)
+	loadSymbolRegexp = regexp.MustCompile(`(?:(\w+)\s*=\s*)?"([^"]*)"`)
+
+	yttModuleNameCompletionRegexp = regexp.MustCompile(`load\(\s*"@ytt:(\w*)$`)
+	dataValuesCompletionRegexp    = regexp.MustCompile(`(\w+)\.values((?:\.\w+)*)\.(\w*)$`)
+	memberCompletionRegexp        = regexp.MustCompile(`(\w+)\.(\w*)$`)
+	dataValuesHoverRegexp         = regexp.MustCompile(`(\w+)\.values((?:\.\w+)+)$`)
+)
+
+// document is an open text document with its load(...) statements.
+type document struct {
+	uri   string
+	lines []string
+	loads []loadStatement
+}
+
+type loadStatement struct {
+	Module  string
+	Line    int
+	Start   int // column range of module path (including quotes)
+	End     int
+	Symbols []loadedSymbol
+}
+
+type loadedSymbol struct {
+	Alias string // name under which symbol is available (same as Name unless renamed)
+	Name  string
+	Start int // column range of symbol (including alias)
+	End   int
+}
+
+func newDocument(uri, text string) *document {
+	doc := &document{uri: uri, lines: strings.Split(text, "\n")}
+
+	for lineIdx, line := range doc.lines {
+		for _, match := range loadRegexp.FindAllStringSubmatchIndex(line, -1) {
+			stmt := loadStatement{
+				Module: line[match[2]:match[3]],
+				Line:   lineIdx,
+				Start:  match[2] - 1,
+				End:    match[3] + 1,
+			}
+			symbolsStr := line[match[4]:match[5]]
+			for _, symMatch := range loadSymbolRegexp.FindAllStringSubmatchIndex(symbolsStr, -1) {
+				sym := loadedSymbol{
+					Name:  symbolsStr[symMatch[4]:symMatch[5]],
+					Start: match[4] + symMatch[0],
+					End:   match[4] + symMatch[1],
+				}
+				sym.Alias = sym.Name
+				if symMatch[2] >= 0 {
+					sym.Alias = symbolsStr[symMatch[2]:symMatch[3]]
+				}
+				stmt.Symbols = append(stmt.Symbols, sym)
+			}
+			doc.loads = append(doc.loads, stmt)
+		}
+	}
+	return doc
+}
+
+// bytePosition converts position measured in UTF-16 code units (as sent by clients) into byte offset within its line.
+func (d *document) bytePosition(pos Position) Position {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return pos
+	}
+	return Position{Line: pos.Line, Character: byteOffset(d.lines[pos.Line], pos.Character)}
+}
+
+// byteOffset converts offset in UTF-16 code units into byte offset within line.
+func byteOffset(line string, utf16Offset int) int {
+	units := 0
+	for i, r := range line {
+		if units >= utf16Offset {
+			return i
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return len(line)
+}
+
+// utf16Offset converts byte offset within line into offset in UTF-16 code units (as expected by clients).
+func utf16Offset(line string, byteOffset int) int {
+	if byteOffset > len(line) {
+		byteOffset = len(line)
+	}
+	return len(utf16.Encode([]rune(line[:byteOffset])))
+}
+
+// linePrefix returns text of the line up to given position.
+func (d *document) linePrefix(pos Position) string {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return ""
+	}
+	line := d.lines[pos.Line]
+	if pos.Character > len(line) {
+		return line
+	}
+	return line[:pos.Character]
+}
+
+// wordAt returns identifier (and its column range) surrounding given position.
+// When withDots is true, identifier is extended to the left to include attribute access (e.g. data.values.foo).
+func (d *document) wordAt(pos Position, withDots bool) (string, int, int) {
+	if pos.Line < 0 || pos.Line >= len(d.lines) {
+		return "", 0, 0
+	}
+	line := d.lines[pos.Line]
+
+	start, end := pos.Character, pos.Character
+	if end > len(line) {
+		start, end = len(line), len(line)
+	}
+	for start > 0 && (isIdentChar(line[start-1]) || (withDots && line[start-1] == '.')) {
+		start--
+	}
+	for end < len(line) && isIdentChar(line[end]) {
+		end++
+	}
+	return line[start:end], start, end
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// findLoadedSymbol finds symbol that was loaded under given alias.
+func (d *document) findLoadedSymbol(alias string) (loadStatement, loadedSymbol, bool) {
+	for _, stmt := range d.loads {
+		for _, sym := range stmt.Symbols {
+			if sym.Alias == alias {
+				return stmt, sym, true
+			}
+		}
+	}
+	return loadStatement{}, loadedSymbol{}, false
+}
+
+func (d *document) isDataModuleAlias(alias string) bool {
+	stmt, sym, found := d.findLoadedSymbol(alias)
+	return found && stmt.Module == dataModule && sym.Name == "data"
+}
+
+// Definition finds location of a load(...) module or a loaded symbol at given position.
+func (d *document) Definition(snap *snapshot, pos Position) ([]Location, error) {
+	var module, symbolName string
+
+	pos = d.bytePosition(pos)
+
+	for _, stmt := range d.loads {
+		if stmt.Line != pos.Line {
+			continue
+		}
+		if stmt.Start <= pos.Character && pos.Character < stmt.End {
+			module = stmt.Module
+		}
+		for _, sym := range stmt.Symbols {
+			if sym.Start <= pos.Character && pos.Character < sym.End {
+				module, symbolName = stmt.Module, sym.Name
+			}
+		}
+	}
+
+	if len(module) == 0 {
+		word, _, _ := d.wordAt(pos, false)
+		stmt, sym, found := d.findLoadedSymbol(word)
+		if !found {
+			return nil, nil
+		}
+		module, symbolName = stmt.Module, sym.Name
+	}
+
+	if strings.HasPrefix(module, yttModulePrefix) {
+		return nil, nil
+	}
+
+	relPath, err := snap.FindLoadableFile(d.uri, module)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := Location{URI: snap.URI(relPath)}
+
+	if len(symbolName) > 0 {
+		text, err := snap.Text(relPath)
+		if err != nil {
+			return nil, err
+		}
+		loc.Range = findSymbolDefinition(text, symbolName)
+	}
+	return []Location{loc}, nil
+}
+
+// findSymbolDefinition finds function definition or assignment of a top level symbol
+// (in both Starlark and YAML templates); returns beginning of file if not found.
+func findSymbolDefinition(text, name string) Range {
+	defRegexp := regexp.MustCompile(fmt.Sprintf(`^(?:#@\s*)?(?:def\s+(%[1]s)\s*\(|(%[1]s)\s*=[^=])`, regexp.QuoteMeta(name)))
+
+	for lineIdx, line := range strings.Split(text, "\n") {
+		match := defRegexp.FindStringSubmatchIndex(line)
+		if match == nil {
+			continue
+		}
+		start := match[2]
+		if start < 0 {
+			start = match[4]
+		}
+		return Range{
+			Start: Position{Line: lineIdx, Character: utf16Offset(line, start)},
+			End:   Position{Line: lineIdx, Character: utf16Offset(line, start+len(name))},
+		}
+	}
+	return Range{}
+}
+
+// Completion suggests '@ytt:' module names, members of loaded '@ytt:' modules and data.values keys.
+func (d *document) Completion(snap *snapshot, pos Position) (CompletionList, error) {
+	prefix := d.linePrefix(d.bytePosition(pos))
+	api := newYTTLibraryAPI()
+
+	if yttModuleNameCompletionRegexp.MatchString(prefix) {
+		var items []CompletionItem
+		for _, name := range api.ModuleNames() {
+			items = append(items, CompletionItem{Label: name, Kind: CompletionItemKindModule})
+		}
+		return CompletionList{Items: items}, nil
+	}
+
+	if match := dataValuesCompletionRegexp.FindStringSubmatch(prefix); match != nil && d.isDataModuleAlias(match[1]) {
+		docType, err := snap.Schema(d.uri)
+		if err != nil {
+			return CompletionList{}, err
+		}
+		return CompletionList{Items: dataValuesCompletionItems(docType, splitKeys(match[2]))}, nil
+	}
+
+	if match := memberCompletionRegexp.FindStringSubmatch(prefix); match != nil {
+		stmt, sym, found := d.findLoadedSymbol(match[1])
+		if !found || !strings.HasPrefix(stmt.Module, yttModulePrefix) {
+			return CompletionList{}, nil
+		}
+		module, err := api.FindModule(strings.TrimPrefix(stmt.Module, yttModulePrefix))
+		if err != nil {
+			return CompletionList{}, nil
+		}
+		return CompletionList{Items: memberCompletionItems(module[sym.Name])}, nil
+	}
+
+	return CompletionList{}, nil
+}
+
+func memberCompletionItems(val starlark.Value) []CompletionItem {
+	attrsVal, ok := val.(starlark.HasAttrs)
+	if !ok {
+		return nil
+	}
+
+	var items []CompletionItem
+	for _, name := range attrsVal.AttrNames() {
+		attrVal, err := attrsVal.Attr(name)
+		if err != nil || attrVal == nil {
+			continue
+		}
+		kind := CompletionItemKindField
+		if _, isCallable := attrVal.(starlark.Callable); isCallable {
+			kind = CompletionItemKindFunction
+		}
+		items = append(items, CompletionItem{Label: name, Kind: kind, Detail: attrVal.Type()})
+	}
+	return items
+}
+
+func dataValuesCompletionItems(docType *schema.DocumentType, keys []string) []CompletionItem {
+	typ, found := schemaTypeAt(docType, keys)
+	if !found {
+		return nil
+	}
+	mapType, ok := asMapType(typ)
+	if !ok {
+		return nil
+	}
+
+	var items []CompletionItem
+	for _, item := range mapType.Items {
+		items = append(items, CompletionItem{
+			Label:         fmt.Sprintf("%s", item.Key),
+			Kind:          CompletionItemKindField,
+			Detail:        item.ValueType.String(),
+			Documentation: item.ValueType.GetDescription(),
+		})
+	}
+	return items
+}
+
+// Hover describes data.values key (its type and description) at given position.
+func (d *document) Hover(snap *snapshot, pos Position) (*Hover, error) {
+	word, start, end := d.wordAt(d.bytePosition(pos), true)
+
+	match := dataValuesHoverRegexp.FindStringSubmatch(word)
+	if match == nil || !d.isDataModuleAlias(match[1]) {
+		return nil, nil
+	}
+
+	docType, err := snap.Schema(d.uri)
+	if err != nil {
+		return nil, err
+	}
+
+	typ, found := schemaTypeAt(docType, splitKeys(match[2]))
+	if !found {
+		return nil, nil
+	}
+
+	contents := fmt.Sprintf("```\n%s: %s\n```", word, typ.String())
+	if desc := typ.GetDescription(); len(desc) > 0 {
+		contents += "\n\n" + desc
+	}
+
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: contents},
+		Range: &Range{
+			Start: Position{Line: pos.Line, Character: utf16Offset(d.lines[pos.Line], start)},
+			End:   Position{Line: pos.Line, Character: utf16Offset(d.lines[pos.Line], end)},
+		},
+	}, nil
+}
+
+// splitKeys splits ".foo.bar" into keys.
+func splitKeys(path string) []string {
+	if len(path) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimPrefix(path, "."), ".")
+}
+
+func schemaTypeAt(docType *schema.DocumentType, keys []string) (schema.Type, bool) {
+	typ := docType.GetValueType()
+
+	for _, key := range keys {
+		mapType, ok := asMapType(typ)
+		if !ok {
+			return nil, false
+		}
+		var found bool
+		for _, item := range mapType.Items {
+			if fmt.Sprintf("%s", item.Key) == key {
+				typ, found = item.ValueType, true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return typ, true
+}
+
+func asMapType(typ schema.Type) (*schema.MapType, bool) {
+	// Nullable values wrap their actual type
+	if nullType, ok := typ.(*schema.NullType); ok {
+		typ = nullType.ValueType
+	}
+	mapType, ok := typ.(*schema.MapType)
+	return mapType, ok
+}
+
+// newYTTLibraryAPI returns '@ytt:' modules suitable for listing their members (not for evaluation).
+func newYTTLibraryAPI() yttlibrary.API {
+	return yttlibrary.NewAPI(nil,
+		yttlibrary.NewDataModule(datavalues.NewEmptyEnvelope().Doc, nil),
+		workspace.NewLibraryModule(workspace.LibraryExecutionContext{}, nil, nil, nil).AsModule())
+}
@@ -0,0 +1,23 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+Package lsp implements a Language Server Protocol server for ytt templates.
+
+Server speaks JSON-RPC 2.0 (framed with Content-Length headers) over a pair of
+streams (typically, stdin and stdout). Workspace root (given by the client
+during initialization) is treated as if it was given to ytt via --file;
+contents of open documents take precedence over contents on disk.
+
+Supported features:
+
+  - diagnostics: errors produced by templating the workspace
+  - go-to-definition: of symbols and modules referenced in load(...) statements
+  - completion: of '@ytt:' module names and their members, and of data.values keys
+  - hover: of data.values keys, showing type and description (@schema/desc)
+
+Documents are synchronized in full (no incremental updates). Positions are
+measured in UTF-16 code units (as required by the protocol); load(...)
+statements are expected to fit on a single line.
+*/
+package lsp
@@ -0,0 +1,77 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package lsp
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/vmware-tanzu/carvel-ytt/pkg/jsonframe"
+)
+
+const (
+	jsonrpcVersion = "2.0"
+
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// request is either a request (has ID) or a notification (does not have ID).
+type request struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+func (r request) IsNotification() bool { return r.ID == nil }
+
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result"`
+}
+
+type errorResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   responseError    `json:"error"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e responseError) Error() string { return e.Message }
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// conn reads and writes messages framed with Content-Length header.
+type conn struct {
+	reader *jsonframe.Reader
+	writer io.Writer
+}
+
+func newConn(in io.Reader, out io.Writer) *conn {
+	return &conn{reader: jsonframe.NewReader(in), writer: out}
+}
+
+func (c *conn) Read() (request, error) {
+	var req request
+	err := c.reader.Read(&req)
+	if err != nil {
+		return request{}, err
+	}
+	return req, nil
+}
+
+func (c *conn) Write(msg interface{}) error {
+	return jsonframe.Write(c.writer, msg)
+}
@@ -0,0 +1,273 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/version"
+)
+
+// ServerOpts configures how workspace is templated.
+type ServerOpts struct {
+	StrictYAML bool
+}
+
+// Server handles requests of a single client.
+type Server struct {
+	conn *conn
+	opts ServerOpts
+	ui   ui.UI
+
+	rootDir       string
+	docs          map[string]string   // by URI
+	diagnosedURIs map[string]struct{} // documents with published (non-empty) diagnostics
+	shutdown      bool
+}
+
+func NewServer(in io.Reader, out io.Writer, opts ServerOpts, ui ui.UI) *Server {
+	return &Server{
+		conn:          newConn(in, out),
+		opts:          opts,
+		ui:            ui,
+		docs:          map[string]string{},
+		diagnosedURIs: map[string]struct{}{},
+	}
+}
+
+// Run handles messages until client sends 'exit' notification (or closes its stream).
+func (s *Server) Run() error {
+	for {
+		req, err := s.conn.Read()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		s.ui.Debugf("lsp: received '%s'\n", req.Method)
+
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("Expected 'shutdown' request before 'exit' notification")
+			}
+			return nil
+		}
+
+		result, err := s.handle(req)
+
+		if req.IsNotification() {
+			if err != nil {
+				s.ui.Warnf("lsp: handling '%s': %s\n", req.Method, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			respErr, ok := err.(responseError)
+			if !ok {
+				respErr = responseError{Code: codeInternalError, Message: err.Error()}
+			}
+			err = s.conn.Write(errorResponse{JSONRPC: jsonrpcVersion, ID: req.ID, Error: respErr})
+		} else {
+			err = s.conn.Write(response{JSONRPC: jsonrpcVersion, ID: req.ID, Result: result})
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(req request) (interface{}, error) {
+	switch req.Method {
+	case "initialize":
+		var params InitializeParams
+		if err := s.unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		return s.initialize(params)
+
+	case "initialized":
+		return nil, nil
+
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := s.unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		s.docs[params.TextDocument.URI] = params.TextDocument.Text
+		return nil, s.publishDiagnostics()
+
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := s.unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		// Only full document sync is supported, hence last change holds whole document
+		if len(params.ContentChanges) > 0 {
+			s.docs[params.TextDocument.URI] = params.ContentChanges[len(params.ContentChanges)-1].Text
+		}
+		return nil, s.publishDiagnostics()
+
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := s.unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, s.publishDiagnostics()
+
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := s.unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		doc, snap, err := s.document(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return doc.Definition(snap, params.Position)
+
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err := s.unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		doc, snap, err := s.document(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return doc.Completion(snap, params.Position)
+
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := s.unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		doc, snap, err := s.document(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return doc.Hover(snap, params.Position)
+
+	default:
+		// Notifications that are not understood (e.g. '$/cancelRequest') are ignored
+		if req.IsNotification() {
+			return nil, nil
+		}
+		return nil, responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("Unsupported method '%s'", req.Method)}
+	}
+}
+
+func (s *Server) unmarshalParams(req request, params interface{}) error {
+	err := json.Unmarshal(req.Params, params)
+	if err != nil {
+		return responseError{Code: codeInvalidParams, Message: fmt.Sprintf("Unmarshaling '%s' params: %s", req.Method, err)}
+	}
+	return nil
+}
+
+func (s *Server) initialize(params InitializeParams) (interface{}, error) {
+	switch {
+	case len(params.RootURI) > 0:
+		path, ok := uriToPath(params.RootURI)
+		if !ok {
+			return nil, fmt.Errorf("Expected root URI '%s' to be a file URI", params.RootURI)
+		}
+		s.rootDir = path
+
+	case len(params.RootPath) > 0:
+		s.rootDir = params.RootPath
+
+	default:
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		s.rootDir = cwd
+	}
+
+	return InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:   TextDocumentSyncFull,
+			CompletionProvider: CompletionOptions{TriggerCharacters: []string{".", ":"}},
+			DefinitionProvider: true,
+			HoverProvider:      true,
+		},
+		ServerInfo: ServerInfo{Name: "ytt", Version: version.Version},
+	}, nil
+}
+
+func (s *Server) document(uri string) (*document, *snapshot, error) {
+	text, found := s.docs[uri]
+	if !found {
+		return nil, nil, responseError{Code: codeInvalidParams, Message: fmt.Sprintf("Expected document '%s' to be open", uri)}
+	}
+	snap, err := newSnapshot(s.rootDir, s.docs, s.opts, s.ui)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newDocument(uri, text), snap, nil
+}
+
+// publishDiagnostics templates workspace and publishes errors for open documents
+// and any other affected files (clearing previously published ones that were fixed).
+func (s *Server) publishDiagnostics() error {
+	var diagsByURI map[string][]Diagnostic
+
+	snap, err := newSnapshot(s.rootDir, s.docs, s.opts, s.ui)
+	if err != nil {
+		diagsByURI = map[string][]Diagnostic{}
+		for uri := range s.docs {
+			diagsByURI[uri] = []Diagnostic{newLineDiagnostic(1, err.Error())}
+		}
+	} else {
+		diagsByURI = snap.Diagnostics()
+	}
+
+	// Always publish for open documents (and previously diagnosed ones) so that fixed errors are cleared
+	for uri := range s.docs {
+		if _, found := diagsByURI[uri]; !found {
+			diagsByURI[uri] = []Diagnostic{}
+		}
+	}
+	for uri := range s.diagnosedURIs {
+		if _, found := diagsByURI[uri]; !found {
+			diagsByURI[uri] = []Diagnostic{}
+		}
+	}
+
+	var uris []string
+	for uri := range diagsByURI {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+
+	s.diagnosedURIs = map[string]struct{}{}
+
+	for _, uri := range uris {
+		err := s.conn.Write(notification{
+			JSONRPC: jsonrpcVersion,
+			Method:  "textDocument/publishDiagnostics",
+			Params:  PublishDiagnosticsParams{URI: uri, Diagnostics: diagsByURI[uri]},
+		})
+		if err != nil {
+			return err
+		}
+		if len(diagsByURI[uri]) > 0 {
+			s.diagnosedURIs[uri] = struct{}{}
+		}
+	}
+	return nil
+}
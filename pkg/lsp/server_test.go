@@ -0,0 +1,316 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package lsp_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/jsonframe"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/lsp"
+)
+
+const configTpl = `#@ load("@ytt:data", "data")
+#@ load("@ytt:base64", "base64")
+#@ load("helpers.star", "greet")
+#@ load("@lib:funcs.star", dbl="double")
+---
+greeting: #@ greet(data.values.name)
+port: #@ dbl(data.values.db.port)
+enc: #@ base64.encode("x")
+`
+
+func TestServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ytt-lsp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "schema.yml", `#@data/values-schema
+---
+#@schema/desc "Name of the app"
+name: app
+db:
+  #@schema/desc "Database port"
+  port: 5432
+`)
+	writeFile(t, dir, "helpers.star", "load(\"@ytt:struct\", \"struct\")\n\ndef greet(name):\n  return \"hello \" + name\nend\n")
+	writeFile(t, dir, "_ytt_lib/lib/funcs.star", "def double(x):\n  return x * 2\nend\n")
+	writeFile(t, dir, "config.yml", configTpl)
+
+	client := startClient(t)
+	configURI := fileURI(filepath.Join(dir, "config.yml"))
+
+	var initResult lsp.InitializeResult
+	client.Call(t, "initialize", lsp.InitializeParams{RootURI: fileURI(dir)}, &initResult)
+	require.True(t, initResult.Capabilities.DefinitionProvider)
+	require.True(t, initResult.Capabilities.HoverProvider)
+	client.Notify(t, "initialized", struct{}{})
+
+	t.Run("publishes diagnostics for open documents", func(t *testing.T) {
+		client.Notify(t, "textDocument/didOpen", lsp.DidOpenTextDocumentParams{
+			TextDocument: lsp.TextDocumentItem{URI: configURI, LanguageID: "yaml", Version: 1, Text: configTpl}})
+		require.Empty(t, client.Diagnostics(t, configURI))
+
+		client.ChangeDocument(t, configURI, configTpl+"bad: #@ 1 + \"a\"\n")
+		diags := client.Diagnostics(t, configURI)
+		require.Len(t, diags, 1)
+		require.Equal(t, 8, diags[0].Range.Start.Line)
+		require.Equal(t, "unknown binary op: int + string", diags[0].Message)
+		require.Equal(t, lsp.SeverityError, diags[0].Severity)
+
+		client.ChangeDocument(t, configURI, "a: [1\n")
+		diags = client.Diagnostics(t, configURI)
+		require.Len(t, diags, 1)
+		require.Equal(t, 0, diags[0].Range.Start.Line)
+		require.Contains(t, diags[0].Message, "Unmarshaling YAML template 'config.yml'")
+
+		client.ChangeDocument(t, configURI, configTpl)
+		require.Empty(t, client.Diagnostics(t, configURI))
+	})
+
+	t.Run("finds definitions of loaded symbols and modules", func(t *testing.T) {
+		var locs []lsp.Location
+		client.Call(t, "textDocument/definition", positionParams(configURI, configTpl, 5, "greet("), &locs)
+		require.Equal(t, []lsp.Location{{
+			URI:   fileURI(filepath.Join(dir, "helpers.star")),
+			Range: lsp.Range{Start: lsp.Position{Line: 2, Character: 4}, End: lsp.Position{Line: 2, Character: 9}},
+		}}, locs)
+
+		client.Call(t, "textDocument/definition", positionParams(configURI, configTpl, 6, "dbl("), &locs)
+		require.Equal(t, []lsp.Location{{
+			URI:   fileURI(filepath.Join(dir, "_ytt_lib/lib/funcs.star")),
+			Range: lsp.Range{Start: lsp.Position{Line: 0, Character: 4}, End: lsp.Position{Line: 0, Character: 10}},
+		}}, locs)
+
+		client.Call(t, "textDocument/definition", positionParams(configURI, configTpl, 2, "helpers.star"), &locs)
+		require.Equal(t, []lsp.Location{{URI: fileURI(filepath.Join(dir, "helpers.star"))}}, locs)
+
+		client.Call(t, "textDocument/definition", positionParams(configURI, configTpl, 1, "base64\")"), &locs)
+		require.Empty(t, locs)
+	})
+
+	t.Run("completes ytt library modules and their members", func(t *testing.T) {
+		var list lsp.CompletionList
+		client.Call(t, "textDocument/completion", positionParams(configURI, configTpl, 7, "encode"), &list)
+		require.Contains(t, completionLabels(list), "encode")
+		require.Contains(t, completionLabels(list), "decode")
+
+		client.Call(t, "textDocument/completion", positionParams(configURI, configTpl, 1, "base64\", "), &list)
+		require.Contains(t, completionLabels(list), "overlay")
+		require.Contains(t, completionLabels(list), "data")
+	})
+
+	t.Run("completes data values keys", func(t *testing.T) {
+		var list lsp.CompletionList
+		client.Call(t, "textDocument/completion", positionParams(configURI, configTpl, 5, "name)"), &list)
+		require.Equal(t, []lsp.CompletionItem{
+			{Label: "name", Kind: lsp.CompletionItemKindField, Detail: "string", Documentation: "Name of the app"},
+			{Label: "db", Kind: lsp.CompletionItemKindField, Detail: "map"},
+		}, list.Items)
+
+		client.Call(t, "textDocument/completion", positionParams(configURI, configTpl, 6, "port)"), &list)
+		require.Equal(t, []lsp.CompletionItem{
+			{Label: "port", Kind: lsp.CompletionItemKindField, Detail: "integer", Documentation: "Database port"},
+		}, list.Items)
+	})
+
+	t.Run("shows data values descriptions on hover", func(t *testing.T) {
+		var hover *lsp.Hover
+		client.Call(t, "textDocument/hover", positionParams(configURI, configTpl, 6, "ort)"), &hover)
+		require.NotNil(t, hover)
+		require.Equal(t, "```\ndata.values.db.port: integer\n```\n\nDatabase port", hover.Contents.Value)
+
+		hover = nil
+		client.Call(t, "textDocument/hover", positionParams(configURI, configTpl, 5, "greet("), &hover)
+		require.Nil(t, hover)
+	})
+
+	t.Run("measures positions in UTF-16 code units", func(t *testing.T) {
+		text := configTpl + "ünïcode: #@ \"😀\" + str(data.values.db.port)\n"
+		client.ChangeDocument(t, configURI, text)
+		require.Empty(t, client.Diagnostics(t, configURI))
+
+		line := strings.Split(text, "\n")[8]
+		utf16Idx := func(substr string) int {
+			return len(utf16.Encode([]rune(line[:strings.LastIndex(line, substr)])))
+		}
+
+		var hover *lsp.Hover
+		client.Call(t, "textDocument/hover", lsp.TextDocumentPositionParams{
+			TextDocument: lsp.TextDocumentIdentifier{URI: configURI},
+			Position:     lsp.Position{Line: 8, Character: utf16Idx("ort)")},
+		}, &hover)
+		require.NotNil(t, hover)
+		require.Equal(t, "```\ndata.values.db.port: integer\n```\n\nDatabase port", hover.Contents.Value)
+		require.Equal(t, &lsp.Range{
+			Start: lsp.Position{Line: 8, Character: utf16Idx("data.values")},
+			End:   lsp.Position{Line: 8, Character: utf16Idx(")")},
+		}, hover.Range)
+
+		client.ChangeDocument(t, configURI, configTpl)
+		require.Empty(t, client.Diagnostics(t, configURI))
+	})
+
+	client.Call(t, "shutdown", nil, nil)
+	client.Notify(t, "exit", nil)
+	require.NoError(t, client.Wait(t))
+}
+
+type testClient struct {
+	writer   io.WriteCloser
+	messages chan map[string]json.RawMessage
+	done     chan error
+	nextID   int
+}
+
+func startClient(t *testing.T) *testClient {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	client := &testClient{
+		writer:   clientWriter,
+		messages: make(chan map[string]json.RawMessage, 100),
+		done:     make(chan error, 1),
+	}
+
+	server := lsp.NewServer(serverReader, serverWriter, lsp.ServerOpts{},
+		ui.NewCustomWriterTTY(false, &bytes.Buffer{}, &bytes.Buffer{}))
+
+	go func() {
+		client.done <- server.Run()
+		serverWriter.Close()
+	}()
+
+	go func() {
+		reader := jsonframe.NewReader(clientReader)
+		for {
+			var msg map[string]json.RawMessage
+			if reader.Read(&msg) != nil {
+				close(client.messages)
+				return
+			}
+			client.messages <- msg
+		}
+	}()
+
+	return client
+}
+
+func (c *testClient) send(t *testing.T, msg map[string]interface{}) {
+	msg["jsonrpc"] = "2.0"
+	body, err := json.Marshal(msg)
+	require.NoError(t, err)
+	_, err = fmt.Fprintf(c.writer, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	require.NoError(t, err)
+}
+
+func (c *testClient) Notify(t *testing.T, method string, params interface{}) {
+	c.send(t, map[string]interface{}{"method": method, "params": params})
+}
+
+// Call sends request and waits for its response (skipping any notifications).
+func (c *testClient) Call(t *testing.T, method string, params interface{}, result interface{}) {
+	c.nextID++
+	c.send(t, map[string]interface{}{"id": c.nextID, "method": method, "params": params})
+
+	for {
+		msg := c.receive(t)
+		if _, isResponse := msg["id"]; !isResponse {
+			continue
+		}
+		require.Equal(t, strconv.Itoa(c.nextID), string(msg["id"]))
+		require.NotContains(t, msg, "error", "Expected successful response to '%s'", method)
+		if result != nil {
+			require.NoError(t, json.Unmarshal(msg["result"], result))
+		}
+		return
+	}
+}
+
+func (c *testClient) ChangeDocument(t *testing.T, uri, text string) {
+	c.Notify(t, "textDocument/didChange", lsp.DidChangeTextDocumentParams{
+		TextDocument:   lsp.TextDocumentIdentifier{URI: uri},
+		ContentChanges: []lsp.TextDocumentContentChangeEvent{{Text: text}},
+	})
+}
+
+// Diagnostics waits for diagnostics published for given document.
+func (c *testClient) Diagnostics(t *testing.T, uri string) []lsp.Diagnostic {
+	for {
+		msg := c.receive(t)
+		if string(msg["method"]) != `"textDocument/publishDiagnostics"` {
+			continue
+		}
+		var params lsp.PublishDiagnosticsParams
+		require.NoError(t, json.Unmarshal(msg["params"], &params))
+		if params.URI == uri {
+			return params.Diagnostics
+		}
+	}
+}
+
+func (c *testClient) receive(t *testing.T) map[string]json.RawMessage {
+	select {
+	case msg, ok := <-c.messages:
+		require.True(t, ok, "Expected server to keep connection open")
+		return msg
+	case <-time.After(30 * time.Second):
+		require.FailNow(t, "Timed out waiting for server message")
+		return nil
+	}
+}
+
+func (c *testClient) Wait(t *testing.T) error {
+	select {
+	case err := <-c.done:
+		return err
+	case <-time.After(30 * time.Second):
+		require.FailNow(t, "Timed out waiting for server to exit")
+		return nil
+	}
+}
+
+// positionParams points to the beginning of the last occurrence of substr within given line.
+func positionParams(uri, text string, line int, substr string) lsp.TextDocumentPositionParams {
+	lineText := strings.Split(text, "\n")[line]
+	idx := strings.LastIndex(lineText, substr)
+	if idx < 0 {
+		panic(fmt.Sprintf("Expected line %d to contain '%s'", line, substr))
+	}
+	return lsp.TextDocumentPositionParams{
+		TextDocument: lsp.TextDocumentIdentifier{URI: uri},
+		Position:     lsp.Position{Line: line, Character: idx},
+	}
+}
+
+func completionLabels(list lsp.CompletionList) []string {
+	var labels []string
+	for _, item := range list.Items {
+		labels = append(labels, item.Label)
+	}
+	return labels
+}
+
+func fileURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+func writeFile(t *testing.T, dir, path, content string) {
+	fullPath := filepath.Join(dir, path)
+	require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0700))
+	require.NoError(t, ioutil.WriteFile(fullPath, []byte(content), 0600))
+}
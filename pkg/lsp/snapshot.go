@@ -0,0 +1,251 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package lsp
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	cmdtpl "github.com/vmware-tanzu/carvel-ytt/pkg/cmd/template"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/schema"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/template"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace"
+)
+
+const diagnosticSource = "ytt"
+
+var (
+	// e.g. "config.yml:3", "Unmarshaling YAML template 'config.yml': yaml: line 3"
+	errPositionRegexp     = regexp.MustCompile(`([^\s':]+):(\d+)`)
+	errYAMLPositionRegexp = regexp.MustCompile(`'([^']+)': yaml: line (\d+)`)
+)
+
+// snapshot is a state of workspace files (as found on disk, overridden by open documents).
+type snapshot struct {
+	rootDir     string
+	docs        map[string]string // by URI
+	files       []*files.File
+	rootLibrary *workspace.Library
+
+	opts ServerOpts
+	ui   ui.UI
+}
+
+func newSnapshot(rootDir string, docs map[string]string, opts ServerOpts, ui ui.UI) (*snapshot, error) {
+	snap := &snapshot{rootDir: rootDir, docs: docs, opts: opts, ui: ui}
+
+	diskFiles, err := files.NewSortedFilesFromPaths([]string{rootDir}, files.SymlinkAllowOpts{})
+	if err != nil {
+		return nil, err
+	}
+
+	filesByPath := map[string]int{}
+	for i, file := range diskFiles {
+		filesByPath[file.RelativePath()] = i
+	}
+
+	for _, uri := range snap.sortedDocURIs() {
+		relPath, ok := snap.RelativePath(uri)
+		if !ok {
+			continue
+		}
+		file, err := files.NewFileFromSource(files.NewBytesSource(relPath, []byte(docs[uri])))
+		if err != nil {
+			return nil, err
+		}
+		if i, found := filesByPath[relPath]; found {
+			diskFiles[i] = file
+		} else {
+			diskFiles = append(diskFiles, file)
+		}
+	}
+
+	snap.files = files.NewSortedFiles(diskFiles)
+	snap.rootLibrary = workspace.NewRootLibrary(snap.files)
+	return snap, nil
+}
+
+func (s *snapshot) sortedDocURIs() []string {
+	var uris []string
+	for uri := range s.docs {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	return uris
+}
+
+// RelativePath returns path of a document relative to workspace root (if it's within the root).
+func (s *snapshot) RelativePath(uri string) (string, bool) {
+	path, ok := uriToPath(uri)
+	if !ok {
+		return "", false
+	}
+	relPath, err := filepath.Rel(s.rootDir, path)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return "", false
+	}
+	return filepath.ToSlash(relPath), true
+}
+
+func (s *snapshot) URI(relPath string) string {
+	return pathToURI(filepath.Join(s.rootDir, filepath.FromSlash(relPath)))
+}
+
+// Text returns contents of a workspace file (preferring contents of an open document).
+func (s *snapshot) Text(relPath string) (string, error) {
+	if text, found := s.docs[s.URI(relPath)]; found {
+		return text, nil
+	}
+	fileInLib, _, err := s.rootLibrary.FindFileInAnyLibrary(relPath)
+	if err != nil {
+		return "", err
+	}
+	bs, err := fileInLib.File.Bytes()
+	if err != nil {
+		return "", err
+	}
+	return string(bs), nil
+}
+
+// FindLoadableFile resolves load(...) module path the same way as templates are evaluated.
+func (s *snapshot) FindLoadableFile(uri, module string) (string, error) {
+	libraryCtx, err := s.libraryContext(uri)
+	if err != nil {
+		return "", err
+	}
+	fileInLib, _, err := libraryCtx.FindLoadableFile(module)
+	if err != nil {
+		return "", err
+	}
+	return fileInLib.File.RelativePath(), nil
+}
+
+// Schema returns data values schema of the library that contains given document.
+func (s *snapshot) Schema(uri string) (*schema.DocumentType, error) {
+	libraryCtx, err := s.libraryContext(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	libraryExecutionFactory := workspace.NewLibraryExecutionFactory(
+		s.ui, workspace.TemplateLoaderOpts{StrictYAML: s.opts.StrictYAML}, true)
+
+	libraryExecution := libraryExecutionFactory.New(
+		workspace.LibraryExecutionContext{Current: libraryCtx.Root, Root: libraryCtx.Root})
+
+	dataValuesSchema, _, err := libraryExecution.Schemas(nil)
+	if err != nil {
+		return nil, err
+	}
+	return dataValuesSchema.GetDocumentType(), nil
+}
+
+func (s *snapshot) libraryContext(uri string) (workspace.LibraryExecutionContext, error) {
+	relPath, ok := s.RelativePath(uri)
+	if !ok {
+		return workspace.LibraryExecutionContext{}, fmt.Errorf("Expected document '%s' to be within workspace '%s'", uri, s.rootDir)
+	}
+	_, libraryCtx, err := s.rootLibrary.FindFileInAnyLibrary(relPath)
+	return libraryCtx, err
+}
+
+// Diagnostics templates workspace and returns resulting errors by document URI.
+func (s *snapshot) Diagnostics() map[string][]Diagnostic {
+	opts := cmdtpl.NewOptions()
+	opts.StrictYAML = s.opts.StrictYAML
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: s.files}, s.ui)
+	if out.Err == nil {
+		return map[string][]Diagnostic{}
+	}
+	return s.errorDiagnostics(out.Err)
+}
+
+func (s *snapshot) errorDiagnostics(err error) map[string][]Diagnostic {
+	result := map[string][]Diagnostic{}
+	add := func(relPath string, lineNum int, msg string) {
+		if !s.isKnownFile(relPath) || lineNum < 1 {
+			return
+		}
+		uri := s.URI(relPath)
+		result[uri] = append(result[uri], newLineDiagnostic(lineNum, msg))
+	}
+
+	if multiErr, ok := err.(template.CompiledTemplateMultiError); ok {
+		for _, tplErr := range multiErr.Errors() {
+			for _, pos := range tplErr.Positions {
+				if lineNum, ok := errorPositionLineNum(pos); ok {
+					add(pos.Filename, lineNum, tplErr.Msg)
+				}
+			}
+		}
+	} else {
+		// Other errors only mention positions within their messages
+		msg := strings.TrimSpace(err.Error())
+		for _, posRegexp := range []*regexp.Regexp{errYAMLPositionRegexp, errPositionRegexp} {
+			for _, match := range posRegexp.FindAllStringSubmatch(msg, -1) {
+				lineNum, _ := strconv.Atoi(match[2])
+				add(match[1], lineNum, msg)
+			}
+		}
+	}
+
+	if len(result) == 0 {
+		// Attach errors without known position to all open documents
+		for uri := range s.docs {
+			result[uri] = []Diagnostic{newLineDiagnostic(1, strings.TrimSpace(err.Error()))}
+		}
+	}
+	return result
+}
+
+func (s *snapshot) isKnownFile(relPath string) bool {
+	for _, file := range s.files {
+		if file.RelativePath() == relPath {
+			return true
+		}
+	}
+	return false
+}
+
+func errorPositionLineNum(pos template.CompiledTemplateErrorPosition) (int, bool) {
+	for _, line := range []*template.Line{pos.TemplateLine, pos.BeforeTemplateLine} {
+		if line != nil && line.SourceLine != nil && line.SourceLine.Position.IsKnown() {
+			return line.SourceLine.Position.LineNum(), true
+		}
+	}
+	return 0, false
+}
+
+// newLineDiagnostic marks whole line (lineNum is 1-based).
+func newLineDiagnostic(lineNum int, msg string) Diagnostic {
+	return Diagnostic{
+		Range: Range{
+			Start: Position{Line: lineNum - 1},
+			End:   Position{Line: lineNum},
+		},
+		Severity: SeverityError,
+		Source:   diagnosticSource,
+		Message:  msg,
+	}
+}
+
+func uriToPath(uri string) (string, bool) {
+	parsedURI, err := url.Parse(uri)
+	if err != nil || parsedURI.Scheme != "file" {
+		return "", false
+	}
+	return filepath.FromSlash(parsedURI.Path), true
+}
+
+func pathToURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
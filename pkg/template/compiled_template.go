@@ -6,6 +6,7 @@ package template
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"unicode"
 
 	"github.com/k14s/starlark-go/resolve"
@@ -15,6 +16,8 @@ import (
 	tplcore "github.com/vmware-tanzu/carvel-ytt/pkg/template/core"
 )
 
+var resolveOptionsOnce sync.Once
+
 type EvaluationCtxDialectName string
 type EvaluationCtxDialects map[EvaluationCtxDialectName]EvaluationCtxDialect
 
@@ -33,13 +36,16 @@ func NewCompiledTemplate(name string, code []Line,
 	evalDialects EvaluationCtxDialects) *CompiledTemplate {
 
 	// TODO package globals
-	resolve.AllowFloat = true
-	resolve.AllowSet = true
-	resolve.AllowLambda = true
-	resolve.AllowNestedDef = true
-	resolve.AllowBitwise = true
-	resolve.AllowRecursion = true
-	resolve.AllowGlobalReassign = true
+	// (set once since templates may be compiled concurrently)
+	resolveOptionsOnce.Do(func() {
+		resolve.AllowFloat = true
+		resolve.AllowSet = true
+		resolve.AllowLambda = true
+		resolve.AllowNestedDef = true
+		resolve.AllowBitwise = true
+		resolve.AllowRecursion = true
+		resolve.AllowGlobalReassign = true
+	})
 
 	return &CompiledTemplate{
 		name:         name,
@@ -119,7 +125,7 @@ func (e *CompiledTemplate) Eval(thread *starlark.Thread, loader CompiledTemplate
 		}
 	}
 
-	updatedGlobals, val, err := e.eval(thread, globals)
+	updatedGlobals, val, err := e.eval(thread, loader, globals)
 	if err != nil {
 		return nil, nil, NewCompiledTemplateMultiError(err, loader)
 	}
@@ -131,8 +137,8 @@ func (e *CompiledTemplate) Eval(thread *starlark.Thread, loader CompiledTemplate
 	return updatedGlobals, val, nil
 }
 
-func (e *CompiledTemplate) eval(
-	thread *starlark.Thread, globals starlark.StringDict) (
+func (e *CompiledTemplate) eval(thread *starlark.Thread,
+	loader CompiledTemplateLoader, globals starlark.StringDict) (
 	gs starlark.StringDict, resultVal interface{}, resultErr error) {
 
 	// Catch any panics to give a better contextual information
@@ -151,7 +157,30 @@ func (e *CompiledTemplate) eval(
 		return nil, nil, err
 	}
 
-	NewProgramAST(f, e.instructions).InsertTplCtxs()
+	programAST := NewProgramAST(f, e.instructions)
+	programAST.InsertTplCtxs()
+
+	// Inserted before other instructions so that only template statements are stopped at
+	if GetDebugger(thread) != nil {
+		programAST.InsertDebugStops(e.debugSourceLineNum)
+		globals[e.instructions.DebugStop.Name] = starlark.NewBuiltin(
+			e.instructions.DebugStop.Name, tplcore.ErrWrapper(e.tplDebugStop(loader)))
+	}
+
+	if GetExecutionLimiter(thread) != nil {
+		programAST.InsertLimitChecks()
+		// Builtin's name is shown in errors
+		globals[e.instructions.CheckLimits.Name] = starlark.NewBuiltin(
+			"execution_limits", tplcore.ErrWrapper(e.tplCheckLimits))
+	}
+
+	if GetProfiler(thread) != nil {
+		programAST.InsertFunctionProfiling()
+		globals[e.instructions.EnterFunction.Name] = starlark.NewBuiltin(
+			e.instructions.EnterFunction.Name, tplcore.ErrWrapper(e.tplEnterFunction))
+		globals[e.instructions.ExitFunction.Name] = starlark.NewBuiltin(
+			e.instructions.ExitFunction.Name, tplcore.ErrWrapper(e.tplExitFunction))
+	}
 
 	prog, err := starlark.FileProgram(f, globals.Has)
 	if err != nil {
@@ -294,3 +323,74 @@ func (e *CompiledTemplate) TplReplaceNode(
 
 	return e.ctxs[len(e.ctxs)-1].TplReplace(thread, f, args, kwargs)
 }
+
+// tplCheckLimits returns its (optional) argument so that it can wrap expressions.
+func (e *CompiledTemplate) tplCheckLimits(
+	thread *starlark.Thread, f *starlark.Builtin,
+	args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+
+	if limiter := GetExecutionLimiter(thread); limiter != nil {
+		if err := limiter.Check(); err != nil {
+			return nil, err
+		}
+	}
+	if args.Len() > 0 {
+		return args.Index(0), nil
+	}
+	return starlark.None, nil
+}
+
+func (e *CompiledTemplate) tplEnterFunction(
+	thread *starlark.Thread, f *starlark.Builtin,
+	args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+
+	if profiler := GetProfiler(thread); profiler != nil {
+		// Skip frame of this builtin to get to the profiled function
+		frame := thread.CallFrame(1)
+
+		lineNum := int(frame.Pos.Line)
+		if line := e.CodeAtLine(filepos.NewPosition(lineNum)); line != nil && line.SourceLine != nil {
+			lineNum = line.SourceLine.Position.LineNum()
+		}
+
+		name := fmt.Sprintf("%s (%s:%d)", frame.Name, e.name, lineNum)
+		profiler.enterFunction(thread, name, thread.CallStackDepth()-1)
+	}
+	return starlark.None, nil
+}
+
+// tplExitFunction returns its (optional) argument so that it can wrap returned values.
+func (e *CompiledTemplate) tplExitFunction(
+	thread *starlark.Thread, f *starlark.Builtin,
+	args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+
+	if profiler := GetProfiler(thread); profiler != nil {
+		profiler.exitFunction(thread, thread.CallStackDepth()-1)
+	}
+	if args.Len() > 0 {
+		return args.Index(0), nil
+	}
+	return starlark.None, nil
+}
+
+// tplDebugStop receives visible variables as keyword arguments
+// (loader is used to map frames of functions defined in other templates).
+func (e *CompiledTemplate) tplDebugStop(loader CompiledTemplateLoader) tplcore.StarlarkFunc {
+	return func(thread *starlark.Thread, f *starlark.Builtin,
+		args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+
+		if debugger := GetDebugger(thread); debugger != nil {
+			stop := DebugStop{Frames: e.debugFrames(thread, loader), Node: e.debugNode()}
+			for _, kwarg := range kwargs {
+				name, _ := starlark.AsString(kwarg[0])
+				stop.Locals = append(stop.Locals, DebugVariable{Name: name, Value: kwarg[1]})
+			}
+
+			err := debugger.BeforeStatement(thread, stop)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return starlark.None, nil
+	}
+}
@@ -63,6 +63,9 @@ func NewCompiledTemplateMultiError(err error, loader CompiledTemplateLoader) err
 	return e
 }
 
+// Errors returns individual errors (each with positions within templates, innermost first).
+func (e CompiledTemplateMultiError) Errors() []CompiledTemplateError { return e.errs }
+
 func (e CompiledTemplateMultiError) Error() string {
 	result := []string{""}
 
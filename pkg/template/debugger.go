@@ -0,0 +1,100 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package template
+
+import (
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/filepos"
+)
+
+const (
+	threadDebuggerKey = "ytt.debugger"
+)
+
+// Debugger is notified before statements of templates are evaluated
+// (at most once per source line within a block) and may pause evaluation
+// by not returning until it is ready to continue.
+//
+// Returned error aborts evaluation of a template.
+type Debugger interface {
+	BeforeStatement(thread *starlark.Thread, stop DebugStop) error
+}
+
+// DebugStop describes evaluation state right before a statement is evaluated.
+type DebugStop struct {
+	// Frames of thread's call stack, innermost first
+	Frames []DebugFrame
+	// Locals are variables definitely assigned at this point of innermost frame (in order of assignment)
+	Locals []DebugVariable
+	// Node is a template node currently being built (nil if statement is not within a node)
+	Node EvaluationNode
+}
+
+// DebugFrame is a single call frame positioned at a template source line (when known).
+type DebugFrame struct {
+	Name string
+	File string
+	Line int
+}
+
+// DebugVariable is a named value visible to a statement.
+type DebugVariable struct {
+	Name  string
+	Value starlark.Value
+}
+
+// SetDebugger configures thread to notify given debugger before evaluating statements.
+func SetDebugger(thread *starlark.Thread, debugger Debugger) {
+	thread.SetLocal(threadDebuggerKey, debugger)
+}
+
+// GetDebugger returns debugger of given thread (nil if thread is not debugged).
+func GetDebugger(thread *starlark.Thread) Debugger {
+	debugger, _ := thread.Local(threadDebuggerKey).(Debugger)
+	return debugger
+}
+
+// debugFrames maps thread's call stack (excluding builtins) to template source lines.
+func (e *CompiledTemplate) debugFrames(thread *starlark.Thread, loader CompiledTemplateLoader) []DebugFrame {
+	var frames []DebugFrame
+
+	stack := thread.CallStack()
+	for i := range stack {
+		frame := stack.At(i)
+		if frame.Pos.Line == 0 {
+			continue // builtins do not have a position
+		}
+
+		lineNum := int(frame.Pos.Line)
+
+		ct := e
+		if frame.Pos.Filename() != e.name {
+			ct, _ = loader.FindCompiledTemplate(frame.Pos.Filename())
+		}
+		if ct != nil {
+			if line := ct.CodeAtLine(filepos.NewPosition(lineNum)); line != nil && line.SourceLine != nil {
+				lineNum = line.SourceLine.Position.LineNum()
+			}
+		}
+
+		frames = append(frames, DebugFrame{Name: frame.Name, File: frame.Pos.Filename(), Line: lineNum})
+	}
+	return frames
+}
+
+// debugSourceLineNum returns template source line of a code line (0 if code line was generated).
+func (e *CompiledTemplate) debugSourceLineNum(codeLineNum int) int {
+	if line := e.CodeAtLine(filepos.NewPosition(codeLineNum)); line != nil && line.SourceLine != nil {
+		return line.SourceLine.Position.LineNum()
+	}
+	return 0
+}
+
+// debugNode returns node that is currently being built (if any).
+func (e *CompiledTemplate) debugNode() EvaluationNode {
+	if len(e.ctxs) == 0 {
+		return nil
+	}
+	return e.ctxs[len(e.ctxs)-1].currentNode()
+}
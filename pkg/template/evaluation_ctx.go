@@ -252,6 +252,14 @@ func (e *EvaluationCtx) startNode(nodeTag NodeTag) error {
 
 func (e *EvaluationCtx) RootNode() interface{} { return e.rootNode }
 
+// currentNode returns innermost node that is being built (root node if none were started).
+func (e *EvaluationCtx) currentNode() EvaluationNode {
+	if len(e.parentNodes) > 0 {
+		return e.parentNodes[len(e.parentNodes)-1]
+	}
+	return e.rootNode
+}
+
 func (e *EvaluationCtx) RootNodeAsStarlarkValue() starlark.Value {
 	val := e.dialect.WrapRootValue(e.rootNode)
 	if typedVal, ok := val.(starlark.Value); ok {
@@ -0,0 +1,109 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package template
+
+import (
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/k14s/starlark-go/starlark"
+)
+
+const (
+	threadExecutionLimiterKey = "ytt.execution_limiter"
+
+	// reading memory stats briefly stops the world, hence it's not done on every step
+	memoryCheckInterval = 10 * time.Millisecond
+)
+
+// ExecutionLimits bound evaluation of templates (zero value of a limit means no limit).
+type ExecutionLimits struct {
+	// MaxSteps limits total number of loop iterations and function calls
+	MaxSteps uint64
+	// Timeout limits wall-clock time of evaluation
+	Timeout time.Duration
+	// MaxMemory limits (in bytes) growth of heap during evaluation
+	MaxMemory uint64
+}
+
+// IsZero indicates that there are no limits.
+func (l ExecutionLimits) IsZero() bool {
+	return l == ExecutionLimits{}
+}
+
+// ExecutionLimiter enforces ExecutionLimits across all threads that participate in a single evaluation.
+//
+// Limits are checked at the beginning of every loop iteration and function call
+// (checks are inserted into the program when its thread has a limiter),
+// hence long running builtin operations (e.g. on huge strings) are not interrupted.
+type ExecutionLimiter struct {
+	limits    ExecutionLimits
+	startTime time.Time
+	startHeap uint64
+
+	steps          uint64 // accessed atomically
+	lastMemoryTime int64  // accessed atomically (unix nanoseconds)
+}
+
+// NewExecutionLimiter starts measuring evaluation (i.e. timeout and memory are measured from now).
+func NewExecutionLimiter(limits ExecutionLimits) *ExecutionLimiter {
+	limiter := &ExecutionLimiter{limits: limits, startTime: time.Now()}
+	if limits.MaxMemory > 0 {
+		limiter.startHeap = heapAlloc()
+		limiter.lastMemoryTime = limiter.startTime.UnixNano()
+	}
+	return limiter
+}
+
+// SetExecutionLimiter configures thread to enforce limits of given limiter.
+func SetExecutionLimiter(thread *starlark.Thread, limiter *ExecutionLimiter) {
+	thread.SetLocal(threadExecutionLimiterKey, limiter)
+}
+
+// GetExecutionLimiter returns limiter of given thread (nil if thread is not limited).
+func GetExecutionLimiter(thread *starlark.Thread) *ExecutionLimiter {
+	limiter, _ := thread.Local(threadExecutionLimiterKey).(*ExecutionLimiter)
+	return limiter
+}
+
+// Check counts a single step and returns an error if any of the limits was exceeded.
+func (l *ExecutionLimiter) Check() error {
+	steps := atomic.AddUint64(&l.steps, 1)
+	if l.limits.MaxSteps > 0 && steps > l.limits.MaxSteps {
+		return fmt.Errorf("Expected evaluation to take at most %d steps (loop iterations and function calls), "+
+			"but it took more", l.limits.MaxSteps)
+	}
+
+	if l.limits.Timeout == 0 && l.limits.MaxMemory == 0 {
+		return nil
+	}
+
+	now := time.Now()
+
+	if l.limits.Timeout > 0 && now.Sub(l.startTime) > l.limits.Timeout {
+		return fmt.Errorf("Expected evaluation to complete within %s, but it took longer", l.limits.Timeout)
+	}
+
+	if l.limits.MaxMemory > 0 {
+		lastMemoryTime := atomic.LoadInt64(&l.lastMemoryTime)
+		if now.UnixNano()-lastMemoryTime >= int64(memoryCheckInterval) &&
+			atomic.CompareAndSwapInt64(&l.lastMemoryTime, lastMemoryTime, now.UnixNano()) {
+
+			if heap := heapAlloc(); heap > l.startHeap && heap-l.startHeap > l.limits.MaxMemory {
+				return fmt.Errorf("Expected evaluation to use at most %d bytes of memory, but it used %d bytes",
+					l.limits.MaxMemory, heap-l.startHeap)
+			}
+		}
+	}
+
+	return nil
+}
+
+func heapAlloc() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
@@ -6,6 +6,7 @@ package template
 import (
 	"fmt"
 	"strings"
+	"sync/atomic"
 )
 
 type InstructionSet struct {
@@ -18,15 +19,21 @@ type InstructionSet struct {
 	SetNode               InstructionOp
 	SetMapItemKey         InstructionOp
 	ReplaceNode           InstructionOp
+	CheckLimits           InstructionOp
+	EnterFunction         InstructionOp
+	ExitFunction          InstructionOp
+	DebugStop             InstructionOp
+
+	// namePrefix is shared by names of all instructions in this set
+	namePrefix string
 }
 
 var (
-	globalInsSetID = 1
+	globalInsSetID int64 = 1 // accessed atomically
 )
 
 func NewInstructionSet() *InstructionSet {
-	globalInsSetID++
-	uniqueID := globalInsSetID
+	uniqueID := atomic.AddInt64(&globalInsSetID, 1)
 	return &InstructionSet{
 		SetCtxType:            InstructionOp{fmt.Sprintf("__ytt_tpl%d_set_ctx_type", uniqueID)},
 		StartCtx:              InstructionOp{fmt.Sprintf("__ytt_tpl%d_start_ctx", uniqueID)},
@@ -37,6 +44,11 @@ func NewInstructionSet() *InstructionSet {
 		SetNode:               InstructionOp{fmt.Sprintf("__ytt_tpl%d_set_node", uniqueID)},
 		SetMapItemKey:         InstructionOp{fmt.Sprintf("__ytt_tpl%d_set_map_item_key", uniqueID)},
 		ReplaceNode:           InstructionOp{fmt.Sprintf("__ytt_tpl%d_replace_node", uniqueID)},
+		CheckLimits:           InstructionOp{fmt.Sprintf("__ytt_tpl%d_check_limits", uniqueID)},
+		EnterFunction:         InstructionOp{fmt.Sprintf("__ytt_tpl%d_enter_function", uniqueID)},
+		ExitFunction:          InstructionOp{fmt.Sprintf("__ytt_tpl%d_exit_function", uniqueID)},
+		DebugStop:             InstructionOp{fmt.Sprintf("__ytt_tpl%d_debug_stop", uniqueID)},
+		namePrefix:            fmt.Sprintf("__ytt_tpl%d_", uniqueID),
 	}
 }
 
@@ -0,0 +1,166 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package template
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/k14s/starlark-go/starlark"
+)
+
+const (
+	threadProfilerKey      = "ytt.profiler"
+	threadProfilerCallsKey = "ytt.profiler_calls"
+)
+
+// ProfileCategory groups measurements of similar operations.
+type ProfileCategory string
+
+const (
+	ProfileCategoryTemplate   ProfileCategory = "template"
+	ProfileCategoryFunction   ProfileCategory = "function"
+	ProfileCategoryOverlay    ProfileCategory = "overlay"
+	ProfileCategoryDataValues ProfileCategory = "data-values"
+)
+
+// ProfileEntry is a cumulative measurement of a single operation (e.g. all calls of a function).
+type ProfileEntry struct {
+	Category ProfileCategory
+	Name     string
+	Calls    int
+	// Total includes time spent in nested operations (e.g. loaded templates, called functions)
+	Total time.Duration
+}
+
+// Profiler collects time spent evaluating templates, calling functions,
+// applying overlays and pre-processing data values.
+//
+// Profiler is safe to be used from multiple threads.
+// Measurement methods are no-ops on a nil Profiler so that callers do not need to check whether profiling is enabled.
+type Profiler struct {
+	lock    sync.Mutex
+	entries map[profileKey]*ProfileEntry
+}
+
+type profileKey struct {
+	category ProfileCategory
+	name     string
+}
+
+// profiledCall is an in-progress function call (tracked per thread).
+type profiledCall struct {
+	key       profileKey
+	depth     int
+	startTime time.Time
+	recursive bool
+}
+
+// NewProfiler returns an empty profiler.
+func NewProfiler() *Profiler {
+	return &Profiler{entries: map[profileKey]*ProfileEntry{}}
+}
+
+// SetProfiler configures thread to report function calls to given profiler.
+func SetProfiler(thread *starlark.Thread, profiler *Profiler) {
+	thread.SetLocal(threadProfilerKey, profiler)
+}
+
+// GetProfiler returns profiler of given thread (nil if thread is not profiled).
+func GetProfiler(thread *starlark.Thread) *Profiler {
+	profiler, _ := thread.Local(threadProfilerKey).(*Profiler)
+	return profiler
+}
+
+// Measure starts measuring a single call of an operation; returned function ends the measurement.
+func (p *Profiler) Measure(category ProfileCategory, name string) func() {
+	if p == nil {
+		return func() {}
+	}
+	startTime := time.Now()
+	return func() { p.add(profileKey{category, name}, 1, time.Since(startTime)) }
+}
+
+// Entries returns all measurements sorted by total time (longest first).
+func (p *Profiler) Entries() []ProfileEntry {
+	if p == nil {
+		return nil
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var result []ProfileEntry
+	for _, entry := range p.entries {
+		result = append(result, *entry)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Total != result[j].Total {
+			return result[i].Total > result[j].Total
+		}
+		if result[i].Category != result[j].Category {
+			return result[i].Category < result[j].Category
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func (p *Profiler) add(key profileKey, calls int, duration time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	entry, found := p.entries[key]
+	if !found {
+		entry = &ProfileEntry{Category: key.category, Name: key.name}
+		p.entries[key] = entry
+	}
+	entry.Calls += calls
+	entry.Total += duration
+}
+
+// enterFunction starts measuring a function call made at given stack depth.
+func (p *Profiler) enterFunction(thread *starlark.Thread, name string, depth int) {
+	calls := p.unwindCalls(thread, depth)
+
+	call := profiledCall{key: profileKey{ProfileCategoryFunction, name}, depth: depth, startTime: time.Now()}
+	for _, outerCall := range calls {
+		if outerCall.key == call.key {
+			call.recursive = true
+			break
+		}
+	}
+
+	thread.SetLocal(threadProfilerCallsKey, append(calls, call))
+
+	// Count call right away since calls that fail never exit
+	p.add(call.key, 1, 0)
+}
+
+// exitFunction ends measuring a function call made at given stack depth.
+func (p *Profiler) exitFunction(thread *starlark.Thread, depth int) {
+	calls := p.unwindCalls(thread, depth+1)
+	if len(calls) == 0 || calls[len(calls)-1].depth != depth {
+		return
+	}
+
+	call := calls[len(calls)-1]
+	thread.SetLocal(threadProfilerCallsKey, calls[:len(calls)-1])
+
+	// Time of recursive calls is already included in the outermost call
+	if !call.recursive {
+		p.add(call.key, 0, time.Since(call.startTime))
+	}
+}
+
+// unwindCalls drops calls made at given stack depth or deeper
+// (they were interrupted by an error, e.g. caught by assert.try_to).
+func (p *Profiler) unwindCalls(thread *starlark.Thread, depth int) []profiledCall {
+	calls, _ := thread.Local(threadProfilerCallsKey).([]profiledCall)
+	for len(calls) > 0 && calls[len(calls)-1].depth >= depth {
+		calls = calls[:len(calls)-1]
+	}
+	return calls
+}
@@ -5,6 +5,7 @@ package template
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/k14s/starlark-go/syntax"
 )
@@ -231,3 +232,244 @@ func (r *ProgramAST) addTplCtxToFunction(function *syntax.DefStmt) {
 
 	function.Body = append(append([]syntax.Stmt{startStmt}, function.Body...), endStmt)
 }
+
+// InsertLimitChecks inserts calls that check execution limits (see ExecutionLimiter)
+// at the beginning of every loop iteration and function call (including comprehensions and lambdas).
+func (r *ProgramAST) InsertLimitChecks() {
+	syntax.Walk(r.f, r.insertLimitChecks)
+}
+
+func (r *ProgramAST) insertLimitChecks(node syntax.Node) bool {
+	switch node := node.(type) {
+	case *syntax.ForStmt:
+		node.Body = append([]syntax.Stmt{r.limitCheckStmt(node.For)}, node.Body...)
+
+	case *syntax.WhileStmt:
+		node.Body = append([]syntax.Stmt{r.limitCheckStmt(node.While)}, node.Body...)
+		// syntax.Walk does not know about while statements
+		syntax.Walk(node.Cond, r.insertLimitChecks)
+		for _, stmt := range node.Body {
+			syntax.Walk(stmt, r.insertLimitChecks)
+		}
+		return false
+
+	case *syntax.DefStmt:
+		node.Body = append([]syntax.Stmt{r.limitCheckStmt(node.Def)}, node.Body...)
+
+	case *syntax.LambdaExpr:
+		node.Body = r.limitCheckExpr(node.Lambda, node.Body)
+
+	case *syntax.Comprehension:
+		// Conditions and nested iterables are evaluated even for iterations that do not reach the body
+		for i, clause := range node.Clauses {
+			switch clause := clause.(type) {
+			case *syntax.IfClause:
+				clause.Cond = r.limitCheckExpr(clause.If, clause.Cond)
+			case *syntax.ForClause:
+				// Iterable of the first clause is evaluated once (in the enclosing scope)
+				if i > 0 {
+					clause.X = r.limitCheckExpr(clause.For, clause.X)
+				}
+			}
+		}
+		if entry, ok := node.Body.(*syntax.DictEntry); ok {
+			entry.Value = r.limitCheckExpr(node.Lbrack, entry.Value)
+		} else {
+			node.Body = r.limitCheckExpr(node.Lbrack, node.Body)
+		}
+	}
+	return true
+}
+
+func (r *ProgramAST) limitCheckStmt(pos syntax.Position) syntax.Stmt {
+	return &syntax.ExprStmt{X: r.limitCheckExpr(pos, nil)}
+}
+
+// limitCheckExpr wraps expression (if any) into a check call that returns expression's value.
+func (r *ProgramAST) limitCheckExpr(pos syntax.Position, expr syntax.Expr) syntax.Expr {
+	return r.instructionCallExpr(pos, r.instructions.CheckLimits, expr)
+}
+
+// InsertFunctionProfiling inserts calls that measure time spent
+// in every function defined via def (see Profiler).
+func (r *ProgramAST) InsertFunctionProfiling() {
+	syntax.Walk(r.f, r.insertFunctionProfiling)
+}
+
+func (r *ProgramAST) insertFunctionProfiling(node syntax.Node) bool {
+	switch node := node.(type) {
+	case *syntax.WhileStmt:
+		// syntax.Walk does not know about while statements
+		syntax.Walk(node.Cond, r.insertFunctionProfiling)
+		for _, stmt := range node.Body {
+			syntax.Walk(stmt, r.insertFunctionProfiling)
+		}
+		return false
+
+	case *syntax.DefStmt:
+		// Nested functions are profiled (and their returns wrapped) on their own
+		for _, stmt := range node.Body {
+			syntax.Walk(stmt, r.insertFunctionProfiling)
+		}
+
+		r.wrapReturnsInExitFunction(node.Body)
+
+		enterStmt := &syntax.ExprStmt{X: r.instructionCallExpr(node.Def, r.instructions.EnterFunction, nil)}
+		exitStmt := &syntax.ExprStmt{X: r.instructionCallExpr(node.Def, r.instructions.ExitFunction, nil)}
+
+		node.Body = append(append([]syntax.Stmt{enterStmt}, node.Body...), exitStmt)
+		return false
+	}
+	return true
+}
+
+// wrapReturnsInExitFunction ends measurement of a function right before it returns
+// (returned value is evaluated before measurement ends).
+func (r *ProgramAST) wrapReturnsInExitFunction(stmts []syntax.Stmt) {
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *syntax.ReturnStmt:
+			stmt.Result = r.instructionCallExpr(stmt.Return, r.instructions.ExitFunction, stmt.Result)
+		case *syntax.IfStmt:
+			r.wrapReturnsInExitFunction(stmt.True)
+			r.wrapReturnsInExitFunction(stmt.False)
+		case *syntax.ForStmt:
+			r.wrapReturnsInExitFunction(stmt.Body)
+		case *syntax.WhileStmt:
+			r.wrapReturnsInExitFunction(stmt.Body)
+		}
+	}
+}
+
+// instructionCallExpr calls an instruction (positioned at given position) with expression (if any) as its argument.
+func (r *ProgramAST) instructionCallExpr(pos syntax.Position, op InstructionOp, expr syntax.Expr) syntax.Expr {
+	var args []syntax.Expr
+	if expr != nil {
+		args = []syntax.Expr{expr}
+	}
+	return &syntax.CallExpr{
+		Fn:     &syntax.Ident{NamePos: pos, Name: op.Name},
+		Lparen: pos,
+		Args:   args,
+		Rparen: pos,
+	}
+}
+
+// InsertDebugStops inserts calls that notify debugger (see Debugger) before statements,
+// passing values of variables that are definitely assigned at that point.
+// Within a block only first statement of each source line is preceded by a call
+// so that statements generated for a single template line are stepped over at once.
+func (r *ProgramAST) InsertDebugStops(sourceLineNum func(codeLineNum int) int) {
+	r.f.Stmts = r.insertDebugStops(r.f.Stmts, nil, sourceLineNum)
+}
+
+func (r *ProgramAST) insertDebugStops(stmts []syntax.Stmt, names []string, sourceLineNum func(int) int) []syntax.Stmt {
+	var result []syntax.Stmt
+	var prevLineNum int
+
+	for _, stmt := range stmts {
+		start, _ := stmt.Span()
+
+		// Generated statements (e.g. ending of template fragments) do not correspond to any source line
+		if lineNum := sourceLineNum(int(start.Line)); lineNum > 0 && lineNum != prevLineNum {
+			result = append(result, r.debugStopStmt(start, names))
+			prevLineNum = lineNum
+		}
+		result = append(result, stmt)
+
+		switch stmt := stmt.(type) {
+		case *syntax.IfStmt:
+			stmt.True = r.insertDebugStops(stmt.True, names, sourceLineNum)
+			stmt.False = r.insertDebugStops(stmt.False, names, sourceLineNum)
+
+		case *syntax.ForStmt:
+			stmt.Body = r.insertDebugStops(stmt.Body, r.appendDebugNames(names, stmt.Vars), sourceLineNum)
+
+		case *syntax.WhileStmt:
+			stmt.Body = r.insertDebugStops(stmt.Body, names, sourceLineNum)
+
+		case *syntax.DefStmt:
+			// Function body only sees its own parameters (enclosing variables are not its locals)
+			var params []string
+			for _, param := range stmt.Params {
+				switch param := param.(type) {
+				case *syntax.BinaryExpr: // default value
+					params = r.appendDebugNames(params, param.X)
+				case *syntax.UnaryExpr: // *args or **kwargs (bare * does not have a name)
+					if param.X != nil {
+						params = r.appendDebugNames(params, param.X)
+					}
+				default:
+					params = r.appendDebugNames(params, param)
+				}
+			}
+			stmt.Body = r.insertDebugStops(stmt.Body, params, sourceLineNum)
+			names = r.appendDebugNames(names, stmt.Name)
+
+		case *syntax.AssignStmt:
+			// Augmented assignments (e.g. +=) only update already assigned variables
+			if stmt.Op == syntax.EQ {
+				names = r.appendDebugNames(names, stmt.LHS)
+			}
+
+		case *syntax.LoadStmt:
+			for _, to := range stmt.To {
+				names = r.appendDebugNames(names, to)
+			}
+		}
+	}
+	return result
+}
+
+// appendDebugNames returns a copy of names with variables assigned by given expression
+// (e.g. an identifier or a tuple of identifiers) added.
+func (r *ProgramAST) appendDebugNames(names []string, expr syntax.Expr) []string {
+	result := append([]string{}, names...)
+
+	var collect func(syntax.Expr)
+	collect = func(expr syntax.Expr) {
+		switch expr := expr.(type) {
+		case *syntax.Ident:
+			if strings.HasPrefix(expr.Name, r.instructions.namePrefix) {
+				return
+			}
+			for _, name := range result {
+				if name == expr.Name {
+					return
+				}
+			}
+			result = append(result, expr.Name)
+
+		case *syntax.TupleExpr:
+			for _, x := range expr.List {
+				collect(x)
+			}
+
+		case *syntax.ListExpr:
+			for _, x := range expr.List {
+				collect(x)
+			}
+
+		case *syntax.ParenExpr:
+			collect(expr.X)
+		}
+		// Assignments to indexes and attributes do not introduce variables
+	}
+
+	collect(expr)
+	return result
+}
+
+// debugStopStmt calls debug stop instruction with given variables as keyword arguments.
+func (r *ProgramAST) debugStopStmt(pos syntax.Position, names []string) syntax.Stmt {
+	call := r.instructionCallExpr(pos, r.instructions.DebugStop, nil).(*syntax.CallExpr)
+	for _, name := range names {
+		call.Args = append(call.Args, &syntax.BinaryExpr{
+			X:     &syntax.Ident{NamePos: pos, Name: name},
+			OpPos: pos,
+			Op:    syntax.EQ,
+			Y:     &syntax.Ident{NamePos: pos, Name: name},
+		})
+	}
+	return &syntax.ExprStmt{X: call}
+}
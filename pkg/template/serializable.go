@@ -0,0 +1,116 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vmware-tanzu/carvel-ytt/pkg/filepos"
+)
+
+// SerializableCompiledTemplate is a form of a CompiledTemplate that can be serialized via encoding/gob
+// (e.g. to cache compiled templates). Nodes are serialized via provided functions
+// since their concrete types are defined by template dialects.
+type SerializableCompiledTemplate struct {
+	Name              string
+	InstructionPrefix string
+	Code              []SerializableLine
+	NodesID           int
+	Nodes             []SerializableNode
+}
+
+type SerializableLine struct {
+	Code       string
+	SourceLine *SourceLine
+}
+
+type SerializableNode struct {
+	Tag         int
+	ParentTag   int
+	Node        interface{}
+	Annotations map[AnnotationName]*filepos.Position
+}
+
+// AsSerializable converts compiled template into its serializable form.
+func (e *CompiledTemplate) AsSerializable(serializeNode func(EvaluationNode) (interface{}, error)) (*SerializableCompiledTemplate, error) {
+	result := &SerializableCompiledTemplate{
+		Name:              e.name,
+		InstructionPrefix: e.instructions.namePrefix,
+	}
+
+	for _, line := range e.code {
+		result.Code = append(result.Code, SerializableLine{Code: line.Instruction.AsString(), SourceLine: line.SourceLine})
+	}
+
+	if e.nodes == nil {
+		return result, nil
+	}
+
+	result.NodesID = e.nodes.id
+
+	for tag, node := range e.nodes.tagToNode {
+		serializedNode, err := serializeNode(node)
+		if err != nil {
+			return nil, fmt.Errorf("Serializing %s: %s", tag, err)
+		}
+
+		var anns map[AnnotationName]*filepos.Position
+		for name, ann := range e.nodes.annotations[tag] {
+			if anns == nil {
+				anns = map[AnnotationName]*filepos.Position{}
+			}
+			anns[name] = ann.Position
+		}
+
+		result.Nodes = append(result.Nodes, SerializableNode{
+			Tag:         tag.id,
+			ParentTag:   e.nodes.childToParentTag[tag].id,
+			Node:        serializedNode,
+			Annotations: anns,
+		})
+	}
+
+	// Keep serialized form stable
+	sort.Slice(result.Nodes, func(i, j int) bool { return result.Nodes[i].Tag < result.Nodes[j].Tag })
+
+	return result, nil
+}
+
+// NewCompiledTemplateFromSerializable converts serializable form back into a compiled template.
+// Code is updated to refer to given instructions instead of ones that were used during compilation.
+func NewCompiledTemplateFromSerializable(s *SerializableCompiledTemplate, instructions *InstructionSet,
+	deserializeNode func(interface{}) (EvaluationNode, error), evalDialects EvaluationCtxDialects) (*CompiledTemplate, error) {
+
+	replacer := strings.NewReplacer(s.InstructionPrefix, instructions.namePrefix)
+
+	var code []Line
+	for _, line := range s.Code {
+		code = append(code, Line{
+			Instruction: instructions.NewCode(replacer.Replace(line.Code)),
+			SourceLine:  line.SourceLine,
+		})
+	}
+
+	nodes := NewNodes()
+	nodes.id = s.NodesID
+
+	for _, serializedNode := range s.Nodes {
+		node, err := deserializeNode(serializedNode.Node)
+		if err != nil {
+			return nil, fmt.Errorf("Deserializing node tag %d: %s", serializedNode.Tag, err)
+		}
+
+		tag := NodeTag{serializedNode.Tag}
+		nodes.tagToNode[tag] = node
+		nodes.childToParentTag[tag] = NodeTag{serializedNode.ParentTag}
+
+		for name, pos := range serializedNode.Annotations {
+			nodes.AddAnnotation(tag, Annotation{Name: name, Position: pos})
+		}
+	}
+
+	return NewCompiledTemplate(s.Name, code, instructions, nodes, evalDialects), nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package testrunner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Reserved file names within test case directories.
+const (
+	ExpectedOutputFile = "expected.yml"
+	ExpectedErrorFile  = "expected-error.txt"
+	DataValuesFile     = "data-values.yml"
+)
+
+// Case is a single test case directory.
+type Case struct {
+	Path string
+}
+
+// ExpectsError indicates that test case expects ytt to fail (i.e. has expected-error.txt).
+func (c Case) ExpectsError() bool {
+	_, err := os.Stat(filepath.Join(c.Path, ExpectedErrorFile))
+	return err == nil
+}
+
+// starlarkTestName names test function of a *_test.star file (or test file itself, when name is empty);
+// test files of test cases are named by their path within test case directory.
+func (c Case) starlarkTestName(path, name string) string {
+	if len(c.Path) > 0 {
+		path = filepath.Join(c.Path, path)
+	}
+	if len(name) == 0 {
+		return path
+	}
+	return path + ":" + name
+}
+
+// FindCases walks given paths and returns (sorted) directories that are test cases.
+// Subdirectories of test cases are not searched since they are inputs of that test case.
+func FindCases(paths []string) ([]Case, error) {
+	var result []Case
+
+	for _, path := range paths {
+		err := filepath.Walk(path, func(walkedPath string, fi os.FileInfo, err error) error {
+			if err != nil || !fi.IsDir() {
+				return err
+			}
+
+			expectedFiles := 0
+			for _, name := range []string{ExpectedOutputFile, ExpectedErrorFile} {
+				if _, err := os.Stat(filepath.Join(walkedPath, name)); err == nil {
+					expectedFiles++
+				}
+			}
+
+			switch expectedFiles {
+			case 0:
+				return nil
+			case 1:
+				result = append(result, Case{Path: walkedPath})
+				return filepath.SkipDir
+			default:
+				return fmt.Errorf("Expected test case '%s' to include only one of '%s' or '%s'",
+					walkedPath, ExpectedOutputFile, ExpectedErrorFile)
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Finding test cases in '%s': %s", path, err)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+
+	return result, nil
+}
@@ -0,0 +1,25 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+Package testrunner runs ytt against directories of test cases and compares
+results with expected (golden) files.
+
+A test case is a directory that contains one of:
+
+  - expected.yml: expected YAML output
+  - expected-error.txt: expected error message
+
+All other files within the directory (including subdirectories) are inputs,
+except for data-values.yml which, if present, is used as a plain data values
+file (i.e. like --data-values-file). Files shared by all test cases may be
+given via RunnerOpts.Files.
+
+Shared files and test case directories may also include Starlark unit tests:
+test_* functions defined in *_test.star files are called (with @ytt:assert
+available for assertions) and each of them is reported as a separate result
+(test files that fail to evaluate are reported as failed results). Tests within
+a test case directory see its inputs and data values. Such files are never
+evaluated when rendering templates.
+*/
+package testrunner
@@ -0,0 +1,323 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package testrunner
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	cmdtpl "github.com/vmware-tanzu/carvel-ytt/pkg/cmd/template"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/yttlibrary"
+)
+
+// RunnerOpts configures how test cases are run.
+type RunnerOpts struct {
+	// Files are inputs shared by all test cases (e.g. templates under test).
+	Files []string
+	// Update overwrites expected files with actual results instead of comparing them.
+	Update bool
+	// Filter, if non-empty, is a regexp selecting test cases by their path.
+	Filter string
+
+	StrictYAML       bool
+	SymlinkAllowOpts files.SymlinkAllowOpts
+}
+
+// Runner runs test cases in-process (via template.Options).
+type Runner struct {
+	opts RunnerOpts
+	ui   ui.UI
+}
+
+// Result holds outcome of a single test case or Starlark test.
+type Result struct {
+	// Name is test case path, or Starlark test file path and function name (e.g. 'helpers_test.star:test_name')
+	Name    string
+	Case    Case
+	Passed  bool
+	Updated bool
+	// Failure describes why test failed, e.g. how actual result differs from expected one (empty when passed).
+	Failure string
+}
+
+// NewRunner constructs a Runner.
+func NewRunner(opts RunnerOpts, ui ui.UI) *Runner {
+	return &Runner{opts, ui}
+}
+
+// Run finds test cases within paths and runs each of them, reporting progress to UI.
+// Returned error indicates that test cases could not be run (failed test cases are reported via results).
+func (r *Runner) Run(paths []string) ([]Result, error) {
+	var filter *regexp.Regexp
+	if len(r.opts.Filter) > 0 {
+		var err error
+		filter, err = regexp.Compile(r.opts.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("Expected filter '%s' to be a valid regexp: %s", r.opts.Filter, err)
+		}
+	}
+
+	cases, err := FindCases(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+
+	starlarkResults, err := r.RunStarlarkTests()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range cases {
+		caseStarlarkResults, err := r.RunCaseStarlarkTests(c)
+		if err != nil {
+			return nil, fmt.Errorf("Running Starlark tests of test case '%s': %s", c.Path, err)
+		}
+		starlarkResults = append(starlarkResults, caseStarlarkResults...)
+	}
+
+	for _, result := range starlarkResults {
+		if filter == nil || filter.MatchString(result.Name) {
+			results = append(results, r.report(result))
+		}
+	}
+
+	for _, c := range cases {
+		if filter != nil && !filter.MatchString(c.Path) {
+			continue
+		}
+
+		result, err := r.RunCase(c)
+		if err != nil {
+			return nil, fmt.Errorf("Running test case '%s': %s", c.Path, err)
+		}
+
+		results = append(results, r.report(result))
+	}
+
+	return results, nil
+}
+
+func (r *Runner) report(result Result) Result {
+	switch {
+	case result.Updated:
+		r.ui.Printf("updated: %s\n", result.Name)
+	case result.Passed:
+		r.ui.Printf("pass: %s\n", result.Name)
+	default:
+		r.ui.Printf("FAIL: %s\n%s\n", result.Name, result.Failure)
+	}
+	return result
+}
+
+// RunStarlarkTests calls test_* functions of *_test.star files found among shared files (i.e. RunnerOpts.Files).
+// Test files that fail to evaluate are reported as failed results (named after the file).
+func (r *Runner) RunStarlarkTests() ([]Result, error) {
+	inputFiles, err := files.NewSortedFilesFromPaths(r.opts.Files, r.opts.SymlinkAllowOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.runStarlarkTests(inputFiles, inputFiles, r.newOptions(), Case{}), nil
+}
+
+// RunCaseStarlarkTests calls test_* functions of *_test.star files found within test case directory
+// (shared files and test case's data values are available to them, as they are to its templates).
+func (r *Runner) RunCaseStarlarkTests(c Case) ([]Result, error) {
+	sharedFiles, caseFiles, err := r.sharedAndCaseFiles(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.runStarlarkTests(files.NewSortedFiles(append(sharedFiles, caseFiles...)), caseFiles, r.caseOptions(c), c), nil
+}
+
+// runStarlarkTests runs tests of testFiles only (other test files among inputFiles are run, but not reported).
+func (r *Runner) runStarlarkTests(inputFiles, testFiles []*files.File, opts *cmdtpl.Options, c Case) []Result {
+	var testPaths []string
+	for _, file := range testFiles {
+		if file.IsStarlarkTest() {
+			testPaths = append(testPaths, file.RelativePath())
+		}
+	}
+	if len(testPaths) == 0 {
+		return nil
+	}
+
+	opts.RunStarlarkTests = true
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: inputFiles}, r.ui)
+	if out.Err != nil {
+		// Tests cannot run at all (e.g. data values are invalid), hence every test file fails
+		var results []Result
+		for _, path := range testPaths {
+			results = append(results, Result{Name: c.starlarkTestName(path, ""), Case: c,
+				Failure: fmt.Sprintf("Running Starlark tests: %s", out.Err)})
+		}
+		return results
+	}
+
+	var results []Result
+	for _, testResult := range out.StarlarkTests {
+		if !containsString(testPaths, testResult.Path) {
+			continue
+		}
+		result := Result{Name: c.starlarkTestName(testResult.Path, testResult.Name), Case: c, Passed: testResult.Err == nil}
+		if testResult.Err != nil {
+			result.Failure = testResult.Err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// RunCase runs a single test case, comparing its result to (or, when updating, storing it in) expected file.
+func (r *Runner) RunCase(c Case) (Result, error) {
+	inputFiles, err := r.inputFiles(c)
+	if err != nil {
+		return Result{}, err
+	}
+
+	opts := r.caseOptions(c)
+
+	out := opts.RunWithFiles(cmdtpl.Input{Files: inputFiles}, r.ui)
+
+	actualFile := ExpectedOutputFile
+	var actual string
+
+	if out.Err != nil {
+		actualFile = ExpectedErrorFile
+		actual = normalizeError(out.Err.Error())
+	} else {
+		actualBytes, err := out.DocSet.AsBytes()
+		if err != nil {
+			return Result{}, fmt.Errorf("Marshaling output: %s", err)
+		}
+		actual = string(actualBytes)
+	}
+
+	expectedFile := ExpectedOutputFile
+	if c.ExpectsError() {
+		expectedFile = ExpectedErrorFile
+	}
+
+	expectedBytes, err := ioutil.ReadFile(filepath.Join(c.Path, expectedFile))
+	if err != nil {
+		return Result{}, err
+	}
+
+	expected := string(expectedBytes)
+	if expectedFile == ExpectedErrorFile {
+		expected = normalizeError(expected)
+	}
+
+	if actualFile == expectedFile && actual == expected {
+		return Result{Name: c.Path, Case: c, Passed: true}, nil
+	}
+
+	if r.opts.Update {
+		if actualFile != expectedFile {
+			err := os.Remove(filepath.Join(c.Path, expectedFile))
+			if err != nil {
+				return Result{}, err
+			}
+		}
+		err := ioutil.WriteFile(filepath.Join(c.Path, actualFile), []byte(actual), 0600)
+		if err != nil {
+			return Result{}, err
+		}
+		return Result{Name: c.Path, Case: c, Passed: true, Updated: true}, nil
+	}
+
+	var failure string
+
+	switch {
+	case actualFile == expectedFile:
+		failure = "Diff (- expected, + actual):\n" + yttlibrary.Diff(expected, actual)
+	case out.Err != nil:
+		failure = fmt.Sprintf("Expected output, but got error:\n%s", actual)
+	default:
+		failure = fmt.Sprintf("Expected error, but got output:\n%s", actual)
+	}
+
+	return Result{Name: c.Path, Case: c, Failure: failure}, nil
+}
+
+func (r *Runner) newOptions() *cmdtpl.Options {
+	opts := cmdtpl.NewOptions()
+	opts.StrictYAML = r.opts.StrictYAML
+	*opts.RegularFilesSourceOpts.SymlinkAllowOpts = r.opts.SymlinkAllowOpts
+	return opts
+}
+
+// caseOptions configures test case's data values (if any).
+func (r *Runner) caseOptions(c Case) *cmdtpl.Options {
+	opts := r.newOptions()
+
+	dataValuesPath := filepath.Join(c.Path, DataValuesFile)
+	if _, err := os.Stat(dataValuesPath); err == nil {
+		opts.DataValuesFlags.FromFiles = []string{dataValuesPath}
+	}
+	return opts
+}
+
+// inputFiles combines shared files with test case files (other than reserved ones).
+func (r *Runner) inputFiles(c Case) ([]*files.File, error) {
+	sharedFiles, caseFiles, err := r.sharedAndCaseFiles(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return files.NewSortedFiles(append(sharedFiles, caseFiles...)), nil
+}
+
+func (r *Runner) sharedAndCaseFiles(c Case) ([]*files.File, []*files.File, error) {
+	sharedFiles, err := files.NewSortedFilesFromPaths(r.opts.Files, r.opts.SymlinkAllowOpts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	allCaseFiles, err := files.NewSortedFilesFromPaths([]string{c.Path}, r.opts.SymlinkAllowOpts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var caseFiles []*files.File
+
+	for _, file := range allCaseFiles {
+		switch file.RelativePath() {
+		case ExpectedOutputFile, ExpectedErrorFile, DataValuesFile:
+			// not an input
+		default:
+			caseFiles = append(caseFiles, file)
+		}
+	}
+
+	return sharedFiles, caseFiles, nil
+}
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeError drops trailing whitespace so that expected error files are easy to edit.
+func normalizeError(msg string) string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimRight(msg, "\n"), "\n") {
+		lines = append(lines, strings.TrimRight(line, "\t "))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
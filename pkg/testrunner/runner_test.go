@@ -0,0 +1,199 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package testrunner_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/testrunner"
+)
+
+func TestRunner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ytt-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "config/tpl.yml", "#@ load(\"@ytt:data\", \"data\")\n---\nname: #@ data.values.name\n")
+	writeFile(t, dir, "config/values.yml", "#@data/values\n---\nname: default\n")
+	writeFile(t, dir, "config/helpers.star", `
+load("@ytt:data", "data")
+def greet(name=data.values.name):
+  return {"greeting": "hello " + name, "names": [name]}
+end
+`)
+	writeFile(t, dir, "config/helpers_test.star", `
+load("@ytt:assert", "assert")
+load("helpers.star", "greet")
+def test_greet():
+  assert.deep_equals({"names": ["default"], "greeting": "hello default"}, greet())
+  assert.contains(greet("x")["greeting"], "x")
+end
+def test_raises():
+  assert.raises(lambda: greet(1), "unknown binary op")
+end
+def test_failing():
+  assert.deep_equals({"greeting": "bye"}, greet())
+end
+def helper():
+  fail("not a test")
+end
+`)
+
+	writeFile(t, dir, "tests/default/expected.yml", "name: default\n")
+	writeFile(t, dir, "tests/custom/data-values.yml", "name: custom\n")
+	writeFile(t, dir, "tests/custom/expected.yml", "name: custom\n")
+	writeFile(t, dir, "tests/extra/extra.yml", "extra: true\n")
+	writeFile(t, dir, "tests/extra/expected.yml", "name: default\n---\nextra: false\n")
+	writeFile(t, dir, "tests/error/bad.yml", "#@ fail(\"bad\")  \n")
+	writeFile(t, dir, "tests/error/expected-error.txt", "- fail: bad\n")
+
+	run := func(opts testrunner.RunnerOpts) ([]testrunner.Result, error) {
+		opts.Files = []string{filepath.Join(dir, "config")}
+		return testrunner.NewRunner(opts, ui.NewCustomWriterTTY(false, &bytes.Buffer{}, &bytes.Buffer{})).Run([]string{filepath.Join(dir, "tests")})
+	}
+
+	results, err := run(testrunner.RunnerOpts{})
+	require.NoError(t, err)
+	require.Len(t, results, 7)
+
+	byName := map[string]testrunner.Result{}
+	for _, result := range results {
+		byName[filepath.Base(result.Name)] = result
+	}
+
+	require.True(t, byName["helpers_test.star:test_greet"].Passed)
+	require.True(t, byName["helpers_test.star:test_raises"].Passed)
+	require.False(t, byName["helpers_test.star:test_failing"].Passed)
+	require.Contains(t, byName["helpers_test.star:test_failing"].Failure, `Not equal (- expected, + actual):
+  0,  0 - |greeting: bye
+  1,  0 + |greeting: hello default
+  1,  1 + |names:
+  1,  2 + |- default`)
+
+	require.True(t, byName["default"].Passed)
+	require.True(t, byName["custom"].Passed)
+	require.False(t, byName["extra"].Passed)
+	require.Equal(t, `Diff (- expected, + actual):
+  0,  0   |name: default
+  1,  1   |---
+  2,  2 - |extra: false
+  3,  2 + |extra: true
+  3,  3   |
+`, byName["extra"].Failure)
+	require.False(t, byName["error"].Passed)
+	require.Contains(t, byName["error"].Failure, "Diff (- expected, + actual):\n")
+
+	t.Run("runs only matching test cases", func(t *testing.T) {
+		results, err := run(testrunner.RunnerOpts{Filter: "cust|test_greet"})
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		require.Equal(t, "helpers_test.star:test_greet", results[0].Name)
+		require.Equal(t, "custom", filepath.Base(results[1].Name))
+	})
+
+	t.Run("updates expected files", func(t *testing.T) {
+		require.NoError(t, os.Remove(filepath.Join(dir, "tests/default/expected.yml")))
+		writeFile(t, dir, "tests/default/expected-error.txt", "old error\n")
+
+		results, err := run(testrunner.RunnerOpts{Update: true, Filter: "tests"})
+		require.NoError(t, err)
+		require.Len(t, results, 4)
+		for _, result := range results {
+			require.True(t, result.Passed)
+		}
+
+		requireFile(t, dir, "tests/default/expected.yml", "name: default\n")
+		requireFile(t, dir, "tests/extra/expected.yml", "name: default\n---\nextra: true\n")
+		_, err = os.Stat(filepath.Join(dir, "tests/default/expected-error.txt"))
+		require.True(t, os.IsNotExist(err))
+
+		results, err = run(testrunner.RunnerOpts{Filter: "tests"})
+		require.NoError(t, err)
+		for _, result := range results {
+			require.True(t, result.Passed, "test case %s", result.Case.Path)
+			require.False(t, result.Updated)
+		}
+	})
+
+	t.Run("rejects test cases with both expected files", func(t *testing.T) {
+		writeFile(t, dir, "tests/custom/expected-error.txt", "err\n")
+		defer os.Remove(filepath.Join(dir, "tests/custom/expected-error.txt"))
+
+		_, err := run(testrunner.RunnerOpts{})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Expected test case '"+filepath.Join(dir, "tests/custom")+
+			"' to include only one of 'expected.yml' or 'expected-error.txt'")
+	})
+}
+
+func TestRunnerStarlarkTestsInCases(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ytt-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "config/tpl.yml", "#@ load(\"@ytt:data\", \"data\")\n---\nname: #@ data.values.name\n")
+	writeFile(t, dir, "config/values.yml", "#@data/values\n---\nname: default\n")
+	writeFile(t, dir, "config/broken_test.star", "def test_broken(:\n")
+
+	writeFile(t, dir, "tests/custom/data-values.yml", "name: custom\n")
+	writeFile(t, dir, "tests/custom/expected.yml", "name: custom\n")
+	writeFile(t, dir, "tests/custom/values_test.star", `
+load("@ytt:assert", "assert")
+load("@ytt:data", "data")
+def test_name():
+  assert.equals("custom", data.values.name)
+end
+`)
+	writeFile(t, dir, "tests/missing/expected.yml", "name: default\n")
+	writeFile(t, dir, "tests/missing/missing_test.star", "load(\"missing.star\", \"x\")\n")
+
+	opts := testrunner.RunnerOpts{Files: []string{filepath.Join(dir, "config")}}
+	results, err := testrunner.NewRunner(opts, ui.NewCustomWriterTTY(false, &bytes.Buffer{}, &bytes.Buffer{})).Run([]string{filepath.Join(dir, "tests")})
+	require.NoError(t, err)
+
+	var names []string
+	byName := map[string]testrunner.Result{}
+	for _, result := range results {
+		name, err := filepath.Rel(dir, result.Name)
+		if err != nil {
+			name = result.Name
+		}
+		names = append(names, filepath.ToSlash(name))
+		byName[filepath.ToSlash(name)] = result
+	}
+
+	require.Equal(t, []string{
+		"broken_test.star",
+		"tests/custom/values_test.star:test_name",
+		"tests/missing/missing_test.star",
+		"tests/custom",
+		"tests/missing",
+	}, names)
+
+	require.False(t, byName["broken_test.star"].Passed)
+	require.Contains(t, byName["broken_test.star"].Failure, "Evaluating tests: ")
+	require.True(t, byName["tests/custom/values_test.star:test_name"].Passed)
+	require.False(t, byName["tests/missing/missing_test.star"].Passed)
+	require.Contains(t, byName["tests/missing/missing_test.star"].Failure, "missing.star")
+	require.True(t, byName["tests/custom"].Passed)
+	require.True(t, byName["tests/missing"].Passed)
+}
+
+func writeFile(t *testing.T, dir, relPath, content string) {
+	path := filepath.Join(dir, filepath.FromSlash(relPath))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+}
+
+func requireFile(t *testing.T, dir, relPath, content string) {
+	bs, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(relPath)))
+	require.NoError(t, err)
+	require.Equal(t, content, string(bs))
+}
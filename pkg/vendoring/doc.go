@@ -0,0 +1,12 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+Package vendoring copies ytt libraries from their sources (local paths, HTTP
+URLs, archives or OCI image layouts) into the private library directory
+(i.e. _ytt_lib) of a root library, as described by a Manifest.
+
+Content digests of vendored files are recorded in a LockFile so that drift
+between the lock file and the vendored directories can be detected later.
+*/
+package vendoring
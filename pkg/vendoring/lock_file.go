@@ -0,0 +1,148 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package vendoring
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vmware-tanzu/carvel-ytt/pkg/yamlmeta"
+)
+
+const (
+	// DefaultLockFile is the name of the lock file used when none is given.
+	DefaultLockFile = "ytt-vendor.lock.yml"
+
+	contentDigestPrefix = "sha256:"
+)
+
+var (
+	contentDigestRegexp = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+// LockFile records content digests of vendored libraries.
+type LockFile struct {
+	Libraries []LockedLibrary `json:"libraries" yaml:"libraries"`
+}
+
+// LockedLibrary records what was vendored for a library.
+type LockedLibrary struct {
+	Name   string       `json:"name" yaml:"name"`
+	Source string       `json:"source" yaml:"source"`
+	Path   string       `json:"path,omitempty" yaml:"path,omitempty"`
+	Digest string       `json:"digest" yaml:"digest"`
+	Files  []LockedFile `json:"files" yaml:"files"`
+}
+
+// LockedFile records digest of a single vendored file (path is relative to library directory).
+type LockedFile struct {
+	Path   string `json:"path" yaml:"path"`
+	Digest string `json:"digest" yaml:"digest"`
+}
+
+// NewLockFileFromFile reads lock file found at path.
+func NewLockFileFromFile(path string) (LockFile, error) {
+	var lockFile LockFile
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return lockFile, fmt.Errorf("Reading vendor lock file: %s", err)
+	}
+
+	err = unmarshalPlainYAML(data, &lockFile)
+	if err != nil {
+		return lockFile, fmt.Errorf("Unmarshaling vendor lock file '%s': %s", path, err)
+	}
+
+	return lockFile, nil
+}
+
+// Write saves lock file at path.
+func (f LockFile) Write(path string) error {
+	data, err := yamlmeta.PlainMarshal(f)
+	if err != nil {
+		return fmt.Errorf("Marshaling vendor lock file: %s", err)
+	}
+
+	return ioutil.WriteFile(path, data, os.FileMode(0600))
+}
+
+// Find returns locked library with given name.
+func (f LockFile) Find(name string) (LockedLibrary, bool) {
+	for _, lib := range f.Libraries {
+		if lib.Name == name {
+			return lib, true
+		}
+	}
+	return LockedLibrary{}, false
+}
+
+// newLockedLibrary computes digests for files (keyed by path relative to library directory).
+func newLockedLibrary(lib ManifestLibrary, files map[string][]byte) LockedLibrary {
+	lockedLib := LockedLibrary{Name: lib.Name, Source: lib.Source, Path: lib.Path}
+
+	for path, data := range files {
+		lockedLib.Files = append(lockedLib.Files, LockedFile{Path: path, Digest: contentDigest(data)})
+	}
+	sort.Slice(lockedLib.Files, func(i, j int) bool {
+		return lockedLib.Files[i].Path < lockedLib.Files[j].Path
+	})
+
+	lockedLib.Digest = lockedLib.filesDigest()
+	return lockedLib
+}
+
+// filesDigest is a digest of all file paths and their digests, hence
+// it changes when any file is added, removed, renamed or modified.
+func (l LockedLibrary) filesDigest() string {
+	var lines []string
+	for _, file := range l.Files {
+		lines = append(lines, file.Path+"\x00"+file.Digest)
+	}
+	sort.Strings(lines)
+	return contentDigest([]byte(strings.Join(lines, "\n")))
+}
+
+// Diff describes how actual library content differs from this locked library.
+func (l LockedLibrary) Diff(actual LockedLibrary) []string {
+	var diffs []string
+
+	expectedFiles := map[string]string{}
+	for _, file := range l.Files {
+		expectedFiles[file.Path] = file.Digest
+	}
+
+	for _, file := range actual.Files {
+		expectedDigest, found := expectedFiles[file.Path]
+		switch {
+		case !found:
+			diffs = append(diffs, fmt.Sprintf("file '%s' was added", file.Path))
+		case expectedDigest != file.Digest:
+			diffs = append(diffs, fmt.Sprintf("file '%s' was modified", file.Path))
+		}
+		delete(expectedFiles, file.Path)
+	}
+
+	for path := range expectedFiles {
+		diffs = append(diffs, fmt.Sprintf("file '%s' was removed", path))
+	}
+
+	if len(diffs) == 0 && l.Digest != actual.Digest {
+		diffs = append(diffs, fmt.Sprintf("library digest '%s' does not match files digest '%s'", l.Digest, actual.Digest))
+	}
+
+	sort.Strings(diffs)
+	return diffs
+}
+
+func contentDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return contentDigestPrefix + hex.EncodeToString(sum[:])
+}
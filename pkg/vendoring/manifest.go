@@ -0,0 +1,160 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package vendoring
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/orderedmap"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/yamlmeta"
+)
+
+const (
+	// DefaultManifestFile is the name of the manifest file used when none is given.
+	DefaultManifestFile = "ytt-vendor.yml"
+)
+
+// Manifest lists libraries to vendor.
+//
+//	libraries:
+//	- name: github.com/org/lib          # directory within _ytt_lib
+//	  source: https://host/lib.tgz      # anything accepted by --file (local path, HTTP URL, archive, 'oci-layout:' dirpath)
+//	  path: config/lib                  # (optional) directory within source to vendor
+//	  digest: sha256:<hex>              # (optional) expected content digest of vendored files
+type Manifest struct {
+	Libraries []ManifestLibrary `json:"libraries"`
+
+	dir string // relative local sources are resolved against this directory
+}
+
+// ManifestLibrary describes a single library to vendor.
+type ManifestLibrary struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Path   string `json:"path"`
+	Digest string `json:"digest"`
+}
+
+// NewManifestFromFile reads and validates manifest found at path.
+func NewManifestFromFile(path string) (Manifest, error) {
+	var manifest Manifest
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return manifest, fmt.Errorf("Reading vendor manifest: %s", err)
+	}
+
+	err = unmarshalPlainYAML(data, &manifest)
+	if err != nil {
+		return manifest, fmt.Errorf("Unmarshaling vendor manifest '%s': %s", path, err)
+	}
+
+	manifest.dir = filepath.Dir(path)
+
+	err = manifest.validate()
+	if err != nil {
+		return manifest, fmt.Errorf("Validating vendor manifest '%s': %s", path, err)
+	}
+
+	return manifest, nil
+}
+
+func (m Manifest) validate() error {
+	names := map[string]struct{}{}
+
+	for i, lib := range m.Libraries {
+		err := checkLibraryName(lib.Name)
+		if err != nil {
+			return fmt.Errorf("Library %d: %s", i, err)
+		}
+		if _, found := names[lib.Name]; found {
+			return fmt.Errorf("Expected library names to be unique, but found '%s' multiple times", lib.Name)
+		}
+		names[lib.Name] = struct{}{}
+
+		if len(lib.Source) == 0 {
+			return fmt.Errorf("Expected library '%s' to specify source", lib.Name)
+		}
+		if len(lib.Digest) > 0 && !contentDigestRegexp.MatchString(lib.Digest) {
+			return fmt.Errorf("Expected library '%s' digest '%s' to be in format 'sha256:<hex>'", lib.Name, lib.Digest)
+		}
+	}
+
+	// nested libraries would overwrite each other's files
+	for name := range names {
+		for otherName := range names {
+			if strings.HasPrefix(otherName, name+"/") {
+				return fmt.Errorf("Expected library '%s' to not be nested within library '%s'", otherName, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// sourcePath returns lib's source in form accepted by files.NewSortedFilesFromPaths
+func (m Manifest) sourcePath(lib ManifestLibrary) string {
+	source := lib.Source
+
+	switch {
+	case strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://"):
+		return source
+	case strings.HasPrefix(source, files.OCILayoutPathPrefix):
+		dir := strings.TrimPrefix(source, files.OCILayoutPathPrefix)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(m.dir, dir)
+		}
+		return files.OCILayoutPathPrefix + dir
+	case filepath.IsAbs(source):
+		return source
+	default:
+		return filepath.Join(m.dir, source)
+	}
+}
+
+func checkLibraryName(name string) error {
+	if len(name) == 0 {
+		return fmt.Errorf("Expected library to specify name")
+	}
+
+	cleanName := path.Clean(name)
+	if cleanName != name || path.IsAbs(name) || cleanName == "." || cleanName == ".." || strings.HasPrefix(cleanName, "../") {
+		return fmt.Errorf("Expected library name '%s' to be a clean relative path (e.g. 'github.com/org/lib')", name)
+	}
+
+	for _, piece := range strings.Split(name, "/") {
+		if piece == workspace.PrivateLibraryDirName {
+			return fmt.Errorf("Expected library name '%s' to not contain '%s'", name, workspace.PrivateLibraryDirName)
+		}
+	}
+
+	return nil
+}
+
+func unmarshalPlainYAML(data []byte, out interface{}) error {
+	var val interface{}
+
+	err := yamlmeta.PlainUnmarshal(data, &val)
+	if err != nil {
+		return err
+	}
+
+	jsonBytes, err := json.Marshal(orderedmap.Conversion{Object: val}.AsUnorderedStringMaps())
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(out)
+}
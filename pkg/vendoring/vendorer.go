@@ -0,0 +1,250 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package vendoring
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace"
+)
+
+// VendorerOpts configures where libraries are vendored to and how their sources are read.
+type VendorerOpts struct {
+	// Directory is the root library directory (libraries are placed in its _ytt_lib directory).
+	Directory    string
+	LockFilePath string
+
+	SymlinkAllowOpts files.SymlinkAllowOpts
+	HTTPSourceOpts   files.HTTPSourceOpts
+}
+
+// Vendorer populates (and verifies) vendored libraries described by a Manifest.
+type Vendorer struct {
+	manifest Manifest
+	opts     VendorerOpts
+	ui       ui.UI
+}
+
+// NewVendorer constructs a Vendorer for manifest.
+func NewVendorer(manifest Manifest, opts VendorerOpts, ui ui.UI) *Vendorer {
+	return &Vendorer{manifest, opts, ui}
+}
+
+// Sync fetches each library from its source, replaces its vendored directory with
+// the fetched files, and records their digests in the lock file. Vendored directories
+// of libraries that are in the lock file, but no longer in the manifest, are removed.
+// Nothing is written unless all libraries were fetched and matched their expected digests.
+func (v *Vendorer) Sync() error {
+	prevLockFile, err := v.prevLockFile()
+	if err != nil {
+		return err
+	}
+
+	var lockFile LockFile
+	var libsFiles []map[string][]byte
+
+	for _, lib := range v.manifest.Libraries {
+		libFiles, err := v.fetch(lib)
+		if err != nil {
+			return fmt.Errorf("Vendoring library '%s': %s", lib.Name, err)
+		}
+
+		lockedLib := newLockedLibrary(lib, libFiles)
+
+		if len(lib.Digest) > 0 && lib.Digest != lockedLib.Digest {
+			return fmt.Errorf("Expected library '%s' to have digest '%s', but was '%s'", lib.Name, lib.Digest, lockedLib.Digest)
+		}
+
+		lockFile.Libraries = append(lockFile.Libraries, lockedLib)
+		libsFiles = append(libsFiles, libFiles)
+	}
+
+	for i, lockedLib := range lockFile.Libraries {
+		var outputFiles []files.OutputFile
+		for _, file := range lockedLib.Files {
+			outputFiles = append(outputFiles, files.NewOutputFile(file.Path, libsFiles[i][file.Path], files.TypeUnknown))
+		}
+
+		err = files.NewOutputDirectory(v.libraryDir(lockedLib.Name), outputFiles, v.ui).Write()
+		if err != nil {
+			return fmt.Errorf("Writing library '%s': %s", lockedLib.Name, err)
+		}
+	}
+
+	for _, prevLib := range prevLockFile.Libraries {
+		if _, found := lockFile.Find(prevLib.Name); found {
+			continue
+		}
+		err := v.removeVendored(prevLib.Name)
+		if err != nil {
+			return fmt.Errorf("Removing library '%s': %s", prevLib.Name, err)
+		}
+	}
+
+	return lockFile.Write(v.opts.LockFilePath)
+}
+
+// Verify checks that lock file matches manifest and that vendored
+// directories contain exactly the files recorded in the lock file.
+func (v *Vendorer) Verify() error {
+	lockFile, err := NewLockFileFromFile(v.opts.LockFilePath)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+
+	for _, lib := range v.manifest.Libraries {
+		lockedLib, found := lockFile.Find(lib.Name)
+		if !found {
+			errs = append(errs, fmt.Sprintf("- library '%s': not found in lock file", lib.Name))
+			continue
+		}
+		if lockedLib.Source != lib.Source || lockedLib.Path != lib.Path {
+			errs = append(errs, fmt.Sprintf("- library '%s': source in lock file does not match manifest", lib.Name))
+			continue
+		}
+		if len(lib.Digest) > 0 && lib.Digest != lockedLib.Digest {
+			errs = append(errs, fmt.Sprintf("- library '%s': digest in lock file does not match manifest", lib.Name))
+			continue
+		}
+
+		libFiles, err := v.readVendored(lib.Name)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("- library '%s': %s", lib.Name, err))
+			continue
+		}
+
+		for _, diff := range lockedLib.Diff(newLockedLibrary(lib, libFiles)) {
+			errs = append(errs, fmt.Sprintf("- library '%s': %s", lib.Name, diff))
+		}
+	}
+
+	for _, lockedLib := range lockFile.Libraries {
+		found := false
+		for _, lib := range v.manifest.Libraries {
+			if lib.Name == lockedLib.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("- library '%s': not found in manifest", lockedLib.Name))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("Expected vendored libraries to match lock file, but did not:\n%s", strings.Join(errs, "\n"))
+	}
+
+	return nil
+}
+
+// fetch returns contents of library files keyed by path relative to library directory.
+func (v *Vendorer) fetch(lib ManifestLibrary) (map[string][]byte, error) {
+	srcFiles, err := files.NewSortedFilesFromPathsWithOpts([]string{v.manifest.sourcePath(lib)},
+		v.opts.SymlinkAllowOpts, v.opts.HTTPSourceOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := ""
+	if len(lib.Path) > 0 {
+		prefix = path.Clean(lib.Path) + "/"
+	}
+
+	result := map[string][]byte{}
+
+	for _, file := range srcFiles {
+		relPath := file.RelativePath()
+		if !strings.HasPrefix(relPath, prefix) {
+			continue
+		}
+
+		data, err := file.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		result[strings.TrimPrefix(relPath, prefix)] = data
+	}
+
+	if len(result) == 0 {
+		if len(prefix) > 0 {
+			return nil, fmt.Errorf("Expected to find files in path '%s' of source, but found none", lib.Path)
+		}
+		return nil, fmt.Errorf("Expected to find files in source, but found none")
+	}
+
+	return result, nil
+}
+
+func (v *Vendorer) readVendored(name string) (map[string][]byte, error) {
+	libDir := v.libraryDir(name)
+
+	_, err := os.Stat(libDir)
+	if err != nil {
+		return nil, fmt.Errorf("Checking vendored directory: %s", err)
+	}
+
+	vendoredFiles, err := files.NewSortedFilesFromPaths([]string{libDir}, v.opts.SymlinkAllowOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string][]byte{}
+	for _, file := range vendoredFiles {
+		data, err := file.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		result[file.RelativePath()] = data
+	}
+	return result, nil
+}
+
+// prevLockFile returns lock file written by previous sync (empty, if there is none).
+func (v *Vendorer) prevLockFile() (LockFile, error) {
+	_, err := os.Stat(v.opts.LockFilePath)
+	if os.IsNotExist(err) {
+		return LockFile{}, nil
+	}
+	return NewLockFileFromFile(v.opts.LockFilePath)
+}
+
+// removeVendored deletes vendored directory of a library together with
+// parent directories that are left empty (e.g. _ytt_lib/github.com/org).
+func (v *Vendorer) removeVendored(name string) error {
+	// Lock file may have been edited by hand
+	err := checkLibraryName(name)
+	if err != nil {
+		return err
+	}
+
+	err = os.RemoveAll(v.libraryDir(name))
+	if err != nil {
+		return err
+	}
+
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		entries, err := os.ReadDir(v.libraryDir(dir))
+		if err != nil || len(entries) > 0 {
+			break
+		}
+		err = os.Remove(v.libraryDir(dir))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *Vendorer) libraryDir(name string) string {
+	return filepath.Join(v.opts.Directory, workspace.PrivateLibraryDirName, filepath.FromSlash(name))
+}
@@ -0,0 +1,171 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package vendoring_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/vendoring"
+)
+
+func TestVendorer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ytt-vendor")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeFile(t, dir, "upstream/config/lib/values.yml", "#@data/values\n---\nfoo: 1\n")
+	writeFile(t, dir, "upstream/config/lib/tpl.yml", "foo: bar\n")
+	writeFile(t, dir, "upstream/README.md", "readme")
+	writeFile(t, dir, "other/lib.star", "x = 1\n")
+
+	writeFile(t, dir, "app/ytt-vendor.yml", `
+libraries:
+- name: github.com/org/lib
+  source: ../upstream
+  path: config/lib
+- name: other
+  source: ../other/lib.star
+`)
+
+	newVendorer := func() *vendoring.Vendorer {
+		manifest, err := vendoring.NewManifestFromFile(filepath.Join(dir, "app", "ytt-vendor.yml"))
+		require.NoError(t, err)
+
+		opts := vendoring.VendorerOpts{
+			Directory:    filepath.Join(dir, "app"),
+			LockFilePath: filepath.Join(dir, "app", vendoring.DefaultLockFile),
+		}
+		return vendoring.NewVendorer(manifest, opts, ui.NewCustomWriterTTY(false, &bytes.Buffer{}, &bytes.Buffer{}))
+	}
+
+	require.NoError(t, newVendorer().Sync())
+
+	require.Equal(t, "foo: bar\n", readFile(t, dir, "app/_ytt_lib/github.com/org/lib/tpl.yml"))
+	require.Equal(t, "#@data/values\n---\nfoo: 1\n", readFile(t, dir, "app/_ytt_lib/github.com/org/lib/values.yml"))
+	require.Equal(t, "x = 1\n", readFile(t, dir, "app/_ytt_lib/other/lib.star"))
+	_, err = os.Stat(filepath.Join(dir, "app/_ytt_lib/github.com/org/lib/README.md"))
+	require.True(t, os.IsNotExist(err))
+
+	lockFile, err := vendoring.NewLockFileFromFile(filepath.Join(dir, "app", vendoring.DefaultLockFile))
+	require.NoError(t, err)
+	require.Len(t, lockFile.Libraries, 2)
+
+	lockedLib, found := lockFile.Find("github.com/org/lib")
+	require.True(t, found)
+	require.Equal(t, "../upstream", lockedLib.Source)
+	require.Regexp(t, "^sha256:[a-f0-9]{64}$", lockedLib.Digest)
+	require.Len(t, lockedLib.Files, 2)
+	require.Equal(t, "tpl.yml", lockedLib.Files[0].Path)
+	require.Equal(t, "values.yml", lockedLib.Files[1].Path)
+
+	t.Run("verifies vendored libraries", func(t *testing.T) {
+		require.NoError(t, newVendorer().Verify())
+	})
+
+	t.Run("fails verification on drift", func(t *testing.T) {
+		writeFile(t, dir, "app/_ytt_lib/github.com/org/lib/tpl.yml", "foo: changed\n")
+		writeFile(t, dir, "app/_ytt_lib/github.com/org/lib/extra.yml", "extra: 1\n")
+		require.NoError(t, os.Remove(filepath.Join(dir, "app/_ytt_lib/github.com/org/lib/values.yml")))
+
+		err := newVendorer().Verify()
+		require.EqualError(t, err, `Expected vendored libraries to match lock file, but did not:
+- library 'github.com/org/lib': file 'extra.yml' was added
+- library 'github.com/org/lib': file 'tpl.yml' was modified
+- library 'github.com/org/lib': file 'values.yml' was removed`)
+
+		require.NoError(t, newVendorer().Sync())
+		require.NoError(t, newVendorer().Verify())
+	})
+
+	t.Run("removes libraries that are no longer in manifest", func(t *testing.T) {
+		writeFile(t, dir, "app/_ytt_lib/local/tpl.yml", "local: 1\n")
+		writeFile(t, dir, "app/ytt-vendor.yml", `
+libraries:
+- name: other
+  source: ../other/lib.star
+`)
+		require.NoError(t, newVendorer().Sync())
+		require.NoError(t, newVendorer().Verify())
+
+		_, err := os.Stat(filepath.Join(dir, "app/_ytt_lib/github.com"))
+		require.True(t, os.IsNotExist(err))
+		require.Equal(t, "x = 1\n", readFile(t, dir, "app/_ytt_lib/other/lib.star"))
+		// Libraries that were not vendored are left alone
+		require.Equal(t, "local: 1\n", readFile(t, dir, "app/_ytt_lib/local/tpl.yml"))
+	})
+
+	t.Run("does not write any library when one of them fails", func(t *testing.T) {
+		writeFile(t, dir, "other/lib.star", "x = 2\n")
+		defer writeFile(t, dir, "other/lib.star", "x = 1\n")
+
+		writeFile(t, dir, "app/ytt-vendor.yml", `
+libraries:
+- name: other
+  source: ../other/lib.star
+- name: missing
+  source: ../missing
+`)
+		err := newVendorer().Sync()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Vendoring library 'missing': ")
+
+		require.Equal(t, "x = 1\n", readFile(t, dir, "app/_ytt_lib/other/lib.star"))
+		_, err = os.Stat(filepath.Join(dir, "app/_ytt_lib/missing"))
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("checks digest from manifest", func(t *testing.T) {
+		badDigest := "sha256:" + strings.Repeat("0", 64)
+		writeFile(t, dir, "app/ytt-vendor.yml", `
+libraries:
+- name: other
+  source: ../other/lib.star
+  digest: `+badDigest+`
+`)
+		err := newVendorer().Sync()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Expected library 'other' to have digest '"+badDigest+"', but was 'sha256:")
+	})
+}
+
+func TestManifestValidation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ytt-vendor")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cases := map[string]string{
+		"libraries:\n- name: ../lib\n  source: x":                               "Validating vendor manifest '%s': Library 0: Expected library name '../lib' to be a clean relative path (e.g. 'github.com/org/lib')",
+		"libraries:\n- name: a/_ytt_lib/b\n  source: x":                         "Validating vendor manifest '%s': Library 0: Expected library name 'a/_ytt_lib/b' to not contain '_ytt_lib'",
+		"libraries:\n- name: lib":                                               "Validating vendor manifest '%s': Expected library 'lib' to specify source",
+		"libraries:\n- name: lib\n  source: x\n- name: lib\n  source: z":        "Validating vendor manifest '%s': Expected library names to be unique, but found 'lib' multiple times",
+		"libraries:\n- name: lib\n  source: x\n- name: lib/nested\n  source: z": "Validating vendor manifest '%s': Expected library 'lib/nested' to not be nested within library 'lib'",
+		"libraries:\n- name: lib\n  src: x":                                     "Unmarshaling vendor manifest '%s': json: unknown field \"src\"",
+	}
+
+	for manifest, expectedErr := range cases {
+		path := writeFile(t, dir, "ytt-vendor.yml", manifest)
+		_, err := vendoring.NewManifestFromFile(path)
+		require.EqualError(t, err, strings.Replace(expectedErr, "%s", path, 1))
+	}
+}
+
+func writeFile(t *testing.T, dir, relPath, content string) string {
+	path := filepath.Join(dir, filepath.FromSlash(relPath))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func readFile(t *testing.T, dir, relPath string) string {
+	data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(relPath)))
+	require.NoError(t, err)
+	return string(data)
+}
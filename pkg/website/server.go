@@ -12,13 +12,24 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/vmware-tanzu/carvel-ytt/pkg/template"
 )
 
+// DefaultExecutionLimits bound evaluation of templates submitted by users.
+var DefaultExecutionLimits = template.ExecutionLimits{
+	MaxSteps:  10000000,
+	Timeout:   10 * time.Second,
+	MaxMemory: 256 * 1024 * 1024,
+}
+
 type ServerOpts struct {
 	ListenAddr      string
 	RedirectToHTTPS bool
 	CheckCookie     bool
-	TemplateFunc    func([]byte) ([]byte, error)
+	// ExecutionLimits are passed to TemplateFunc (see DefaultExecutionLimits)
+	ExecutionLimits template.ExecutionLimits
+	TemplateFunc    func([]byte, template.ExecutionLimits) ([]byte, error)
 	ErrorFunc       func(error) ([]byte, error)
 }
 
@@ -121,7 +132,7 @@ func (s *Server) templateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := s.opts.TemplateFunc(data)
+	resp, err := s.opts.TemplateFunc(data, s.opts.ExecutionLimits)
 	if err != nil {
 		s.logError(w, err)
 		return
@@ -0,0 +1,130 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package workspace
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/vmware-tanzu/carvel-ytt/pkg/template"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/texttemplate"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/version"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/yamlmeta"
+)
+
+const (
+	// compiledTemplateCacheFormat should be bumped whenever serialized form changes
+	compiledTemplateCacheFormat = "1"
+
+	compiledTemplateCacheKindDocSet   = "docset"
+	compiledTemplateCacheKindTemplate = "template"
+)
+
+func init() {
+	// Concrete types held in interface{} fields of serialized nodes
+	gob.Register(&yamlmeta.SerializableNode{})
+	gob.Register(&texttemplate.NodeRoot{})
+	gob.Register(&texttemplate.NodeText{})
+	gob.Register(time.Time{})
+}
+
+// CompiledTemplateCache stores parsed YAML documents and compiled YAML templates on disk
+// so that unchanged files do not need to be parsed and compiled again in subsequent runs.
+//
+// Entries are keyed by file path, content, options affecting parsing and compilation, and ytt version.
+// Methods are no-ops on a nil CompiledTemplateCache so that callers do not need to check whether caching is enabled.
+type CompiledTemplateCache struct {
+	dir string
+}
+
+// NewCompiledTemplateCache returns a cache stored in given directory (created if missing).
+func NewCompiledTemplateCache(dir string) (*CompiledTemplateCache, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, fmt.Errorf("Creating cache directory: %s", err)
+	}
+	return &CompiledTemplateCache{dir}, nil
+}
+
+// cachedCompiledTemplate is an entry stored for a YAML template.
+type cachedCompiledTemplate struct {
+	Template *template.SerializableCompiledTemplate
+}
+
+// cachedDocSet is an entry stored for a YAML document set.
+type cachedDocSet struct {
+	DocSet *yamlmeta.SerializableNode
+}
+
+// key uniquely identifies an entry of given kind for a file with given content.
+func (c *CompiledTemplateCache) key(kind, path string, content []byte, opts ...bool) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%s\x00%s\x00%v\x00", compiledTemplateCacheFormat, version.Version, kind, path, opts)
+	hash.Write(content)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// get decodes an entry into val; returns false if entry is not present.
+func (c *CompiledTemplateCache) get(key string, val interface{}) (bool, error) {
+	if c == nil {
+		return false, nil
+	}
+
+	file, err := os.Open(c.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("Opening cache entry: %s", err)
+	}
+	defer file.Close()
+
+	err = gob.NewDecoder(bufio.NewReader(file)).Decode(val)
+	if err != nil {
+		return false, fmt.Errorf("Decoding cache entry: %s", err)
+	}
+
+	return true, nil
+}
+
+// put stores val as an entry; concurrent writers of the same entry do not observe partially written entries.
+func (c *CompiledTemplateCache) put(key string, val interface{}) error {
+	if c == nil {
+		return nil
+	}
+
+	file, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("Creating cache entry: %s", err)
+	}
+
+	writer := bufio.NewWriter(file)
+
+	err = gob.NewEncoder(writer).Encode(val)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), c.path(key))
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("Writing cache entry: %s", err)
+	}
+
+	return nil
+}
+
+func (c *CompiledTemplateCache) path(key string) string {
+	return filepath.Join(c.dir, key+".gob")
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/k14s/starlark-go/starlark"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/schema"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/template"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace/datavalues"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/yamlmeta"
 	yttoverlay "github.com/vmware-tanzu/carvel-ytt/pkg/yttlibrary/overlay"
@@ -32,6 +33,8 @@ func (pp DataValuesPreProcessing) Apply() (*datavalues.Envelope, []*datavalues.E
 	// Respect assigned file order for data values overlaying to succeed
 	SortFilesInLibrary(files)
 
+	defer pp.loader.opts.Profiler.Measure(template.ProfileCategoryDataValues, pp.profileDesc(files))()
+
 	dataValues, libraryDataValues, err := pp.apply(files)
 	if err != nil {
 		errMsg := "Overlaying data values (in following order: %s): %s"
@@ -146,6 +149,13 @@ func (pp DataValuesPreProcessing) extractDataValueDocs(dvFile *FileInLibrary) ([
 	return valuesDocs, nil
 }
 
+func (pp DataValuesPreProcessing) profileDesc(files []*FileInLibrary) string {
+	if fileDescs := pp.allFileDescs(files); len(fileDescs) > 0 {
+		return "values (" + fileDescs + ")"
+	}
+	return "values"
+}
+
 func (pp DataValuesPreProcessing) allFileDescs(files []*FileInLibrary) string {
 	var result []string
 	for _, f := range files {
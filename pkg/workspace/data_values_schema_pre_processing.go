@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/k14s/starlark-go/starlark"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/template"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace/datavalues"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/yamlmeta"
 	yttoverlay "github.com/vmware-tanzu/carvel-ytt/pkg/yttlibrary/overlay"
@@ -30,6 +31,8 @@ func (pp DataValuesSchemaPreProcessing) Apply() (*datavalues.Schema, []*datavalu
 	// Ensure files are in assigned order so that overlays will be applied correctly.
 	SortFilesInLibrary(files)
 
+	defer pp.loader.opts.Profiler.Measure(template.ProfileCategoryDataValues, pp.profileDesc(files))()
+
 	schema, libSchemas, err := pp.apply(files)
 	if err != nil {
 		errMsg := "Overlaying data values schema (in following order: %s): %s"
@@ -123,6 +126,13 @@ func (pp DataValuesSchemaPreProcessing) extractSchemaDocs(schemaFile *FileInLibr
 	return schemaDocs, nil
 }
 
+func (pp DataValuesSchemaPreProcessing) profileDesc(files []*FileInLibrary) string {
+	if fileDescs := pp.allFileDescs(files); len(fileDescs) > 0 {
+		return "schema (" + fileDescs + ")"
+	}
+	return "schema"
+}
+
 func (pp DataValuesSchemaPreProcessing) allFileDescs(files []*FileInLibrary) string {
 	var result []string
 	for _, f := range files {
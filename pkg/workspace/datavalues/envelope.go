@@ -6,6 +6,7 @@ package datavalues
 import (
 	"fmt"
 	"strings"
+	"sync/atomic"
 
 	"github.com/vmware-tanzu/carvel-ytt/pkg/filepos"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/template"
@@ -28,7 +29,7 @@ const (
 type Envelope struct {
 	Doc         *yamlmeta.Document
 	AfterLibMod bool
-	used        bool
+	used        int32 // accessed atomically (templates may be evaluated concurrently)
 
 	originalLibRef []ref.LibraryRef
 	libRef         []ref.LibraryRef
@@ -85,8 +86,8 @@ func newEnvelopeWithLibRef(libRefs ExtractLibRefs, doc *yamlmeta.Document, libRe
 }
 
 // IsUsed reports whether this Envelope of Data Values has been "delivered"/used.
-func (dvd *Envelope) IsUsed() bool { return dvd.used }
-func (dvd *Envelope) markUsed()    { dvd.used = true }
+func (dvd *Envelope) IsUsed() bool { return atomic.LoadInt32(&dvd.used) == 1 }
+func (dvd *Envelope) markUsed()    { atomic.StoreInt32(&dvd.used, 1) }
 
 // Desc reports the destination library of this Envelope
 func (dvd *Envelope) Desc() string {
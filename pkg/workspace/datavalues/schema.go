@@ -6,6 +6,7 @@ package datavalues
 import (
 	"fmt"
 	"strings"
+	"sync/atomic"
 
 	"github.com/vmware-tanzu/carvel-ytt/pkg/schema"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/template"
@@ -24,7 +25,7 @@ type Schema struct {
 type SchemaEnvelope struct {
 	Doc *Schema
 
-	used           bool
+	used           int32 // accessed atomically (templates may be evaluated concurrently)
 	originalLibRef []ref.LibraryRef
 	libRef         []ref.LibraryRef
 }
@@ -64,6 +65,41 @@ func NewSchemaEnvelope(doc *yamlmeta.Document) (*SchemaEnvelope, error) {
 	}, nil
 }
 
+// NewSchemaEnvelopeWithLibRef generates a new Schema wrapped in a SchemaEnvelope, using the given library reference
+// for "addressing".
+// If libRefStr is empty string, then the addressing (if any) is extracted from the document's annotations.
+func NewSchemaEnvelopeWithLibRef(doc *yamlmeta.Document, libRefStr string) (*SchemaEnvelope, error) {
+	if len(libRefStr) == 0 {
+		return NewSchemaEnvelope(doc)
+	}
+
+	libRefExtractor := ref.LibraryRefExtractor{}
+	libRef, err := libRefExtractor.FromStr(libRefStr)
+	if err != nil {
+		return nil, err
+	}
+
+	libRefFromAnn, err := getSchemaLibRef(libRefExtractor, doc)
+	if err != nil {
+		return nil, err
+	}
+	if len(libRefFromAnn) > 0 {
+		return nil, fmt.Errorf("Expected library to be specified either as argument ('%s') or with %s annotation, but was both",
+			libRefStr, ref.AnnotationLibraryRef)
+	}
+
+	schema, err := NewSchema(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SchemaEnvelope{
+		Doc:            schema,
+		originalLibRef: libRef,
+		libRef:         libRef,
+	}, nil
+}
+
 // NewNullSchema provides the "Null Object" value of Schema. This is used in the case where no schema was provided.
 func NewNullSchema() *Schema {
 	return &Schema{
@@ -133,7 +169,7 @@ func (e *SchemaEnvelope) Desc() string {
 }
 
 // IsUsed reports whether or not the contained Schema was delivered/consumed.
-func (e *SchemaEnvelope) IsUsed() bool { return e.used }
+func (e *SchemaEnvelope) IsUsed() bool { return atomic.LoadInt32(&e.used) == 1 }
 
 // IntendedForAnotherLibrary indicates whether the contained Schema is addressed to another library.
 func (e *SchemaEnvelope) IntendedForAnotherLibrary() bool {
@@ -163,7 +199,7 @@ func (e *SchemaEnvelope) UsedInLibrary(expectedRefPiece ref.LibraryRef) (*Schema
 	return childSchemaProcessing, !childSchemaProcessing.IntendedForAnotherLibrary()
 }
 
-func (e *SchemaEnvelope) markUsed() { e.used = true }
+func (e *SchemaEnvelope) markUsed() { atomic.StoreInt32(&e.used, 1) }
 
 func (e *SchemaEnvelope) deepCopyUnused() *SchemaEnvelope {
 	var copiedPieces []ref.LibraryRef
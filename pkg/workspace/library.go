@@ -14,7 +14,8 @@ import (
 )
 
 const (
-	privateName = "_ytt_lib"
+	// PrivateLibraryDirName is the name of the directory that holds libraries private to the enclosing library.
+	PrivateLibraryDirName = "_ytt_lib"
 )
 
 type Library struct {
@@ -56,7 +57,7 @@ func (l *Library) findLibrary(name string) (*Library, bool) {
 }
 
 func (l *Library) CreateLibrary(name string) *Library {
-	lib := &Library{name: name, private: name == privateName}
+	lib := &Library{name: name, private: name == PrivateLibraryDirName}
 	l.children = append(l.children, lib)
 	return lib
 }
@@ -67,7 +68,7 @@ func (l *Library) FindAccessibleLibrary(path string) (*Library, error) {
 
 	privateLib, found := l.findPrivateLibrary()
 	if !found {
-		return nil, fmt.Errorf("Could not find private library (directory '%s' missing?)", privateName)
+		return nil, fmt.Errorf("Could not find private library (directory '%s' missing?)", PrivateLibraryDirName)
 	}
 
 	var currLibrary *Library = privateLib
@@ -106,7 +107,7 @@ func (l *Library) FindLibrary(path string) (*Library, error) {
 			return nil, fmt.Errorf("Did not find '%s'", files.JoinPath(dirPieces[:i]))
 		}
 		if lib.private {
-			return nil, fmt.Errorf("Encountered private library '%s'", privateName)
+			return nil, fmt.Errorf("Encountered private library '%s'", PrivateLibraryDirName)
 		}
 		currLibrary = lib
 	}
@@ -138,6 +139,33 @@ func (l *Library) FindFile(path string) (FileInLibrary, error) {
 		"Expected to find file '%s' (hint: only files included via -f flag are available)", path)
 }
 
+// FindFileInAnyLibrary finds file by its relative path (including files within private libraries)
+// and returns context in which such file is evaluated.
+func (l *Library) FindFileInAnyLibrary(path string) (FileInLibrary, LibraryExecutionContext, error) {
+	dirPieces, _ := files.SplitPath(path)
+	libraryCtx := LibraryExecutionContext{Current: l, Root: l}
+
+	for _, piece := range dirPieces {
+		lib, found := libraryCtx.Current.findLibrary(piece)
+		if !found {
+			return FileInLibrary{}, libraryCtx, fmt.Errorf("Expected to find file '%s', but did not find directory '%s'", path, piece)
+		}
+		// Libraries directly within private library directory are roots of their own files
+		if libraryCtx.Current.private {
+			libraryCtx.Root = lib
+		}
+		libraryCtx.Current = lib
+	}
+
+	for _, file := range libraryCtx.Current.files {
+		if file.RelativePath() == path {
+			return FileInLibrary{File: file, Library: libraryCtx.Current}, libraryCtx, nil
+		}
+	}
+
+	return FileInLibrary{}, libraryCtx, fmt.Errorf("Expected to find file '%s', but did not", path)
+}
+
 type FileInLibrary struct {
 	File            *files.File
 	Library         *Library
@@ -6,6 +6,7 @@ package workspace
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/k14s/starlark-go/starlark"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
@@ -31,6 +32,14 @@ type EvalResult struct {
 	Files   []files.OutputFile
 	DocSet  *yamlmeta.DocumentSet
 	Exports []EvalExport
+
+	templateDocSets map[int]TemplateDocSet // by index in Files
+}
+
+// TemplateDocSet holds result of a YAML template along with format of its output file.
+type TemplateDocSet struct {
+	DocSet *yamlmeta.DocumentSet
+	Format string // one of files.OutputFormats
 }
 
 type EvalExport struct {
@@ -72,6 +81,33 @@ func (ll *LibraryExecution) Schemas(overlays []*datavalues.SchemaEnvelope) (*dat
 	return spp.Apply()
 }
 
+// SchemaOverlaysFromFile evaluates `file` (typically given outside of any library, e.g. on the command line) in the
+// context of this library, producing a SchemaEnvelope for each of its (non-empty) documents, addressed to the library
+// referenced by libRef.
+//
+// The resulting overlays are expected to be given to Schemas().
+func (ll *LibraryExecution) SchemaOverlaysFromFile(file *files.File, libRef string) ([]*datavalues.SchemaEnvelope, error) {
+	loader := NewTemplateLoader(datavalues.NewEmptyEnvelope(), nil, nil, ll.templateLoaderOpts, ll.libraryExecFactory, ll.ui)
+
+	_, docSet, err := loader.EvalYAML(ll.libraryCtx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*datavalues.SchemaEnvelope
+	for _, doc := range docSet.Items {
+		if doc.IsEmpty() {
+			continue
+		}
+		schemaEnv, err := datavalues.NewSchemaEnvelopeWithLibRef(doc, libRef)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, schemaEnv)
+	}
+	return result, nil
+}
+
 // Values calculates the final Data Values for this library by combining/overlaying defaults from the schema, the Data
 // Values file(s) in the library, and the passed-in Data Values overlays. The final Data Values are validated using
 // the validations annotated on a Data Value.
@@ -180,15 +216,21 @@ func (ll *LibraryExecution) Eval(values *datavalues.Envelope, libraryValues []*d
 		return nil, err
 	}
 
-	docSets, err = (&OverlayPostProcessing{docSets: docSets}).Apply()
+	docSets, err = (&OverlayPostProcessing{
+		docSets:              docSets,
+		unmatchedOverlayFunc: ll.templateLoaderOpts.UnmatchedOverlayFunc,
+		executionLimiter:     ll.templateLoaderOpts.ExecutionLimiter,
+		profiler:             ll.templateLoaderOpts.Profiler,
+	}).Apply()
 	if err != nil {
 		return nil, err
 	}
 
 	result := &EvalResult{
-		Files:   outputFiles,
-		DocSet:  &yamlmeta.DocumentSet{},
-		Exports: exports,
+		Files:           outputFiles,
+		DocSet:          &yamlmeta.DocumentSet{},
+		Exports:         exports,
+		templateDocSets: map[int]TemplateDocSet{},
 	}
 
 	for _, fileInLib := range ll.sortedOutputDocSets(docSets) {
@@ -201,12 +243,47 @@ func (ll *LibraryExecution) Eval(values *datavalues.Envelope, libraryValues []*d
 		}
 
 		ll.ui.Debugf("### %s result\n%s", fileInLib.RelativePath(), resultDocBytes)
+
+		format, _ := fileInLib.File.OutputFormat()
+		result.templateDocSets[len(result.Files)] = TemplateDocSet{DocSet: docSet, Format: format}
+
 		result.Files = append(result.Files, files.NewOutputFile(fileInLib.RelativePath(), resultDocBytes, fileInLib.File.Type()))
 	}
 
 	return result, nil
 }
 
+// FormatFiles prints output files produced by YAML templates in their output formats, styling YAML
+// according to given opts (files are printed as YAML in default style by Eval).
+func (r *EvalResult) FormatFiles(opts yamlmeta.YAMLPrinterOpts) error {
+	for idx, file := range r.Files {
+		tplDocSet, found := r.templateDocSets[idx]
+		if !found || (tplDocSet.Format == files.OutputFormatYAML && opts.IsDefault()) {
+			continue
+		}
+
+		resultDocBytes, err := outputFileBytes(tplDocSet.DocSet, tplDocSet.Format, opts)
+		if err != nil {
+			return fmt.Errorf("Marshaling template result '%s': %s", file.RelativePath(), err)
+		}
+
+		// Documents of files in other formats are still YAML documents (e.g. included in combined output)
+		r.Files[idx] = files.NewOutputFile(OutputFormatPath(file.RelativePath(), tplDocSet.Format), resultDocBytes, file.Type())
+	}
+	return nil
+}
+
+// TemplateDocSets returns results of YAML templates in order of their output files.
+func (r *EvalResult) TemplateDocSets() []TemplateDocSet {
+	var result []TemplateDocSet
+	for idx := range r.Files {
+		if tplDocSet, found := r.templateDocSets[idx]; found {
+			result = append(result, tplDocSet)
+		}
+	}
+	return result
+}
+
 func (ll *LibraryExecution) eval(values *datavalues.Envelope, libraryValues []*datavalues.Envelope, librarySchemas []*datavalues.SchemaEnvelope) ([]EvalExport, map[*FileInLibrary]*yamlmeta.DocumentSet, []files.OutputFile, error) {
 
 	loader := NewTemplateLoader(values, libraryValues, librarySchemas, ll.templateLoaderOpts, ll.libraryExecFactory, ll.ui)
@@ -215,76 +292,179 @@ func (ll *LibraryExecution) eval(values *datavalues.Envelope, libraryValues []*d
 	docSets := map[*FileInLibrary]*yamlmeta.DocumentSet{}
 	outputFiles := []files.OutputFile{}
 
-	for _, fileInLib := range ll.libraryCtx.Current.ListAccessibleFiles() {
-		libraryCtx := LibraryExecutionContext{Current: fileInLib.Library, Root: ll.libraryCtx.Root}
-
-		switch {
-		case fileInLib.File.IsForOutput():
-			// Do not collect globals produced by templates
-			switch fileInLib.File.Type() {
-			case files.TypeYAML:
-				_, resultDocSet, err := loader.EvalYAML(libraryCtx, fileInLib.File)
-				if err != nil {
-					return nil, nil, nil, err
-				}
+	filesInLib := ll.libraryCtx.Current.ListAccessibleFiles()
 
-				docSets[fileInLib] = resultDocSet
+	results, err := ll.evalFiles(loader, filesInLib)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
-			case files.TypeText:
-				_, resultVal, err := loader.EvalText(libraryCtx, fileInLib.File)
-				if err != nil {
-					return nil, nil, nil, err
-				}
+	// Collect results in file order (regardless of evaluation order)
+	for i, result := range results {
+		if result.docSet != nil {
+			docSets[filesInLib[i]] = result.docSet
+		}
+		if result.outputFile != nil {
+			outputFiles = append(outputFiles, *result.outputFile)
+		}
+		if result.export != nil {
+			exports = append(exports, *result.export)
+		}
+	}
 
-				resultStr := resultVal.AsString()
+	return exports, docSets, outputFiles, ll.checkUnusedDVsOrSchemas(libraryValues, librarySchemas)
+}
 
-				ll.ui.Debugf("### %s result\n%s", fileInLib.RelativePath(), resultStr)
-				outputFiles = append(outputFiles, files.NewOutputFile(fileInLib.RelativePath(), []byte(resultStr), fileInLib.File.Type()))
+// fileEvalResult holds the outcome of evaluating a single file (at most one field is set).
+type fileEvalResult struct {
+	docSet     *yamlmeta.DocumentSet
+	outputFile *files.OutputFile
+	export     *EvalExport
+}
 
-			default:
-				return nil, nil, nil, fmt.Errorf("Unknown file type")
+// evalFiles evaluates files (concurrently, if configured via TemplateLoaderOpts.Parallelism).
+//
+// Templates only share read-only data values, hence they can be evaluated independently.
+// Results are in the same order as given files; if multiple files fail, error of the first one (in file order)
+// is returned so that it does not depend on evaluation order. Once a file fails, only files before it are
+// still evaluated (to find out if any of them fails as well).
+func (ll *LibraryExecution) evalFiles(loader *TemplateLoader, filesInLib []*FileInLibrary) ([]fileEvalResult, error) {
+	results := make([]fileEvalResult, len(filesInLib))
+
+	if ll.templateLoaderOpts.Parallelism <= 1 {
+		for i, fileInLib := range filesInLib {
+			var err error
+			results[i], err = ll.evalFile(loader, fileInLib)
+			if err != nil {
+				return nil, err
 			}
+		}
+		return results, nil
+	}
 
-		case fileInLib.File.IsLibrary():
-			// Collect globals produced by library files
-			var evalFunc func(LibraryExecutionContext, *files.File) (starlark.StringDict, error)
+	errs := make([]error, len(filesInLib))
+	indexes := make(chan int)
 
-			switch fileInLib.File.Type() {
-			case files.TypeYAML:
-				evalFunc = func(libraryCtx LibraryExecutionContext, file *files.File) (starlark.StringDict, error) {
-					globals, _, err := loader.EvalYAML(libraryCtx, fileInLib.File)
-					return globals, err
-				}
+	var failedIdxLock sync.Mutex
+	failedIdx := len(filesInLib) // lowest index of failed file
+
+	isAfterFailed := func(idx int) bool {
+		failedIdxLock.Lock()
+		defer failedIdxLock.Unlock()
+		return idx > failedIdx
+	}
 
-			case files.TypeText:
-				evalFunc = func(libraryCtx LibraryExecutionContext, file *files.File) (starlark.StringDict, error) {
-					globals, _, err := loader.EvalText(libraryCtx, fileInLib.File)
-					return globals, err
+	var wg sync.WaitGroup
+	for i := 0; i < ll.templateLoaderOpts.Parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indexes {
+				if isAfterFailed(idx) {
+					continue
 				}
+				results[idx], errs[idx] = ll.evalFile(loader, filesInLib[idx])
+				if errs[idx] != nil {
+					failedIdxLock.Lock()
+					if idx < failedIdx {
+						failedIdx = idx
+					}
+					failedIdxLock.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := range filesInLib {
+		if isAfterFailed(i) {
+			break
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func (ll *LibraryExecution) evalFile(loader *TemplateLoader, fileInLib *FileInLibrary) (fileEvalResult, error) {
+	libraryCtx := LibraryExecutionContext{Current: fileInLib.Library, Root: ll.libraryCtx.Root}
 
-			case files.TypeStarlark:
-				evalFunc = loader.EvalStarlark
+	switch {
+	case fileInLib.File.IsStarlarkTest():
+		// Only evaluated when running tests (see StarlarkTests)
 
-			default:
-				// TODO should we allow skipping over unknown library files?
-				// do nothing
+	case fileInLib.File.IsForOutput():
+		// Do not collect globals produced by templates
+		switch fileInLib.File.Type() {
+		case files.TypeYAML:
+			_, resultDocSet, err := loader.EvalYAML(libraryCtx, fileInLib.File)
+			if err != nil {
+				return fileEvalResult{}, err
 			}
 
-			if evalFunc != nil {
-				globals, err := evalFunc(libraryCtx, fileInLib.File)
-				if err != nil {
-					return nil, nil, nil, err
-				}
+			return fileEvalResult{docSet: resultDocSet}, nil
 
-				exports = append(exports, EvalExport{Path: fileInLib.RelativePath(), Symbols: globals})
+		case files.TypeText:
+			_, resultVal, err := loader.EvalText(libraryCtx, fileInLib.File)
+			if err != nil {
+				return fileEvalResult{}, err
 			}
 
+			resultStr := resultVal.AsString()
+
+			ll.ui.Debugf("### %s result\n%s", fileInLib.RelativePath(), resultStr)
+			outputFile := files.NewOutputFile(fileInLib.RelativePath(), []byte(resultStr), fileInLib.File.Type())
+			return fileEvalResult{outputFile: &outputFile}, nil
+
 		default:
+			return fileEvalResult{}, fmt.Errorf("Unknown file type")
+		}
+
+	case fileInLib.File.IsLibrary():
+		// Collect globals produced by library files
+		var evalFunc func(LibraryExecutionContext, *files.File) (starlark.StringDict, error)
+
+		switch fileInLib.File.Type() {
+		case files.TypeYAML:
+			evalFunc = func(libraryCtx LibraryExecutionContext, file *files.File) (starlark.StringDict, error) {
+				globals, _, err := loader.EvalYAML(libraryCtx, fileInLib.File)
+				return globals, err
+			}
+
+		case files.TypeText:
+			evalFunc = func(libraryCtx LibraryExecutionContext, file *files.File) (starlark.StringDict, error) {
+				globals, _, err := loader.EvalText(libraryCtx, fileInLib.File)
+				return globals, err
+			}
+
+		case files.TypeStarlark:
+			evalFunc = loader.EvalStarlark
+
+		default:
+			// TODO should we allow skipping over unknown library files?
 			// do nothing
 		}
+
+		if evalFunc != nil {
+			globals, err := evalFunc(libraryCtx, fileInLib.File)
+			if err != nil {
+				return fileEvalResult{}, err
+			}
+
+			return fileEvalResult{export: &EvalExport{Path: fileInLib.RelativePath(), Symbols: globals}}, nil
+		}
+
+	default:
+		// do nothing
 	}
 
-	return exports, docSets, outputFiles, ll.checkUnusedDVsOrSchemas(libraryValues, librarySchemas)
+	return fileEvalResult{}, nil
 }
 
 func (*LibraryExecution) sortedOutputDocSets(outputDocSets map[*FileInLibrary]*yamlmeta.DocumentSet) []*FileInLibrary {
@@ -4,7 +4,11 @@
 package workspace
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
 )
 
 // LibraryExecutionContext holds the total set of inputs that are involved in a LibraryExecution.
@@ -13,6 +17,57 @@ type LibraryExecutionContext struct {
 	Root    *Library // reference to the root library to support accessing "absolute path" loading of files.
 }
 
+const yttLibraryModulePrefix = "@ytt:"
+
+// FindLoadableFile resolves module path (as given to load(...)) to a library file,
+// returning it together with the context in which it should be evaluated.
+// Builtin ytt library modules (e.g. '@ytt:base64') are not files and are not resolved here.
+func (ctx LibraryExecutionContext) FindLoadableFile(module string) (FileInLibrary, LibraryExecutionContext, error) {
+	libraryCtx := ctx
+	filePath := module
+
+	if strings.HasPrefix(module, "@") {
+		pieces := strings.SplitN(module[1:], ":", 2)
+		if len(pieces) != 2 {
+			return FileInLibrary{}, ctx, fmt.Errorf("Expected library path to be in format '@name:path' " +
+				"e.g. '@github.com/vmware-tanzu/test:test.star' or '@ytt:base64'")
+		}
+
+		if pieces[0] == "ytt" {
+			return FileInLibrary{}, ctx, fmt.Errorf("Expected '%s' to be a file, but was builtin ytt library module", module)
+		}
+
+		foundLib, err := libraryCtx.Current.FindAccessibleLibrary(pieces[0])
+		if err != nil {
+			return FileInLibrary{}, ctx, err
+		}
+
+		libraryCtx = LibraryExecutionContext{Current: foundLib, Root: foundLib}
+		filePath = pieces[1]
+	}
+
+	var libraryWithFile *Library = libraryCtx.Current
+
+	// If path starts from a root, then it should be relative to root library
+	if files.IsRootPath(filePath) {
+		libraryWithFile = libraryCtx.Root
+		filePath = files.StripRootPath(filePath)
+	}
+
+	fileInLib, err := libraryWithFile.FindFile(filePath)
+	if err != nil {
+		return FileInLibrary{}, ctx, err
+	}
+
+	if !fileInLib.File.IsLibrary() {
+		return FileInLibrary{}, ctx, fmt.Errorf("Expected file '%s' to be a library file, but was not "+
+			"(hint: library filename must end with '.lib.yml' or '.star'; use data.read(...) for loading non-templated file contents)", fileInLib.File.RelativePath())
+	}
+
+	// File might be inside nested libraries, make sure to update current library
+	return fileInLib, LibraryExecutionContext{Current: fileInLib.Library, Root: libraryCtx.Root}, nil
+}
+
 // LibraryExecutionFactory holds configuration for and produces instances of LibraryExecution's.
 type LibraryExecutionFactory struct {
 	ui                 ui.UI
@@ -0,0 +1,68 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package workspace
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/yamlmeta"
+)
+
+// OutputFormatPrinter returns printer of documents in given format (one of files.OutputFormats);
+// yamlOpts style YAML format.
+func OutputFormatPrinter(format string, yamlOpts yamlmeta.YAMLPrinterOpts) (func(io.Writer) yamlmeta.DocumentPrinter, error) {
+	switch format {
+	case files.OutputFormatYAML:
+		return func(w io.Writer) yamlmeta.DocumentPrinter { return yamlmeta.NewYAMLPrinterWithOpts(w, yamlOpts) }, nil
+	case files.OutputFormatJSON:
+		return func(w io.Writer) yamlmeta.DocumentPrinter { return yamlmeta.NewJSONPrinter(w) }, nil
+	case files.OutputFormatJSONPretty:
+		return func(w io.Writer) yamlmeta.DocumentPrinter { return yamlmeta.NewPrettyJSONPrinter(w) }, nil
+	case files.OutputFormatJSONLines:
+		return func(w io.Writer) yamlmeta.DocumentPrinter { return yamlmeta.NewJSONLinesPrinter(w) }, nil
+	default:
+		return nil, fmt.Errorf("Unknown output format '%s' (expected one of: %s)", format, strings.Join(files.OutputFormats, ", "))
+	}
+}
+
+// OutputFormatPath replaces YAML extension of relPath with one matching format.
+func OutputFormatPath(relPath, format string) string {
+	var ext string
+	switch format {
+	case files.OutputFormatJSON, files.OutputFormatJSONPretty:
+		ext = ".json"
+	case files.OutputFormatJSONLines:
+		ext = ".jsonl"
+	default:
+		return relPath
+	}
+
+	for _, yamlExt := range []string{".yml", ".yaml"} {
+		if strings.HasSuffix(relPath, yamlExt) {
+			return strings.TrimSuffix(relPath, yamlExt) + ext
+		}
+	}
+	return relPath + ext
+}
+
+// outputFileBytes prints docSet in given format (yamlOpts style YAML format); JSON formats (other than JSON Lines)
+// can only represent a single document.
+func outputFileBytes(docSet *yamlmeta.DocumentSet, format string, yamlOpts yamlmeta.YAMLPrinterOpts) ([]byte, error) {
+	printerFunc, err := OutputFormatPrinter(format, yamlOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == files.OutputFormatJSON || format == files.OutputFormatJSONPretty {
+		if numDocs := len(docSet.PrintedItems()); numDocs > 1 {
+			return nil, fmt.Errorf("Expected single document to output in format '%s', but found %d "+
+				"(hint: use '%s' format, or split documents via --output-split)", format, numDocs, files.OutputFormatJSONLines)
+		}
+	}
+
+	return docSet.AsBytesWithPrinter(printerFunc)
+}
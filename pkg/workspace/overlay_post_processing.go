@@ -14,7 +14,10 @@ import (
 )
 
 type OverlayPostProcessing struct {
-	docSets map[*FileInLibrary]*yamlmeta.DocumentSet
+	docSets              map[*FileInLibrary]*yamlmeta.DocumentSet
+	unmatchedOverlayFunc yttoverlay.UnmatchedDocumentFunc
+	executionLimiter     *template.ExecutionLimiter
+	profiler             *template.Profiler
 }
 
 func (o OverlayPostProcessing) Apply() (map[*FileInLibrary]*yamlmeta.DocumentSet, error) {
@@ -52,15 +55,29 @@ func (o OverlayPostProcessing) Apply() (map[*FileInLibrary]*yamlmeta.DocumentSet
 
 	for _, file := range sortedOverlayFiles {
 		for _, overlay := range overlayDocSets[file] {
+			thread := &starlark.Thread{Name: "overlay-post-processing"}
+			if o.unmatchedOverlayFunc != nil {
+				yttoverlay.SetUnmatchedDocumentFunc(thread, o.unmatchedOverlayFunc)
+			}
+			if o.executionLimiter != nil {
+				// matchers (e.g. lambdas) are evaluated on this thread
+				template.SetExecutionLimiter(thread, o.executionLimiter)
+			}
+			if o.profiler != nil {
+				template.SetProfiler(thread, o.profiler)
+			}
+
 			op := yttoverlay.Op{
 				// special case: array of docsets so that file association can be preserved
 				Left: docSetsWithoutOverlays,
 				Right: &yamlmeta.DocumentSet{
 					Items: []*yamlmeta.Document{overlay},
 				},
-				Thread: &starlark.Thread{Name: "overlay-post-processing"},
+				Thread: thread,
 			}
+			endMeasure := o.profiler.Measure(template.ProfileCategoryOverlay, o.overlayDesc(file, overlay))
 			newLeft, err := op.Apply()
+			endMeasure()
 			if err != nil {
 				return nil, fmt.Errorf("Overlaying (in following order: %s): %s",
 					o.allFileDescs(sortedOverlayFiles), err)
@@ -89,3 +106,7 @@ func (o OverlayPostProcessing) allFileDescs(files []*FileInLibrary) string {
 	}
 	return strings.Join(result, ", ")
 }
+
+func (o OverlayPostProcessing) overlayDesc(file *FileInLibrary, overlay *yamlmeta.Document) string {
+	return fmt.Sprintf("%s:%d", file.File.RelativePath(), overlay.Position.LineNum())
+}
@@ -0,0 +1,178 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package workspace
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/syntax"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/files"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/template"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace/datavalues"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/yttlibrary"
+)
+
+const (
+	replFileName = "<repl>"
+)
+
+// REPL evaluates Starlark code interactively in the context of a library:
+// its data values and all @ytt: modules are predeclared, and load(...) resolves files the same way templates do.
+//
+// Globals defined by evaluated code are retained between evaluations.
+type REPL struct {
+	loader  *TemplateLoader
+	thread  *starlark.Thread
+	globals starlark.StringDict
+}
+
+// REPL returns a new REPL for this library using given data values (typically calculated via Values()).
+func (ll *LibraryExecution) REPL(values *datavalues.Envelope, libraryValues []*datavalues.Envelope, librarySchemas []*datavalues.SchemaEnvelope) *REPL {
+	loader := NewTemplateLoader(values, libraryValues, librarySchemas, ll.templateLoaderOpts, ll.libraryExecFactory, ll.ui)
+
+	// Code is not known upfront, hence compiled template only provides access to @ytt:template functions
+	instructions := template.NewInstructionSet()
+	compiledTemplate := template.NewCompiledTemplate(replFileName, nil, instructions, template.NewNodes(), template.EvaluationCtxDialects{})
+	loader.addCompiledTemplate(replFileName, compiledTemplate)
+
+	yttLibrary := yttlibrary.NewAPI(compiledTemplate.TplReplaceNode,
+		yttlibrary.NewDataModule(loader.values.Doc, DataLoader{ll.libraryCtx}),
+		NewLibraryModule(ll.libraryCtx, loader.libraryExecFactory, loader.libraryValuess, loader.librarySchemas).AsModule())
+
+	globals := starlark.StringDict{}
+	for _, name := range yttLibrary.ModuleNames() {
+		module, err := yttLibrary.FindModule(name)
+		if err != nil {
+			panic(fmt.Sprintf("Expected to find ytt library module '%s': %s", name, err))
+		}
+		for symbol, val := range module {
+			globals[symbol] = val
+		}
+	}
+
+	thread := loader.newThread(ll.libraryCtx, yttLibrary, files.MustNewFileFromSource(files.NewBytesSource(replFileName, nil)))
+
+	return &REPL{loader: loader, thread: thread, globals: globals}
+}
+
+// Eval parses and evaluates a single statement (possibly spanning multiple lines, e.g. a function definition)
+// read via readline. Returns value of an expression, or None if statement is not an expression.
+//
+// Returns an error from readline as is (e.g. io.EOF when there is no more input).
+func (r *REPL) Eval(readline func() ([]byte, error)) (starlark.Value, error) {
+	var readlineErr error
+	var src []byte
+	var blockEnded bool
+
+	f, err := r.parse(func() ([]byte, error) {
+		if blockEnded {
+			// Parser looks ahead past closing 'end'; treating it as end of input
+			// avoids consuming (and losing) the next statement
+			return nil, nil
+		}
+		line, err := readline()
+		if err != nil {
+			readlineErr = err
+			return line, err
+		}
+		src = append(src, line...)
+		blockEnded = r.isBlockEnd(line, src)
+		return line, nil
+	})
+	if readlineErr != nil {
+		return nil, readlineErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(f.Stmts) == 1 {
+		if stmt, ok := f.Stmts[0].(*syntax.ExprStmt); ok {
+			return starlark.EvalExpr(r.thread, stmt.X, r.globals)
+		}
+	}
+
+	// Loaded symbols are bound to globals (instead of being file local)
+	// so that they are available in subsequent evaluations
+	var stmts []syntax.Stmt
+	for _, stmt := range f.Stmts {
+		if loadStmt, ok := stmt.(*syntax.LoadStmt); ok {
+			err := r.load(loadStmt)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			stmts = append(stmts, stmt)
+		}
+	}
+	f.Stmts = stmts
+
+	prog, err := starlark.FileProgram(f, r.globals.Has)
+	if err != nil {
+		return nil, err
+	}
+
+	// Globals are not frozen so that they could be modified in subsequent evaluations
+	updatedGlobals, err := prog.Init(r.thread, r.globals)
+	for name, val := range updatedGlobals {
+		r.globals[name] = val
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return starlark.None, nil
+}
+
+func (r *REPL) parse(readline func() ([]byte, error)) (f *syntax.File, resultErr error) {
+	// Parser with if/end syntax does not recover from syntax errors itself
+	defer func() {
+		if err := recover(); err != nil {
+			if typedErr, ok := err.(syntax.Error); ok {
+				resultErr = typedErr
+			} else {
+				resultErr = fmt.Errorf("(p) %s", err)
+			}
+		}
+	}()
+
+	return syntax.ParseCompoundStmt(replFileName, readline)
+}
+
+// isBlockEnd returns true if line closes the outermost block of src.
+func (r *REPL) isBlockEnd(line, src []byte) (result bool) {
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 || fields[0] != "end" {
+		return false
+	}
+
+	defer func() {
+		if err := recover(); err != nil {
+			result = false
+		}
+	}()
+
+	_, err := syntax.Parse(replFileName, src, syntax.BlockScanner)
+	return err == nil
+}
+
+func (r *REPL) load(stmt *syntax.LoadStmt) error {
+	module := stmt.Module.Value.(string)
+
+	symbols, err := r.loader.Load(r.thread, module)
+	if err != nil {
+		return fmt.Errorf("cannot load %s: %s", module, err)
+	}
+
+	for i, from := range stmt.From {
+		val, found := symbols[from.Name]
+		if !found {
+			return fmt.Errorf("load: name %s not found in module %s", from.Name, module)
+		}
+		r.globals[stmt.To[i].Name] = val
+	}
+	return nil
+}
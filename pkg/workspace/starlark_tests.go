@@ -0,0 +1,69 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package workspace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/workspace/datavalues"
+)
+
+const (
+	starlarkTestFuncPrefix = "test_"
+)
+
+// StarlarkTestResult holds outcome of a single test function (i.e. test_*) defined in a *_test.star file.
+type StarlarkTestResult struct {
+	Path string
+	// Name is empty when test file itself failed to evaluate
+	Name string
+	// Err is nil when test passed
+	Err error
+}
+
+// StarlarkTests evaluates each *_test.star file accessible to this library and calls
+// its test_* functions (without arguments), in order of their names.
+//
+// Failures of individual tests, and of test files that fail to evaluate, are reported via results.
+func (ll *LibraryExecution) StarlarkTests(values *datavalues.Envelope, libraryValues []*datavalues.Envelope, librarySchemas []*datavalues.SchemaEnvelope) ([]StarlarkTestResult, error) {
+	loader := NewTemplateLoader(values, libraryValues, librarySchemas, ll.templateLoaderOpts, ll.libraryExecFactory, ll.ui)
+
+	var results []StarlarkTestResult
+
+	for _, fileInLib := range ll.libraryCtx.Current.ListAccessibleFiles() {
+		if !fileInLib.File.IsStarlarkTest() {
+			continue
+		}
+
+		libraryCtx := LibraryExecutionContext{Current: fileInLib.Library, Root: ll.libraryCtx.Root}
+
+		globals, thread, err := loader.evalStarlark(libraryCtx, fileInLib.File)
+		if err != nil {
+			results = append(results, StarlarkTestResult{Path: fileInLib.RelativePath(), Err: fmt.Errorf("Evaluating tests: %s", err)})
+			continue
+		}
+
+		var names []string
+		for name, val := range globals {
+			if _, ok := val.(starlark.Callable); ok && strings.HasPrefix(name, starlarkTestFuncPrefix) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			_, err := starlark.Call(thread, globals[name], nil, nil)
+			if evalErr, ok := err.(*starlark.EvalError); ok {
+				// include location of the failure
+				err = fmt.Errorf("%s", evalErr.Backtrace())
+			}
+			results = append(results, StarlarkTestResult{Path: fileInLib.RelativePath(), Name: name, Err: err})
+		}
+	}
+
+	return results, nil
+}
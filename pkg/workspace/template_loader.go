@@ -6,6 +6,7 @@ package workspace
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/k14s/starlark-go/starlark"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/cmd/ui"
@@ -16,6 +17,7 @@ import (
 	"github.com/vmware-tanzu/carvel-ytt/pkg/yamlmeta"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/yamltemplate"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/yttlibrary"
+	yttoverlay "github.com/vmware-tanzu/carvel-ytt/pkg/yttlibrary/overlay"
 )
 
 type TemplateLoader struct {
@@ -24,8 +26,11 @@ type TemplateLoader struct {
 	libraryValuess     []*datavalues.Envelope
 	librarySchemas     []*datavalues.SchemaEnvelope
 	opts               TemplateLoaderOpts
-	compiledTemplates  map[string]*template.CompiledTemplate
 	libraryExecFactory *LibraryExecutionFactory
+
+	// templates may be evaluated concurrently (see TemplateLoaderOpts.Parallelism)
+	compiledTemplatesLock sync.Mutex
+	compiledTemplates     map[string]*template.CompiledTemplate
 }
 
 // TemplateLoaderOpts holds configuration options that adjust how each individual template is executed/evaluated.
@@ -33,6 +38,18 @@ type TemplateLoaderOpts struct {
 	IgnoreUnknownComments   bool
 	ImplicitMapKeyOverrides bool
 	StrictYAML              bool
+	// UnmatchedOverlayFunc, if set, is notified of overlay documents that had no effect (used for linting)
+	UnmatchedOverlayFunc yttoverlay.UnmatchedDocumentFunc
+	// ExecutionLimiter, if set, bounds evaluation of all templates (shared by all libraries)
+	ExecutionLimiter *template.ExecutionLimiter
+	// Profiler, if set, collects time spent evaluating templates, functions, overlays and data values
+	Profiler *template.Profiler
+	// Debugger, if set, is notified before template statements are evaluated (requires sequential evaluation)
+	Debugger template.Debugger
+	// Parallelism limits number of templates within a library evaluated concurrently (0 or 1 means sequentially)
+	Parallelism int
+	// CompiledTemplateCache, if set, is used to skip parsing and compilation of unchanged YAML files
+	CompiledTemplateCache *CompiledTemplateCache
 }
 
 // TemplateLoaderOptsOverrides hold potential overriding values to be merged over a TemplateLoaderOpts.
@@ -62,6 +79,9 @@ func NewTemplateLoader(values *datavalues.Envelope, libraryValuess []*datavalues
 }
 
 func (l *TemplateLoader) FindCompiledTemplate(path string) (*template.CompiledTemplate, error) {
+	l.compiledTemplatesLock.Lock()
+	defer l.compiledTemplatesLock.Unlock()
+
 	ct, found := l.compiledTemplates[path]
 	if !found {
 		return nil, fmt.Errorf("Expected to find '%s' compiled template", path)
@@ -74,50 +94,18 @@ func (l *TemplateLoader) Load(thread *starlark.Thread, module string) (starlark.
 		Current: l.getCurrentLibrary(thread),
 		Root:    l.getRootLibrary(thread),
 	}
-	filePath := module
-
-	if strings.HasPrefix(module, "@") {
-		pieces := strings.SplitN(module[1:], ":", 2)
-		if len(pieces) != 2 {
-			return nil, fmt.Errorf("Expected library path to be in format '@name:path' " +
-				"e.g. '@github.com/vmware-tanzu/test:test.star' or '@ytt:base64'")
-		}
-
-		if pieces[0] == "ytt" {
-			return l.getYTTLibrary(thread).FindModule(pieces[1])
-		}
-
-		foundLib, err := libraryCtx.Current.FindAccessibleLibrary(pieces[0])
-		if err != nil {
-			return nil, err
-		}
-
-		libraryCtx = LibraryExecutionContext{Current: foundLib, Root: foundLib}
-		filePath = pieces[1]
-	}
 
-	var libraryWithFile *Library = libraryCtx.Current
-
-	// If path starts from a root, then it should be relative to root library
-	if files.IsRootPath(filePath) {
-		libraryWithFile = libraryCtx.Root
-		filePath = files.StripRootPath(filePath)
+	if strings.HasPrefix(module, yttLibraryModulePrefix) {
+		return l.getYTTLibrary(thread).FindModule(strings.TrimPrefix(module, yttLibraryModulePrefix))
 	}
 
-	fileInLib, err := libraryWithFile.FindFile(filePath)
+	fileInLib, libraryCtx, err := libraryCtx.FindLoadableFile(module)
 	if err != nil {
 		return nil, err
 	}
 
-	// File might be inside nested libraries, make sure to update current library
-	libraryCtx = LibraryExecutionContext{Current: fileInLib.Library, Root: libraryCtx.Root}
 	file := fileInLib.File
 
-	if !file.IsLibrary() {
-		return nil, fmt.Errorf("Expected file '%s' to be a library file, but was not "+
-			"(hint: library filename must end with '.lib.yml' or '.star'; use data.read(...) for loading non-templated file contents)", file.RelativePath())
-	}
-
 	switch file.Type() {
 	case files.TypeYAML:
 		globals, _, err := l.EvalYAML(libraryCtx, file)
@@ -148,15 +136,87 @@ func (l *TemplateLoader) EvalPlainYAML(file *files.File) (*yamlmeta.DocumentSet,
 	}
 	l.ui.Debugf("## file %s (opts %#v)\n", file.RelativePath(), docSetOpts)
 
+	cacheKey := l.opts.CompiledTemplateCache.key(compiledTemplateCacheKindDocSet,
+		file.RelativePath(), fileBs, docSetOpts.WithoutComments, docSetOpts.Strict)
+
+	var cached cachedDocSet
+	if l.getCached(cacheKey, &cached) {
+		node, err := cached.DocSet.AsNode()
+		if docSet, ok := node.(*yamlmeta.DocumentSet); err == nil && ok {
+			return docSet, nil
+		}
+		l.ui.Debugf("### cache: unexpected entry for '%s' (error: %v)\n", file.RelativePath(), err)
+	}
+
 	docSet, err := yamlmeta.NewDocumentSetFromBytes(fileBs, docSetOpts)
 	if err != nil {
 		return nil, fmt.Errorf("Unmarshaling YAML template '%s': %s", file.RelativePath(), err)
 	}
 
+	l.putCached(cacheKey, func() (interface{}, error) {
+		return cachedDocSet{yamlmeta.NewSerializableNode(docSet)}, nil
+	})
+
 	return docSet, nil
 }
 
 func (l *TemplateLoader) EvalYAML(libraryCtx LibraryExecutionContext, file *files.File) (starlark.StringDict, *yamlmeta.DocumentSet, error) {
+	docSet, compiledTemplate, err := l.compileYAML(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	if compiledTemplate == nil {
+		return nil, docSet, nil
+	}
+
+	l.addCompiledTemplate(file.RelativePath(), compiledTemplate)
+	l.ui.Debugf("### template\n%s", compiledTemplate.DebugCodeAsString())
+
+	yttLibrary := yttlibrary.NewAPI(compiledTemplate.TplReplaceNode,
+		yttlibrary.NewDataModule(l.values.Doc, DataLoader{libraryCtx}),
+		NewLibraryModule(libraryCtx, l.libraryExecFactory, l.libraryValuess, l.librarySchemas).AsModule())
+
+	thread := l.newThread(libraryCtx, yttLibrary, file)
+
+	endMeasure := l.opts.Profiler.Measure(template.ProfileCategoryTemplate, file.RelativePath())
+	globals, resultVal, err := compiledTemplate.Eval(thread, l)
+	endMeasure()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return globals, resultVal.(*yamlmeta.DocumentSet), nil
+}
+
+// compileYAML returns either a plain document set or a compiled template (if file is templated).
+func (l *TemplateLoader) compileYAML(file *files.File) (*yamlmeta.DocumentSet, *template.CompiledTemplate, error) {
+	tplOpts := yamltemplate.TemplateOpts{
+		IgnoreUnknownComments:   l.opts.IgnoreUnknownComments,
+		ImplicitMapKeyOverrides: l.opts.ImplicitMapKeyOverrides,
+	}
+
+	var cacheKey string
+
+	if file.IsTemplate() || file.IsLibrary() {
+		fileBs, err := file.Bytes()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		cacheKey = l.opts.CompiledTemplateCache.key(compiledTemplateCacheKindTemplate, file.RelativePath(),
+			fileBs, l.opts.StrictYAML, tplOpts.IgnoreUnknownComments, tplOpts.ImplicitMapKeyOverrides)
+
+		var cached cachedCompiledTemplate
+		if l.getCached(cacheKey, &cached) {
+			compiledTemplate, err := yamltemplate.NewTemplate(file.RelativePath(), tplOpts).CompileFromSerializable(cached.Template)
+			if err == nil {
+				l.ui.Debugf("## file %s (cached)\n", file.RelativePath())
+				return nil, compiledTemplate, nil
+			}
+			l.ui.Debugf("### cache: unexpected entry for '%s' (error: %s)\n", file.RelativePath(), err)
+		}
+	}
+
 	docSet, err := l.EvalPlainYAML(file)
 	if err != nil {
 		return nil, nil, err
@@ -173,37 +233,45 @@ func (l *TemplateLoader) EvalYAML(libraryCtx LibraryExecutionContext, file *file
 		l.ui.Debugf("(plain)\n")
 		docSet.Print(l.ui.DebugWriter())
 
-		return nil, docSet, nil
+		return docSet, nil, nil
 	}
 
 	l.ui.Debugf("(templated)\n")
 	docSet.Print(l.ui.DebugWriter())
 
-	tplOpts := yamltemplate.TemplateOpts{
-		IgnoreUnknownComments:   l.opts.IgnoreUnknownComments,
-		ImplicitMapKeyOverrides: l.opts.ImplicitMapKeyOverrides,
-	}
-
 	compiledTemplate, err := yamltemplate.NewTemplate(file.RelativePath(), tplOpts).Compile(docSet)
 	if err != nil {
 		return nil, nil, fmt.Errorf("Compiling YAML template '%s': %s", file.RelativePath(), err)
 	}
 
-	l.addCompiledTemplate(file.RelativePath(), compiledTemplate)
-	l.ui.Debugf("### template\n%s", compiledTemplate.DebugCodeAsString())
-
-	yttLibrary := yttlibrary.NewAPI(compiledTemplate.TplReplaceNode,
-		yttlibrary.NewDataModule(l.values.Doc, DataLoader{libraryCtx}),
-		NewLibraryModule(libraryCtx, l.libraryExecFactory, l.libraryValuess, l.librarySchemas).AsModule())
+	l.putCached(cacheKey, func() (interface{}, error) {
+		serializable, err := yamltemplate.NewSerializableCompiledTemplate(compiledTemplate)
+		return cachedCompiledTemplate{serializable}, err
+	})
 
-	thread := l.newThread(libraryCtx, yttLibrary, file)
+	return nil, compiledTemplate, nil
+}
 
-	globals, resultVal, err := compiledTemplate.Eval(thread, l)
+func (l *TemplateLoader) getCached(key string, val interface{}) bool {
+	found, err := l.opts.CompiledTemplateCache.get(key, val)
 	if err != nil {
-		return nil, nil, err
+		// Unreadable entries are treated as missing (they are overwritten)
+		l.ui.Debugf("### cache: %s\n", err)
 	}
+	return found
+}
 
-	return globals, resultVal.(*yamlmeta.DocumentSet), nil
+func (l *TemplateLoader) putCached(key string, valFunc func() (interface{}, error)) {
+	if l.opts.CompiledTemplateCache == nil {
+		return
+	}
+	val, err := valFunc()
+	if err == nil {
+		err = l.opts.CompiledTemplateCache.put(key, val)
+	}
+	if err != nil {
+		l.ui.Debugf("### cache: %s\n", err)
+	}
 }
 
 func (l *TemplateLoader) EvalText(libraryCtx LibraryExecutionContext, file *files.File) (starlark.StringDict, *texttemplate.NodeRoot, error) {
@@ -240,7 +308,9 @@ func (l *TemplateLoader) EvalText(libraryCtx LibraryExecutionContext, file *file
 
 	thread := l.newThread(libraryCtx, yttLibrary, file)
 
+	endMeasure := l.opts.Profiler.Measure(template.ProfileCategoryTemplate, file.RelativePath())
 	globals, resultVal, err := compiledTemplate.Eval(thread, l)
+	endMeasure()
 	if err != nil {
 		return nil, nil, fmt.Errorf("Evaluating text template: %s", err)
 	}
@@ -249,9 +319,16 @@ func (l *TemplateLoader) EvalText(libraryCtx LibraryExecutionContext, file *file
 }
 
 func (l *TemplateLoader) EvalStarlark(libraryCtx LibraryExecutionContext, file *files.File) (starlark.StringDict, error) {
+	globals, _, err := l.evalStarlark(libraryCtx, file)
+	return globals, err
+}
+
+// evalStarlark is like EvalStarlark, also returning thread that was used for evaluation
+// (e.g. to later call functions defined in the file).
+func (l *TemplateLoader) evalStarlark(libraryCtx LibraryExecutionContext, file *files.File) (starlark.StringDict, *starlark.Thread, error) {
 	fileBs, err := file.Bytes()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	l.ui.Debugf("## file %s\n", file.RelativePath())
@@ -270,12 +347,14 @@ func (l *TemplateLoader) EvalStarlark(libraryCtx LibraryExecutionContext, file *
 
 	thread := l.newThread(libraryCtx, yttLibrary, file)
 
+	endMeasure := l.opts.Profiler.Measure(template.ProfileCategoryTemplate, file.RelativePath())
 	globals, _, err := compiledTemplate.Eval(thread, l)
+	endMeasure()
 	if err != nil {
-		return nil, fmt.Errorf("Evaluating starlark template: %s", err)
+		return nil, nil, fmt.Errorf("Evaluating starlark template: %s", err)
 	}
 
-	return globals, nil
+	return globals, thread, nil
 }
 
 const (
@@ -327,10 +406,25 @@ func (l *TemplateLoader) newThread(libraryCtx LibraryExecutionContext,
 	l.setCurrentLibrary(thread, libraryCtx.Current)
 	l.setRootLibrary(thread, libraryCtx.Root)
 	l.setYTTLibrary(thread, yttLibrary)
+	if l.opts.UnmatchedOverlayFunc != nil {
+		yttoverlay.SetUnmatchedDocumentFunc(thread, l.opts.UnmatchedOverlayFunc)
+	}
+	if l.opts.ExecutionLimiter != nil {
+		template.SetExecutionLimiter(thread, l.opts.ExecutionLimiter)
+	}
+	if l.opts.Profiler != nil {
+		template.SetProfiler(thread, l.opts.Profiler)
+	}
+	if l.opts.Debugger != nil {
+		template.SetDebugger(thread, l.opts.Debugger)
+	}
 	return thread
 }
 
 func (l *TemplateLoader) addCompiledTemplate(path string, ct *template.CompiledTemplate) {
+	l.compiledTemplatesLock.Lock()
+	defer l.compiledTemplatesLock.Unlock()
+
 	l.compiledTemplates[path] = ct
 }
 
@@ -25,6 +25,15 @@ func (d *Document) AsYAMLBytes() ([]byte, error) {
 	return yaml.Marshal(convertToLowYAML(convertToGo(d.Value)))
 }
 
+// AsYAMLBytesWithOpts marshals document styled according to given opts.
+func (d *Document) AsYAMLBytesWithOpts(opts YAMLPrinterOpts) ([]byte, error) {
+	val := convertToLowYAML(convertToGo(d.Value))
+	if opts.SortKeys {
+		val = sortLowYAMLKeys(val)
+	}
+	return yaml.MarshalWithOpts(val, opts.encoderOpts())
+}
+
 func (d *Document) AsInterface() interface{} {
 	return convertToGo(d.Value)
 }
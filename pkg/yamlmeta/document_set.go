@@ -51,14 +51,23 @@ func (ds *DocumentSet) AsBytesWithPrinter(printerFunc func(io.Writer) DocumentPr
 	buf := new(bytes.Buffer)
 	printer := printerFunc(buf)
 
+	for _, item := range ds.PrintedItems() {
+		printer.Print(item)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// PrintedItems returns documents that are printed (i.e. neither injected nor empty).
+func (ds *DocumentSet) PrintedItems() []*Document {
+	var result []*Document
 	for _, item := range ds.Items {
 		if item.injected || item.IsEmpty() {
 			continue
 		}
-		printer.Print(item)
+		result = append(result, item)
 	}
-
-	return buf.Bytes(), nil
+	return result
 }
 
 // OverrideMapKeys within any contained Map, where there is more than one MapItem with the same key, delete all but the last.
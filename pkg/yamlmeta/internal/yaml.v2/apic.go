@@ -90,6 +90,8 @@ func yamlEmitterInitialize(emitter *yamlEmitterT) {
 		states:    make([]yamlEmitterStateT, 0, initialStackSize),
 		events:    make([]yamlEventT, 0, initialQueueSize),
 		bestWidth: -1,
+
+		quotedStyle: yamlSingleQuotedScalarStyle,
 	}
 }
 
@@ -163,6 +165,16 @@ func yamlEmitterSetUnicode(emitter *yamlEmitterT, unicode bool) {
 	emitter.unicode = unicode
 }
 
+// Set if block sequences that are mapping values are indented (instead of being indentless).
+func yamlEmitterSetIndentSequences(emitter *yamlEmitterT, indentSequences bool) {
+	emitter.indentSequences = indentSequences
+}
+
+// Set the style of plain scalars that cannot be emitted plain (single or double quoted).
+func yamlEmitterSetQuotedStyle(emitter *yamlEmitterT, style yamlScalarStyleT) {
+	emitter.quotedStyle = style
+}
+
 // Set the preferred line break character.
 func yamlEmitterSetBreak(emitter *yamlEmitterT, lineBreak yamlBreakT) {
 	emitter.lineBreak = lineBreak
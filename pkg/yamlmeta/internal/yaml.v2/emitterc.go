@@ -581,7 +581,7 @@ func yamlEmitterEmitFlowMappingValue(emitter *yamlEmitterT, event *yamlEventT, s
 // Expect a block item node.
 func yamlEmitterEmitBlockSequenceItem(emitter *yamlEmitterT, event *yamlEventT, first bool) bool {
 	if first {
-		if !yamlEmitterIncreaseIndent(emitter, false, emitter.mappingContext && !emitter.indention) {
+		if !yamlEmitterIncreaseIndent(emitter, false, emitter.mappingContext && !emitter.indention && !emitter.indentSequences) {
 			return false
 		}
 	}
@@ -819,13 +819,13 @@ func yamlEmitterSelectScalarStyle(emitter *yamlEmitterT, event *yamlEventT) bool
 	if style == yamlPlainScalarStyle {
 		if emitter.flowLevel > 0 && !emitter.scalarData.flowPlainAllowed ||
 			emitter.flowLevel == 0 && !emitter.scalarData.blockPlainAllowed {
-			style = yamlSingleQuotedScalarStyle
+			style = emitter.quotedStyle
 		}
 		if len(emitter.scalarData.value) == 0 && (emitter.flowLevel > 0 || emitter.simpleKeyContext) {
-			style = yamlSingleQuotedScalarStyle
+			style = emitter.quotedStyle
 		}
 		if noTag && !event.implicit {
-			style = yamlSingleQuotedScalarStyle
+			style = emitter.quotedStyle
 		}
 	}
 	if style == yamlSingleQuotedScalarStyle {
@@ -34,6 +34,9 @@ type encoder struct {
 	event   yamlEventT
 	out     []byte
 	flow    bool
+	// singleQuoted holds whether strings that cannot be plain
+	// are single (instead of double) quoted.
+	singleQuoted bool
 	// doneInit holds whether the initial stream_start_event has been
 	// emitted.
 	doneInit bool
@@ -331,6 +334,9 @@ func (e *encoder) stringv(tag string, in reflect.Value) {
 		style = yamlLiteralScalarStyle
 	case canUsePlain:
 		style = yamlPlainScalarStyle
+	case e.singleQuoted:
+		// emitter falls back to double quotes if value cannot be single quoted
+		style = yamlSingleQuotedScalarStyle
 	default:
 		style = yamlDoubleQuotedScalarStyle
 	}
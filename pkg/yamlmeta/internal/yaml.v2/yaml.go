@@ -265,6 +265,59 @@ func Marshal(in interface{}) (out []byte, err error) {
 	return
 }
 
+// EncoderOpts controls style of YAML produced by MarshalWithOpts.
+type EncoderOpts struct {
+	// Indent is the number of spaces used for each indentation level (2-9; defaults to 2).
+	Indent int
+	// IndentSequences indents block sequences that are mapping values
+	// (by default such sequences are at the same level as their key).
+	IndentSequences bool
+	// Width is the preferred line width after which long strings are folded
+	// (non-positive values disable folding).
+	Width int
+	// Quotes selects quotes used for strings that cannot be plain.
+	Quotes QuoteStyle
+}
+
+// QuoteStyle selects quotes used for strings that cannot be plain.
+type QuoteStyle int
+
+const (
+	// DefaultQuotes double quotes strings that would resolve to other types
+	// (e.g. "123") and single quotes strings that contain indicators (e.g. 'a: b').
+	DefaultQuotes QuoteStyle = iota
+	// DoubleQuotes double quotes all strings that cannot be plain.
+	DoubleQuotes
+	// SingleQuotes single quotes all strings that cannot be plain,
+	// falling back to double quotes for strings that cannot be single quoted.
+	SingleQuotes
+)
+
+// MarshalWithOpts is like Marshal but allows to configure output style.
+func MarshalWithOpts(in interface{}, opts EncoderOpts) (out []byte, err error) {
+	defer handleErr(&err)
+	e := newEncoder()
+	defer e.destroy()
+	if opts.Indent > 0 {
+		yamlEmitterSetIndent(&e.emitter, opts.Indent)
+	}
+	if opts.Width > 0 {
+		yamlEmitterSetWidth(&e.emitter, opts.Width)
+	}
+	yamlEmitterSetIndentSequences(&e.emitter, opts.IndentSequences)
+	switch opts.Quotes {
+	case DoubleQuotes:
+		yamlEmitterSetQuotedStyle(&e.emitter, yamlDoubleQuotedScalarStyle)
+	case SingleQuotes:
+		e.singleQuoted = true
+		yamlEmitterSetQuotedStyle(&e.emitter, yamlSingleQuotedScalarStyle)
+	}
+	e.marshalDoc("", reflect.ValueOf(in))
+	e.finish()
+	out = e.out
+	return
+}
+
 // An Encoder writes YAML values to an output stream.
 type Encoder struct {
 	encoder *encoder
@@ -679,11 +679,14 @@ type yamlEmitterT struct {
 
 	// Emitter stuff
 
-	canonical  bool       // If the output is in the canonical style?
-	bestIndent int        // The number of indentation spaces.
-	bestWidth  int        // The preferred width of the output lines.
-	unicode    bool       // Allow unescaped non-ASCII characters?
-	lineBreak  yamlBreakT // The preferred line break.
+	canonical       bool       // If the output is in the canonical style?
+	bestIndent      int        // The number of indentation spaces.
+	bestWidth       int        // The preferred width of the output lines.
+	unicode         bool       // Allow unescaped non-ASCII characters?
+	indentSequences bool       // Indent block sequences that are mapping values?
+	lineBreak       yamlBreakT // The preferred line break.
+
+	quotedStyle yamlScalarStyleT // The style of plain scalars that cannot be emitted plain.
 
 	state  yamlEmitterStateT   // The current emitter state.
 	states []yamlEmitterStateT // The stack of states.
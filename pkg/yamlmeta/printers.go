@@ -17,13 +17,19 @@ type DocumentPrinter interface {
 
 type YAMLPrinter struct {
 	buf         io.Writer
+	opts        YAMLPrinterOpts
 	writtenOnce bool
 }
 
 var _ DocumentPrinter = &YAMLPrinter{}
 
 func NewYAMLPrinter(writer io.Writer) *YAMLPrinter {
-	return &YAMLPrinter{writer, false}
+	return &YAMLPrinter{buf: writer}
+}
+
+// NewYAMLPrinterWithOpts prints documents styled according to given opts.
+func NewYAMLPrinterWithOpts(writer io.Writer, opts YAMLPrinterOpts) *YAMLPrinter {
+	return &YAMLPrinter{buf: writer, opts: opts}
 }
 
 func (p *YAMLPrinter) Print(item *Document) error {
@@ -33,7 +39,7 @@ func (p *YAMLPrinter) Print(item *Document) error {
 		p.writtenOnce = true
 	}
 
-	bs, err := item.AsYAMLBytes()
+	bs, err := item.AsYAMLBytesWithOpts(p.opts)
 	if err != nil {
 		return fmt.Errorf("marshaling document: %s", err)
 	}
@@ -42,22 +48,44 @@ func (p *YAMLPrinter) Print(item *Document) error {
 }
 
 type JSONPrinter struct {
-	buf io.Writer
+	buf    io.Writer
+	indent string
+	lines  bool
 }
 
 var _ DocumentPrinter = &JSONPrinter{}
 
 func NewJSONPrinter(writer io.Writer) JSONPrinter {
-	return JSONPrinter{writer}
+	return JSONPrinter{buf: writer}
+}
+
+// NewPrettyJSONPrinter prints each document as indented JSON followed by a newline.
+func NewPrettyJSONPrinter(writer io.Writer) JSONPrinter {
+	return JSONPrinter{buf: writer, indent: "  "}
+}
+
+// NewJSONLinesPrinter prints each document as compact JSON on its own line (i.e. JSON Lines).
+func NewJSONLinesPrinter(writer io.Writer) JSONPrinter {
+	return JSONPrinter{buf: writer, lines: true}
 }
 
 func (p JSONPrinter) Print(item *Document) error {
-	val := item.AsInterface()
+	val := orderedmap.Conversion{Object: item.AsInterface()}.AsUnorderedStringMaps()
+
+	var bs []byte
+	var err error
 
-	bs, err := json.Marshal(orderedmap.Conversion{val}.AsUnorderedStringMaps())
+	if len(p.indent) > 0 {
+		bs, err = json.MarshalIndent(val, "", p.indent)
+	} else {
+		bs, err = json.Marshal(val)
+	}
 	if err != nil {
 		return fmt.Errorf("marshaling document: %s", err)
 	}
+	if len(p.indent) > 0 || p.lines {
+		bs = append(bs, '\n')
+	}
 	p.buf.Write(bs)
 	return nil
 }
@@ -0,0 +1,57 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package yamlmeta_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/yamlmeta"
+)
+
+const quotedStringsYAML = `a: "123"
+b: "q: y"
+c: "#x"
+d: "yes"
+e: "it's"
+`
+
+func TestYAMLPrinterDefaultQuoteStyleChoosesQuotesPerString(t *testing.T) {
+	require.Equal(t, `a: "123"
+b: 'q: y'
+c: '#x'
+d: "yes"
+e: it's
+`, printYAML(t, quotedStringsYAML, yamlmeta.YAMLPrinterOpts{}))
+}
+
+func TestYAMLPrinterDoubleQuoteStyleDoubleQuotesAllStrings(t *testing.T) {
+	require.Equal(t, `a: "123"
+b: "q: y"
+c: "#x"
+d: "yes"
+e: it's
+`, printYAML(t, quotedStringsYAML, yamlmeta.YAMLPrinterOpts{QuoteStyle: yamlmeta.QuoteStyleDouble}))
+}
+
+func TestYAMLPrinterSingleQuoteStyleSingleQuotesAllStrings(t *testing.T) {
+	require.Equal(t, `a: '123'
+b: 'q: y'
+c: '#x'
+d: 'yes'
+e: it's
+`, printYAML(t, quotedStringsYAML, yamlmeta.YAMLPrinterOpts{QuoteStyle: yamlmeta.QuoteStyleSingle}))
+}
+
+func printYAML(t *testing.T, data string, opts yamlmeta.YAMLPrinterOpts) string {
+	docSet, err := yamlmeta.NewDocumentSetFromBytes([]byte(data), yamlmeta.DocSetOpts{AssociatedName: "data.yml"})
+	require.NoError(t, err)
+
+	printed, err := docSet.AsBytesWithPrinter(func(w io.Writer) yamlmeta.DocumentPrinter {
+		return yamlmeta.NewYAMLPrinterWithOpts(w, opts)
+	})
+	require.NoError(t, err)
+	return string(printed)
+}
@@ -0,0 +1,199 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package yamlmeta
+
+import (
+	"fmt"
+
+	"github.com/vmware-tanzu/carvel-ytt/pkg/filepos"
+)
+
+// SerializableNode is a form of a Node (including its children) that can be serialized via encoding/gob
+// (e.g. to cache parsed templates).
+//
+// Metadata and annotations are not included since they are only attached to nodes during evaluation.
+type SerializableNode struct {
+	Kind        string
+	Comments    []*Comment
+	AllComments []*Comment
+	Position    *filepos.Position
+
+	// Key is set for map items
+	Key interface{}
+	// Value is set when node's value is a scalar
+	Value interface{}
+	// Items hold either node's items (e.g. documents of a document set) or node's value when it's a node
+	Items []*SerializableNode
+
+	Injected      bool
+	OriginalBytes []byte
+}
+
+const (
+	serializableKindDocumentSet = "document-set"
+	serializableKindDocument    = "document"
+	serializableKindMap         = "map"
+	serializableKindMapItem     = "map-item"
+	serializableKindArray       = "array"
+	serializableKindArrayItem   = "array-item"
+)
+
+// NewSerializableNode converts node (including its children) into its serializable form.
+func NewSerializableNode(node Node) *SerializableNode {
+	return newSerializableNode(node, true)
+}
+
+// NewShallowSerializableNode converts node into its serializable form
+// keeping only its scalar value (i.e. child nodes and original source are not included).
+func NewShallowSerializableNode(node Node) *SerializableNode {
+	return newSerializableNode(node, false)
+}
+
+func newSerializableNode(node Node, withChildren bool) *SerializableNode {
+	result := &SerializableNode{
+		Comments: node.GetComments(),
+		Position: node.GetPosition(),
+	}
+
+	switch typedNode := node.(type) {
+	case *DocumentSet:
+		result.Kind = serializableKindDocumentSet
+		result.AllComments = typedNode.AllComments
+		if withChildren {
+			result.OriginalBytes, _ = typedNode.AsSourceBytes()
+			result.addItems(typedNode.GetValues())
+		}
+
+	case *Document:
+		result.Kind = serializableKindDocument
+		result.Injected = typedNode.injected
+		result.setValue(typedNode.Value, withChildren)
+
+	case *Map:
+		result.Kind = serializableKindMap
+		if withChildren {
+			result.addItems(typedNode.GetValues())
+		}
+
+	case *MapItem:
+		result.Kind = serializableKindMapItem
+		result.Key = typedNode.Key
+		result.setValue(typedNode.Value, withChildren)
+
+	case *Array:
+		result.Kind = serializableKindArray
+		if withChildren {
+			result.addItems(typedNode.GetValues())
+		}
+
+	case *ArrayItem:
+		result.Kind = serializableKindArrayItem
+		result.setValue(typedNode.Value, withChildren)
+
+	default:
+		panic(fmt.Sprintf("Unexpected node type %T", node))
+	}
+
+	return result
+}
+
+func (n *SerializableNode) addItems(items []interface{}) {
+	for _, item := range items {
+		n.Items = append(n.Items, newSerializableNode(item.(Node), true))
+	}
+}
+
+func (n *SerializableNode) setValue(val interface{}, withChildren bool) {
+	if node, ok := val.(Node); ok {
+		if withChildren {
+			n.Items = []*SerializableNode{newSerializableNode(node, true)}
+		}
+	} else {
+		n.Value = val
+	}
+}
+
+// AsNode converts serializable form back into a node (including its children).
+func (n *SerializableNode) AsNode() (Node, error) {
+	switch n.Kind {
+	case serializableKindDocumentSet:
+		result := &DocumentSet{Comments: n.Comments, AllComments: n.AllComments, Position: n.Position}
+		if n.OriginalBytes != nil {
+			originalBytes := n.OriginalBytes
+			result.originalBytes = &originalBytes
+		}
+		for _, item := range n.Items {
+			doc, err := item.asNodeOfKind(serializableKindDocument)
+			if err != nil {
+				return nil, err
+			}
+			result.Items = append(result.Items, doc.(*Document))
+		}
+		return result, nil
+
+	case serializableKindDocument:
+		val, err := n.value()
+		if err != nil {
+			return nil, err
+		}
+		return &Document{Comments: n.Comments, Value: val, Position: n.Position, injected: n.Injected}, nil
+
+	case serializableKindMap:
+		result := &Map{Comments: n.Comments, Position: n.Position}
+		for _, item := range n.Items {
+			mapItem, err := item.asNodeOfKind(serializableKindMapItem)
+			if err != nil {
+				return nil, err
+			}
+			result.Items = append(result.Items, mapItem.(*MapItem))
+		}
+		return result, nil
+
+	case serializableKindMapItem:
+		val, err := n.value()
+		if err != nil {
+			return nil, err
+		}
+		return &MapItem{Comments: n.Comments, Key: n.Key, Value: val, Position: n.Position}, nil
+
+	case serializableKindArray:
+		result := &Array{Comments: n.Comments, Position: n.Position}
+		for _, item := range n.Items {
+			arrayItem, err := item.asNodeOfKind(serializableKindArrayItem)
+			if err != nil {
+				return nil, err
+			}
+			result.Items = append(result.Items, arrayItem.(*ArrayItem))
+		}
+		return result, nil
+
+	case serializableKindArrayItem:
+		val, err := n.value()
+		if err != nil {
+			return nil, err
+		}
+		return &ArrayItem{Comments: n.Comments, Value: val, Position: n.Position}, nil
+
+	default:
+		return nil, fmt.Errorf("Unknown serialized node kind '%s'", n.Kind)
+	}
+}
+
+func (n *SerializableNode) asNodeOfKind(kind string) (Node, error) {
+	if n.Kind != kind {
+		return nil, fmt.Errorf("Expected serialized node kind to be '%s', but was '%s'", kind, n.Kind)
+	}
+	return n.AsNode()
+}
+
+func (n *SerializableNode) value() (interface{}, error) {
+	switch len(n.Items) {
+	case 0:
+		return n.Value, nil
+	case 1:
+		return n.Items[0].AsNode()
+	default:
+		return nil, fmt.Errorf("Expected serialized node to have at most one value, but had %d", len(n.Items))
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package yamlmeta
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vmware-tanzu/carvel-ytt/pkg/yamlmeta/internal/yaml.v2"
+)
+
+// Quote styles used for strings that cannot be printed as plain scalars.
+const (
+	QuoteStyleDouble = "double"
+	QuoteStyleSingle = "single"
+)
+
+// QuoteStyles lists all supported quote styles.
+var QuoteStyles = []string{QuoteStyleDouble, QuoteStyleSingle}
+
+// YAMLPrinterOpts configures style of printed YAML. Zero value matches default style
+// (2 space indent, indentless sequences, quotes chosen per string, no line folding, keys in original order).
+type YAMLPrinterOpts struct {
+	// Indent is the number of spaces per indentation level (2-9).
+	Indent int
+	// IndentSequences indents sequences that are map values (e.g. "key:\n  - item").
+	IndentSequences bool
+	// QuoteStyle is used for strings that cannot be plain (one of QuoteStyles).
+	// Single quotes fall back to double quotes for strings that cannot be single quoted.
+	// When empty, strings that would resolve to other types are double quoted
+	// and strings that contain indicators (e.g. 'a: b') are single quoted.
+	QuoteStyle string
+	// LineWidth is the preferred line width at which long strings are folded (0 means no folding).
+	LineWidth int
+	// SortKeys orders map keys alphabetically instead of keeping their order from templates.
+	SortKeys bool
+}
+
+// Validate checks that opts are within supported ranges.
+func (o YAMLPrinterOpts) Validate() error {
+	if o.Indent != 0 && (o.Indent < 2 || o.Indent > 9) {
+		return fmt.Errorf("Expected indent to be between 2 and 9, but was %d", o.Indent)
+	}
+	switch o.QuoteStyle {
+	case "", QuoteStyleDouble, QuoteStyleSingle:
+	default:
+		return fmt.Errorf("Expected quote style to be one of: %s, but was '%s'", strings.Join(QuoteStyles, ", "), o.QuoteStyle)
+	}
+	if o.LineWidth < 0 {
+		return fmt.Errorf("Expected line width to be non-negative (0 disables folding), but was %d", o.LineWidth)
+	}
+	indent := o.Indent
+	if indent == 0 {
+		indent = 2
+	}
+	if o.LineWidth > 0 && o.LineWidth <= indent*2 {
+		return fmt.Errorf("Expected line width to be greater than twice the indent (%d), but was %d", indent*2, o.LineWidth)
+	}
+	return nil
+}
+
+// IsDefault returns true if opts describe default style (either explicitly or via zero values).
+func (o YAMLPrinterOpts) IsDefault() bool {
+	return (o.Indent == 0 || o.Indent == 2) && !o.IndentSequences &&
+		o.QuoteStyle == "" && o.LineWidth == 0 && !o.SortKeys
+}
+
+func (o YAMLPrinterOpts) encoderOpts() yaml.EncoderOpts {
+	return yaml.EncoderOpts{
+		Indent:          o.Indent,
+		IndentSequences: o.IndentSequences,
+		Width:           o.LineWidth,
+		Quotes:          o.quotes(),
+	}
+}
+
+func (o YAMLPrinterOpts) quotes() yaml.QuoteStyle {
+	switch o.QuoteStyle {
+	case QuoteStyleDouble:
+		return yaml.DoubleQuotes
+	case QuoteStyleSingle:
+		return yaml.SingleQuotes
+	default:
+		return yaml.DefaultQuotes
+	}
+}
+
+// sortLowYAMLKeys orders keys of all maps within val (keys of different types are ordered by their string form).
+func sortLowYAMLKeys(val interface{}) interface{} {
+	switch typedVal := val.(type) {
+	case yaml.MapSlice:
+		result := yaml.MapSlice{}
+		for _, item := range typedVal {
+			result = append(result, yaml.MapItem{Key: item.Key, Value: sortLowYAMLKeys(item.Value)})
+		}
+		sort.SliceStable(result, func(i, j int) bool {
+			return fmt.Sprintf("%v", result[i].Key) < fmt.Sprintf("%v", result[j].Key)
+		})
+		return result
+
+	case []interface{}:
+		result := []interface{}{}
+		for _, item := range typedVal {
+			result = append(result, sortLowYAMLKeys(item))
+		}
+		return result
+
+	default:
+		return val
+	}
+}
@@ -84,12 +84,52 @@ func (e *Template) Compile(docSet *yamlmeta.DocumentSet) (*template.CompiledTemp
 		Instruction: e.instructions.NewEndCtxNone(), // TODO ideally we would return array of docset
 	})
 
-	return template.NewCompiledTemplate(e.name, code, e.instructions, e.nodes, template.EvaluationCtxDialects{
+	return template.NewCompiledTemplate(e.name, code, e.instructions, e.nodes, e.evalDialects()), nil
+}
+
+// CompileFromSerializable recreates compiled template from its serializable form
+// (previously produced by NewSerializableCompiledTemplate).
+func (e *Template) CompileFromSerializable(s *template.SerializableCompiledTemplate) (*template.CompiledTemplate, error) {
+	return template.NewCompiledTemplateFromSerializable(s, e.instructions, deserializeNode, e.evalDialects())
+}
+
+// NewSerializableCompiledTemplate converts compiled YAML template into its serializable form.
+func NewSerializableCompiledTemplate(compiledTemplate *template.CompiledTemplate) (*template.SerializableCompiledTemplate, error) {
+	return compiledTemplate.AsSerializable(serializeNode)
+}
+
+func (e *Template) evalDialects() template.EvaluationCtxDialects {
+	return template.EvaluationCtxDialects{
 		EvaluationCtxDialectName: EvaluationCtx{
 			implicitMapKeyOverrides: e.opts.ImplicitMapKeyOverrides,
 		},
 		texttemplate.EvaluationCtxDialectName: texttemplate.EvaluationCtx{},
-	}), nil
+	}
+}
+
+func serializeNode(node template.EvaluationNode) (interface{}, error) {
+	switch typedNode := node.(type) {
+	case yamlmeta.Node:
+		// Only node's own value is used during evaluation (children have their own tags)
+		return yamlmeta.NewShallowSerializableNode(typedNode), nil
+	case *texttemplate.NodeRoot, *texttemplate.NodeText:
+		return typedNode, nil
+	default:
+		return nil, fmt.Errorf("Unexpected node type %T", node)
+	}
+}
+
+func deserializeNode(val interface{}) (template.EvaluationNode, error) {
+	switch typedVal := val.(type) {
+	case *yamlmeta.SerializableNode:
+		return typedVal.AsNode()
+	case *texttemplate.NodeRoot:
+		return typedVal, nil
+	case *texttemplate.NodeText:
+		return typedVal, nil
+	default:
+		return nil, fmt.Errorf("Unexpected serialized node type %T", val)
+	}
 }
 
 type buildOpts struct {
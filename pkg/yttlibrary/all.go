@@ -5,6 +5,7 @@ package yttlibrary
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/k14s/starlark-go/starlark"
 	"github.com/k14s/starlark-go/starlarkstruct"
@@ -86,3 +87,13 @@ func (a API) FindModule(module string) (starlark.StringDict, error) {
 	return nil, fmt.Errorf("builtin ytt library does not have module '%s' "+
 		"(hint: is it available in newer version of ytt?)", module)
 }
+
+// ModuleNames returns sorted names of all modules available via '@ytt:' prefix.
+func (a API) ModuleNames() []string {
+	var names []string
+	for name := range a.modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -5,10 +5,14 @@ package yttlibrary
 
 import (
 	"fmt"
+	"io"
+	"strings"
 
+	"github.com/k14s/difflib"
 	"github.com/k14s/starlark-go/starlark"
 	"github.com/k14s/starlark-go/starlarkstruct"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/template/core"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/yamlmeta"
 )
 
 var (
@@ -16,9 +20,12 @@ var (
 		"assert": &starlarkstruct.Module{
 			Name: "assert",
 			Members: starlark.StringDict{
-				"equals": starlark.NewBuiltin("assert.equals", core.ErrWrapper(assertModule{}.Equals)),
-				"fail":   starlark.NewBuiltin("assert.fail", core.ErrWrapper(assertModule{}.Fail)),
-				"try_to": starlark.NewBuiltin("assert.try_to", core.ErrWrapper(assertModule{}.TryTo)),
+				"equals":      starlark.NewBuiltin("assert.equals", core.ErrWrapper(assertModule{}.Equals)),
+				"deep_equals": starlark.NewBuiltin("assert.deep_equals", core.ErrWrapper(assertModule{}.DeepEquals)),
+				"contains":    starlark.NewBuiltin("assert.contains", core.ErrWrapper(assertModule{}.Contains)),
+				"raises":      starlark.NewBuiltin("assert.raises", core.ErrWrapper(assertModule{}.Raises)),
+				"fail":        starlark.NewBuiltin("assert.fail", core.ErrWrapper(assertModule{}.Fail)),
+				"try_to":      starlark.NewBuiltin("assert.try_to", core.ErrWrapper(assertModule{}.TryTo)),
 			},
 		},
 	}
@@ -72,6 +79,125 @@ func (b assertModule) asString(value starlark.Value) (string, error) {
 	return yamlString, nil
 }
 
+// DeepEquals compares two values for equality ignoring order of map keys; differences are shown as a diff
+func (b assertModule) DeepEquals(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 2 {
+		return starlark.None, fmt.Errorf("expected two arguments")
+	}
+
+	var strs []string
+
+	for _, val := range args {
+		if _, notOk := val.(starlark.Callable); notOk {
+			return starlark.None, fmt.Errorf("expected argument not to be a function, but was %T", val)
+		}
+
+		goVal, err := core.NewStarlarkValue(val).AsGoValue()
+		if err != nil {
+			return starlark.None, err
+		}
+
+		docSet := &yamlmeta.DocumentSet{Items: []*yamlmeta.Document{{Value: goVal}}}
+		valBs, err := docSet.AsBytesWithPrinter(func(w io.Writer) yamlmeta.DocumentPrinter {
+			return yamlmeta.NewYAMLPrinterWithOpts(w, yamlmeta.YAMLPrinterOpts{SortKeys: true})
+		})
+		if err != nil {
+			return starlark.None, err
+		}
+
+		strs = append(strs, strings.TrimSuffix(string(valBs), "\n"))
+	}
+
+	if strs[0] != strs[1] {
+		return starlark.None, fmt.Errorf("Not equal (- expected, + actual):\n%s", strings.TrimSuffix(Diff(strs[0], strs[1]), "\n"))
+	}
+
+	return starlark.None, nil
+}
+
+// Diff returns line by line difference marking lines only in expected with '-' and lines only in actual with '+'.
+func Diff(expected, actual string) string {
+	// PPDiff marks lines only in its second argument with '-'
+	return difflib.PPDiff(strings.Split(actual, "\n"), strings.Split(expected, "\n"))
+}
+
+// Contains checks that a string includes a substring, a list (or tuple) includes an element, or a dict includes a key
+func (b assertModule) Contains(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() != 2 {
+		return starlark.None, fmt.Errorf("expected two arguments")
+	}
+
+	container := args.Index(0)
+	item := args.Index(1)
+
+	var found bool
+
+	switch typedContainer := container.(type) {
+	case starlark.String:
+		substr, ok := item.(starlark.String)
+		if !ok {
+			return starlark.None, fmt.Errorf("expected second argument to be a string, but was %s", item.Type())
+		}
+		found = strings.Contains(string(typedContainer), string(substr))
+
+	case starlark.Indexable:
+		for i := 0; i < typedContainer.Len(); i++ {
+			eq, err := starlark.Equal(typedContainer.Index(i), item)
+			if err != nil {
+				return starlark.None, err
+			}
+			if eq {
+				found = true
+				break
+			}
+		}
+
+	case starlark.Mapping:
+		_, found, _ = typedContainer.Get(item)
+
+	default:
+		return starlark.None, fmt.Errorf("expected first argument to be a string, list, tuple or dict, but was %s", container.Type())
+	}
+
+	if !found {
+		return starlark.None, fmt.Errorf("Expected %s to contain %s", container.String(), item.String())
+	}
+
+	return starlark.None, nil
+}
+
+// Raises calls a function expecting it to fail (optionally with an error containing given string); returns error message
+func (b assertModule) Raises(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if args.Len() < 1 || args.Len() > 2 {
+		return starlark.None, fmt.Errorf("expected one or two arguments")
+	}
+
+	lambda := args.Index(0)
+	if _, ok := lambda.(starlark.Callable); !ok {
+		return starlark.None, fmt.Errorf("expected argument to be a function, but was %T", lambda)
+	}
+
+	var expectedMsg string
+	if args.Len() == 2 {
+		msg, err := core.NewStarlarkValue(args.Index(1)).AsString()
+		if err != nil {
+			return starlark.None, err
+		}
+		expectedMsg = msg
+	}
+
+	_, err := starlark.Call(thread, lambda, nil, nil)
+	if err == nil {
+		return starlark.None, fmt.Errorf("Expected function to fail, but it succeeded")
+	}
+
+	if !strings.Contains(err.Error(), expectedMsg) {
+		return starlark.None, fmt.Errorf("Expected function to fail with error containing '%s', but error was: %s", expectedMsg, err)
+	}
+
+	return starlark.String(err.Error()), nil
+}
+
 func (b assertModule) Fail(thread *starlark.Thread, f *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 	if args.Len() != 1 {
 		return starlark.None, fmt.Errorf("expected exactly one argument")
@@ -74,6 +74,12 @@ func (b overlayModule) Index(
 		return starlark.None, err
 	}
 
+	return IndexMatcher(expectedIdx64), nil
+}
+
+// IndexMatcher produces an array item matcher (suitable for use as 'by' kwarg of '@overlay/match')
+// that matches the item at position expectedIdx64 (same as overlay.index(...)).
+func IndexMatcher(expectedIdx64 int64) *starlark.Builtin {
 	matchFunc := func(thread *starlark.Thread, f *starlark.Builtin,
 		args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 
@@ -93,7 +99,7 @@ func (b overlayModule) Index(
 		return starlark.Bool(false), nil
 	}
 
-	return starlark.NewBuiltin("overlay.index_matcher", core.ErrWrapper(matchFunc)), nil
+	return starlark.NewBuiltin("overlay.index_matcher", core.ErrWrapper(matchFunc))
 }
 
 func (b overlayModule) All(
@@ -69,7 +69,12 @@ func (a DocumentMatchAnnotation) IndexTuples(leftDocSets []*yamlmeta.DocumentSet
 		return nil, err
 	}
 
-	return idxs, a.expects.Check(matches)
+	err = a.expects.Check(matches)
+	if err == nil && len(matches) == 0 && !a.exact && a.expects.expects == nil {
+		notifyUnmatchedDocument(a.thread, a.newDoc.Position)
+	}
+
+	return idxs, err
 }
 
 func (a DocumentMatchAnnotation) MatchNodes(leftDocSets []*yamlmeta.DocumentSet) ([][]int, []*filepos.Position, error) {
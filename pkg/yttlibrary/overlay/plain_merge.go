@@ -5,8 +5,12 @@ package overlay
 
 import (
 	"fmt"
+	"math/big"
+	"strings"
 
 	"github.com/k14s/starlark-go/starlark"
+	"github.com/k14s/starlark-go/starlarkstruct"
+	"github.com/k14s/starlark-go/syntax"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/template"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/yamlmeta"
 	"github.com/vmware-tanzu/carvel-ytt/pkg/yamltemplate"
@@ -56,3 +60,331 @@ func addOverlayReplace(node yamlmeta.Node) {
 
 	node.SetAnnotations(anns)
 }
+
+var (
+	plainMergeAllowedAnns = []template.AnnotationName{
+		AnnotationMatch,
+		AnnotationMerge,
+		AnnotationRemove,
+		AnnotationReplace,
+		AnnotationInsert,
+		AnnotationAppend,
+	}
+)
+
+// AnnotateForPlainMergeWithOverlays configures `node` to be an overlay doing a "plain merge" (see AnnotateForPlainMerge)
+// while honoring overlay annotations (e.g. `@overlay/replace`, `@overlay/remove`, `@overlay/match`) found on its map
+// items and array items; explicit annotations take precedence over those implied by "plain merge".
+//
+// Annotation arguments are not evaluated as code; only literals and `overlay` module members are accepted.
+// Returns an error when `node` contains any other templating (i.e. code, values, or other annotations).
+func AnnotateForPlainMergeWithOverlays(node yamlmeta.Node) error {
+	thread := &starlark.Thread{Name: "plain-merge-overlay-annotations"}
+	_, err := addOverlayReplaceWithOverlays(node, thread)
+	return err
+}
+
+// addOverlayReplaceWithOverlays annotates `node` (and its children), returning whether `node` has explicit
+// overlay annotations (for arrays: whether any of their items do).
+func addOverlayReplaceWithOverlays(node yamlmeta.Node, thread *starlark.Thread) (bool, error) {
+	explicitAnns, err := explicitOverlayAnnotations(node, thread)
+	if err != nil {
+		return false, err
+	}
+
+	childrenExplicit := false
+	for _, val := range node.GetValues() {
+		if typedVal, ok := val.(yamlmeta.Node); ok {
+			explicit, err := addOverlayReplaceWithOverlays(typedVal, thread)
+			if err != nil {
+				return false, err
+			}
+			childrenExplicit = childrenExplicit || explicit
+		}
+	}
+
+	anns := template.NodeAnnotations{}
+
+	// unannotated array items are appended
+	if _, isArrayItem := node.(*yamlmeta.ArrayItem); !isArrayItem {
+		anns[AnnotationMatch] = template.NodeAnnotation{
+			Kwargs: []starlark.Tuple{{
+				starlark.String(MatchAnnotationKwargMissingOK),
+				starlark.Bool(true),
+			}},
+		}
+
+		replaceAnn := template.NodeAnnotation{
+			Kwargs: []starlark.Tuple{{
+				starlark.String(ReplaceAnnotationKwargOrAdd),
+				starlark.Bool(true),
+			}},
+		}
+
+		for _, val := range node.GetValues() {
+			switch val.(type) {
+			case *yamlmeta.Array:
+				// arrays with annotated items are merged, item by item
+				if !childrenExplicit {
+					anns[AnnotationReplace] = replaceAnn
+				}
+			case yamlmeta.Node:
+			default:
+				anns[AnnotationReplace] = replaceAnn
+			}
+		}
+	}
+
+	for name, ann := range explicitAnns {
+		if name != AnnotationMatch {
+			delete(anns, AnnotationReplace)
+		}
+		anns[name] = ann
+	}
+
+	node.SetAnnotations(anns)
+
+	if _, isArray := node.(*yamlmeta.Array); isArray {
+		return childrenExplicit, nil
+	}
+	return len(explicitAnns) > 0, nil
+}
+
+func explicitOverlayAnnotations(node yamlmeta.Node, thread *starlark.Thread) (template.NodeAnnotations, error) {
+	anns := template.NodeAnnotations{}
+	var plainComments []*yamlmeta.Comment
+
+	for _, comment := range node.GetComments() {
+		ann, err := yamltemplate.NewTemplateAnnotationFromYAMLComment(comment, node.GetPosition(), template.MetaOpts{IgnoreUnknown: true})
+		if err != nil {
+			return nil, err
+		}
+		if ann.Name == template.AnnotationComment {
+			plainComments = append(plainComments, comment)
+			continue
+		}
+
+		if !isPlainMergeAllowedAnn(ann.Name) {
+			return nil, fmt.Errorf("Expected to be plain YAML, having only overlay annotations (%s), but found '#%s' on line %s",
+				plainMergeAllowedAnnsDesc(), comment.Data, comment.Position.AsCompactString())
+		}
+		switch node.(type) {
+		case *yamlmeta.MapItem, *yamlmeta.ArrayItem:
+		default:
+			return nil, fmt.Errorf("Expected overlay annotations to be attached to map items or array items only, but found '#%s' on line %s",
+				comment.Data, comment.Position.AsCompactString())
+		}
+
+		nodeAnn, err := evalPlainMergeAnnotation(ann, thread)
+		if err != nil {
+			return nil, fmt.Errorf("Evaluating annotation '#%s' on line %s: %s", comment.Data, comment.Position.AsCompactString(), err)
+		}
+		anns[ann.Name] = nodeAnn
+	}
+
+	node.SetComments(plainComments)
+	return anns, nil
+}
+
+// evalPlainMergeAnnotation evaluates arguments of `ann` without executing any code:
+// arguments may only be literals (and lists, tuples, dicts of them) and members of the `overlay` module
+// (optionally called with such arguments, e.g. `overlay.subset({"name": "a"})`).
+func evalPlainMergeAnnotation(ann template.Annotation, thread *starlark.Thread) (template.NodeAnnotation, error) {
+	result := template.NodeAnnotation{Position: ann.Position}
+	if len(ann.Content) == 0 {
+		return result, nil
+	}
+
+	// Arguments are parsed (not evaluated) as a call so that keyword arguments are recognized
+	evaluator := plainMergeArgsEvaluator{src: "args(" + ann.Content + ")", thread: thread}
+
+	expr, err := syntax.ParseExpr(ann.Position.AsCompactString(), evaluator.src, 0)
+	if err != nil {
+		return template.NodeAnnotation{}, err
+	}
+	callExpr, ok := expr.(*syntax.CallExpr)
+	if !ok {
+		return template.NodeAnnotation{}, fmt.Errorf("Expected annotation arguments, but was '%s'", ann.Content)
+	}
+
+	result.Args, result.Kwargs, err = evaluator.CallArgs(callExpr.Args)
+	if err != nil {
+		return template.NodeAnnotation{}, err
+	}
+	return result, nil
+}
+
+type plainMergeArgsEvaluator struct {
+	src    string
+	thread *starlark.Thread
+}
+
+func (e plainMergeArgsEvaluator) CallArgs(argExprs []syntax.Expr) (starlark.Tuple, []starlark.Tuple, error) {
+	var args starlark.Tuple
+	var kwargs []starlark.Tuple
+
+	for _, argExpr := range argExprs {
+		if binExpr, ok := argExpr.(*syntax.BinaryExpr); ok && binExpr.Op == syntax.EQ {
+			name, ok := binExpr.X.(*syntax.Ident)
+			if !ok {
+				return nil, nil, e.unsupportedExprErr(argExpr)
+			}
+			val, err := e.Value(binExpr.Y)
+			if err != nil {
+				return nil, nil, err
+			}
+			kwargs = append(kwargs, starlark.Tuple{starlark.String(name.Name), val})
+			continue
+		}
+
+		if len(kwargs) > 0 {
+			return nil, nil, fmt.Errorf("Expected positional arguments to precede keyword arguments")
+		}
+		val, err := e.Value(argExpr)
+		if err != nil {
+			return nil, nil, err
+		}
+		args = append(args, val)
+	}
+
+	return args, kwargs, nil
+}
+
+func (e plainMergeArgsEvaluator) Value(expr syntax.Expr) (starlark.Value, error) {
+	switch typedExpr := expr.(type) {
+	case *syntax.Literal:
+		switch val := typedExpr.Value.(type) {
+		case string:
+			return starlark.String(val), nil
+		case int64:
+			return starlark.MakeInt64(val), nil
+		case *big.Int:
+			return starlark.MakeBigInt(val), nil
+		case float64:
+			return starlark.Float(val), nil
+		}
+
+	case *syntax.Ident:
+		switch typedExpr.Name {
+		case "True":
+			return starlark.True, nil
+		case "False":
+			return starlark.False, nil
+		case "None":
+			return starlark.None, nil
+		}
+
+	case *syntax.UnaryExpr:
+		if typedExpr.Op == syntax.MINUS {
+			if lit, ok := typedExpr.X.(*syntax.Literal); ok && lit.Token != syntax.STRING {
+				val, err := e.Value(lit)
+				if err != nil {
+					return nil, err
+				}
+				return starlark.Unary(syntax.MINUS, val)
+			}
+		}
+
+	case *syntax.ParenExpr:
+		return e.Value(typedExpr.X)
+
+	case *syntax.ListExpr:
+		vals, err := e.Values(typedExpr.List)
+		if err != nil {
+			return nil, err
+		}
+		return starlark.NewList(vals), nil
+
+	case *syntax.TupleExpr:
+		vals, err := e.Values(typedExpr.List)
+		if err != nil {
+			return nil, err
+		}
+		return starlark.Tuple(vals), nil
+
+	case *syntax.DictExpr:
+		dict := starlark.NewDict(len(typedExpr.List))
+		for _, entryExpr := range typedExpr.List {
+			entry := entryExpr.(*syntax.DictEntry)
+			key, err := e.Value(entry.Key)
+			if err != nil {
+				return nil, err
+			}
+			val, err := e.Value(entry.Value)
+			if err != nil {
+				return nil, err
+			}
+			err = dict.SetKey(key, val)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+
+	case *syntax.DotExpr:
+		return e.overlayMember(typedExpr)
+
+	case *syntax.CallExpr:
+		dotExpr, ok := typedExpr.Fn.(*syntax.DotExpr)
+		if !ok {
+			break
+		}
+		fn, err := e.overlayMember(dotExpr)
+		if err != nil {
+			return nil, err
+		}
+		args, kwargs, err := e.CallArgs(typedExpr.Args)
+		if err != nil {
+			return nil, err
+		}
+		return starlark.Call(e.thread, fn, args, kwargs)
+	}
+
+	return nil, e.unsupportedExprErr(expr)
+}
+
+func (e plainMergeArgsEvaluator) Values(exprs []syntax.Expr) ([]starlark.Value, error) {
+	var vals []starlark.Value
+	for _, expr := range exprs {
+		val, err := e.Value(expr)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, val)
+	}
+	return vals, nil
+}
+
+func (e plainMergeArgsEvaluator) overlayMember(expr *syntax.DotExpr) (starlark.Value, error) {
+	if ident, ok := expr.X.(*syntax.Ident); !ok || ident.Name != "overlay" {
+		return nil, e.unsupportedExprErr(expr)
+	}
+	val, err := API["overlay"].(*starlarkstruct.Module).Attr(expr.Name.Name)
+	if err != nil || val == nil {
+		return nil, fmt.Errorf("Expected 'overlay' module to have member '%s', but did not", expr.Name.Name)
+	}
+	return val, nil
+}
+
+func (e plainMergeArgsEvaluator) unsupportedExprErr(expr syntax.Expr) error {
+	start, end := expr.Span()
+	return fmt.Errorf("Expected annotation argument to be a literal value or a member of 'overlay' module, but was '%s'",
+		e.src[start.Col-1:end.Col-1])
+}
+
+func isPlainMergeAllowedAnn(name template.AnnotationName) bool {
+	for _, allowed := range plainMergeAllowedAnns {
+		if name == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func plainMergeAllowedAnnsDesc() string {
+	var names []string
+	for _, name := range plainMergeAllowedAnns {
+		names = append(names, "@"+string(name))
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,30 @@
+// Copyright 2022 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package overlay
+
+import (
+	"github.com/k14s/starlark-go/starlark"
+	"github.com/vmware-tanzu/carvel-ytt/pkg/filepos"
+)
+
+const threadUnmatchedDocumentFuncKey = "ytt.overlay.unmatched_document_func_key"
+
+// UnmatchedDocumentFunc is called with position of an overlay document which did not match
+// any documents, while not explicitly expecting so (i.e. without 'expects', e.g. via missing_ok=True).
+// Such overlays have no effect which typically indicates a mistake in their matcher.
+type UnmatchedDocumentFunc func(*filepos.Position)
+
+// SetUnmatchedDocumentFunc configures overlays applied within thread to report unmatched overlay documents.
+func SetUnmatchedDocumentFunc(thread *starlark.Thread, f UnmatchedDocumentFunc) {
+	thread.SetLocal(threadUnmatchedDocumentFuncKey, f)
+}
+
+func notifyUnmatchedDocument(thread *starlark.Thread, pos *filepos.Position) {
+	if thread == nil {
+		return
+	}
+	if f, ok := thread.Local(threadUnmatchedDocumentFuncKey).(UnmatchedDocumentFunc); ok && f != nil {
+		f(pos)
+	}
+}